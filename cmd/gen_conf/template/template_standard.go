@@ -21,6 +21,7 @@ var Options = map[string]interface{}{
 	"besteffort":          7,
 	"shared":              2,
 	"shrink":              false,
+	"rebalance":           false,
 	"policypath":          "/etc/rmd/policy.toml",
 	"sysresctrl":          "/sys/fs/resctrl",
 	"plugins":             "",
@@ -47,6 +48,8 @@ dbValidatorInterval = {{.dbValidatorInterval}}
 
 [rdt]
 mbaMode = "{{.mbaMode}}" # MBA mode of operation, possible options are: "none", "percentage" and "mbps"
+# mbaPeakMbps = 0 # measured peak memory bandwidth per socket (in Mbps) used to convert MBA policy params between modes
+# throttleMbps = 0 # memory bandwidth per socket (in Mbps) of shared/besteffort workload that triggers MBA throttling
 
 [log]
 path = "{{.logfile}}"
@@ -78,6 +81,11 @@ max_allowed_shared = {{.max_shared}} # max allowed workload in shared pool, defa
 guarantee = {{.guarantee}}
 besteffort = {{.besteffort}}
 shared = {{.shared}}
+rebalance = {{.rebalance}} # grow/shrink besteffort workloads ways according to cache occupancy
+# rebalance_interval = 10 # seconds
+# grow_threshold = 90 # percent of allocated ways occupied
+# shrink_threshold = 50
+# hysteresis = 3 # consecutive samples needed to change ways
 
 [acl]
 path = "/etc/rmd/acl/"  #
@@ -15,6 +15,7 @@ import (
 	proxyserver "github.com/intel/rmd/internal/proxy/server"
 	proxytypes "github.com/intel/rmd/internal/proxy/types"
 	cacheconf "github.com/intel/rmd/modules/cache/config"
+	"github.com/intel/rmd/modules/policy"
 	util "github.com/intel/rmd/utils"
 	"github.com/intel/rmd/utils/bootcheck"
 	appconf "github.com/intel/rmd/utils/config"
@@ -260,6 +261,17 @@ func main() {
 			}
 		}
 
+		// SIGHUP is forwarded to REST API server process to reload policy file
+		hupc := make(chan os.Signal, 1)
+		signal.Notify(hupc, syscall.SIGHUP)
+		go func(p *os.Process) {
+			for range hupc {
+				if err := p.Signal(syscall.SIGHUP); err != nil {
+					loginfo.Errorf("Failed to forward SIGHUP to REST API server: %v", err)
+				}
+			}
+		}(child)
+
 		fmt.Printf("RMD server started, REST API server serving on process %d\n", child.Pid)
 		proxyserver.RegisterAndServe(out)
 	}
@@ -270,15 +282,23 @@ func main() {
 		loginfo.Println(err)
 		os.Exit(1)
 	}
+	// SIGHUP is also sent on parent death, parent process is changed then
+	ppid := os.Getppid()
 	sigc := make(chan os.Signal, 1)
 	signal.Notify(sigc, syscall.SIGHUP)
 	go func() {
-		sig := <-sigc
-		//NOTE, should we add some cleanup?
-		cleanupFunc()
-		loginfo.Printf("Received %s, shutdown RMD for root process exit.", sig.String())
-		// Do not Exit(0), for there are some thing wrong with supper RMD.
-		os.Exit(1)
+		for sig := range sigc {
+			if os.Getppid() == ppid {
+				loginfo.Printf("Received %s, reload policy file.", sig.String())
+				policy.Reload()
+				continue
+			}
+			//NOTE, should we add some cleanup?
+			cleanupFunc()
+			loginfo.Printf("Received %s, shutdown RMD for root process exit.", sig.String())
+			// Do not Exit(0), for there are some thing wrong with supper RMD.
+			os.Exit(1)
+		}
 	}()
 
 	//in.Writer
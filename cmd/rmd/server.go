@@ -13,11 +13,15 @@ import (
 	appConf "github.com/intel/rmd/utils/config"
 
 	"github.com/emicklei/go-restful"
+	"github.com/intel/rmd/internal/inventory"
+	"github.com/intel/rmd/internal/kubernetes"
 	"github.com/intel/rmd/internal/openstack"
 	"github.com/intel/rmd/internal/plugins"
 	"github.com/intel/rmd/modules/cache"
+	"github.com/intel/rmd/modules/events"
 	"github.com/intel/rmd/modules/hospitality"
 	"github.com/intel/rmd/modules/mba"
+	"github.com/intel/rmd/modules/metrics"
 	"github.com/intel/rmd/modules/policy"
 	"github.com/intel/rmd/modules/workload"
 	"github.com/intel/rmd/utils/auth"
@@ -42,6 +46,7 @@ type GenericConfig struct {
 	IsClientCertAuthOption bool
 	Debug                  bool
 	OpenStackEnable        bool
+	KubernetesEnable       bool
 }
 
 // Config is application configuration
@@ -73,6 +78,7 @@ func buildServerConfig() *Config {
 		IsClientCertAuthOption: isClientCertAuthOption,
 		Debug:                  appconfig.Dbg.Enabled,
 		OpenStackEnable:        appconfig.Def.OpenStackEnable,
+		KubernetesEnable:       appconfig.Def.KubernetesEnable,
 	}
 
 	return &Config{
@@ -99,6 +105,8 @@ func Initialize(c *Config) (*restful.Container, error) {
 	}
 
 	wsContainer.Filter(apptls.ACL)
+	// count requests only after authentication and authorization
+	wsContainer.Filter(metrics.Filter)
 	wsContainer.Router(restful.CurlyRouter{})
 
 	// Register controller to container
@@ -106,7 +114,12 @@ func Initialize(c *Config) (*restful.Container, error) {
 	policy.Register(prefix, wsContainer)
 	hospitality.Register(prefix, wsContainer)
 	workload.Register(prefix, wsContainer)
+	workload.RegisterBatch(prefix, wsContainer)
+	workload.RegisterReconcile(prefix, wsContainer)
+	events.Register(prefix, wsContainer)
+	(&inventory.Inventory{}).Register(wsContainer)
 	mba.Register(prefix, wsContainer)
+	metrics.Register(wsContainer)
 
 	// iterate over list of modules and register enpoints for them
 	for pluginName, pluginInterface := range plugins.Interfaces {
@@ -149,6 +162,11 @@ func RunServer() {
 		log.Fatal(err)
 	}
 
+	// Policy file is used only in rmd user process
+	if err := policy.Watch(); err != nil {
+		log.Error("Failed to watch policy file: ", err.Error())
+	}
+
 	// Notification listener should run in rmd user process
 	if config.Generic.OpenStackEnable {
 		if err := openstack.Init(); err != nil {
@@ -157,6 +175,15 @@ func RunServer() {
 		log.Debug("OpenStack initialized properly")
 	}
 
+	// Container events listener should run in rmd user process
+	if config.Generic.KubernetesEnable {
+		if err := kubernetes.Init(); err != nil {
+			log.Error("Kubernetes initialization failed: ", err.Error())
+		} else {
+			log.Debug("Kubernetes initialized properly")
+		}
+	}
+
 	// TODO cleanup this mass logic, especially for unixsock
 
 	if config.Generic.Debug {
@@ -24,6 +24,7 @@ require (
 	github.com/onsi/gomega v1.10.1
 	github.com/pelletier/go-buffruneio v0.2.0 // indirect
 	github.com/prashantv/gostub v0.0.0-20170112001514-5c68b99bb088
+	github.com/prometheus/client_golang v1.7.1
 	github.com/sergi/go-diff v0.0.0-20170409071739-feef008d51ad // indirect
 	github.com/sirupsen/logrus v1.6.0
 	github.com/smartystreets/assertions v1.0.1 // indirect
@@ -37,7 +38,7 @@ require (
 	github.com/yudai/gojsondiff v1.0.0 // indirect
 	github.com/yudai/golcs v0.0.0-20170316035057-ecda9a501e82 // indirect
 	github.com/yudai/pp v2.0.1+incompatible // indirect
-	golang.org/x/sys v0.0.0-20200519105757-fe76b779f299
+	golang.org/x/sys v0.0.0-20200615200032-f1bc736245b1
 	gopkg.in/check.v1 v1.0.0-20190902080502-41f04d3bba15 // indirect
 	gopkg.in/gavv/httpexpect.v1 v1.0.0-20170328075429-d852bb8255b6
 )
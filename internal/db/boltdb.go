@@ -1,6 +1,7 @@
 package db
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"reflect"
@@ -11,6 +12,8 @@ import (
 	bolt "github.com/etcd-io/bbolt"
 
 	"github.com/intel/rmd/internal/db/config"
+	evtypes "github.com/intel/rmd/modules/events/types"
+	ptypes "github.com/intel/rmd/modules/policy/types"
 	wltypes "github.com/intel/rmd/modules/workload/types"
 	util "github.com/intel/rmd/utils"
 )
@@ -66,7 +69,7 @@ func newBoltDB() (DB, error) {
 	return &db, nil
 }
 
-// Initialize creates two buckets: for storing workloads and UUID-ID mapping
+// Initialize creates buckets for storing workloads, UUID-ID mapping, events and policies
 func (b *BoltDB) Initialize(transport, dbname string) error {
 	return b.session.Update(func(tx *bolt.Tx) error {
 		// First touch a Bucket for workloads ...
@@ -74,11 +77,25 @@ func (b *BoltDB) Initialize(transport, dbname string) error {
 		if err != nil {
 			return err
 		}
-		// ... and for UUID-ID mapping
+		// ... and for UUID-ID mapping ...
 		_, err = tx.CreateBucketIfNotExists([]byte(MappingTableName))
 		if err != nil {
 			return err
 		}
+		// ... and for events ...
+		_, err = tx.CreateBucketIfNotExists([]byte(EventTableName))
+		if err != nil {
+			return err
+		}
+		// ... and for policies ...
+		_, err = tx.CreateBucketIfNotExists([]byte(PolicyTableName))
+		if err != nil {
+			return err
+		}
+		// ... and for workload indexes (built from workloads stored before indexes were added)
+		if tx.Bucket([]byte(WorkloadIndexTableName)) == nil {
+			return buildIndexes(tx)
+		}
 		return nil
 	})
 
@@ -105,36 +122,45 @@ func (b *BoltDB) CreateWorkload(w *wltypes.RDTWorkLoad) error {
 	}
 
 	return b.session.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(WorkloadTableName))
-		if bucket == nil {
-			return errors.New("Bucket fetching failed")
-		}
-		if (w != nil) && (w.ID == "") {
-			// Generate ID for the workload.
-			id, err := bucket.NextSequence()
-			if err != nil {
-				return err
-			}
-			w.ID = strconv.Itoa(int(id))
-		}
-		// Marshal  data into bytes.
-		buf, err := json.Marshal(w)
+		return putWorkload(tx, w)
+	})
+}
+
+// putWorkload stores new workload (and its UUID mapping) in transaction,
+// ID is generated for workload without it
+func putWorkload(tx *bolt.Tx, w *wltypes.RDTWorkLoad) error {
+	bucket := tx.Bucket([]byte(WorkloadTableName))
+	if bucket == nil {
+		return errors.New("Bucket fetching failed")
+	}
+	if (w != nil) && (w.ID == "") {
+		// Generate ID for the workload.
+		id, err := bucket.NextSequence()
 		if err != nil {
 			return err
 		}
+		w.ID = strconv.Itoa(int(id))
+	}
+	// Marshal  data into bytes.
+	buf, err := json.Marshal(w)
+	if err != nil {
+		return err
+	}
+	if err = indexWorkload(tx, bucket.Get([]byte(w.ID)), w); err != nil {
+		return err
+	}
 
-		// add entry to mapping bucket if UUID exists
-		if len(w.UUID) > 0 {
-			mb := tx.Bucket([]byte(MappingTableName))
-			err = mb.Put([]byte(w.UUID), []byte(w.ID))
-			if err != nil {
-				return errors.New("Failed to add workload mapping")
-			}
+	// add entry to mapping bucket if UUID exists
+	if len(w.UUID) > 0 {
+		mb := tx.Bucket([]byte(MappingTableName))
+		err = mb.Put([]byte(w.UUID), []byte(w.ID))
+		if err != nil {
+			return errors.New("Failed to add workload mapping")
 		}
+	}
 
-		// Persist bytes to users bucket.
-		return bucket.Put([]byte(w.ID), buf)
-	})
+	// Persist bytes to users bucket.
+	return bucket.Put([]byte(w.ID), buf)
 }
 
 // DeleteWorkload removes workload from db
@@ -144,25 +170,68 @@ func (b *BoltDB) DeleteWorkload(w *wltypes.RDTWorkLoad) error {
 	}
 
 	return b.session.Update(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(WorkloadTableName))
-		if bucket == nil {
-			return errors.New("Bucket 'bucket' creation failed")
+		return removeWorkload(tx, w)
+	})
+}
+
+// removeWorkload removes workload (and its UUID mapping) in transaction
+func removeWorkload(tx *bolt.Tx, w *wltypes.RDTWorkLoad) error {
+	bucket := tx.Bucket([]byte(WorkloadTableName))
+	if bucket == nil {
+		return errors.New("Bucket 'bucket' creation failed")
+	}
+
+	if len(w.UUID) > 0 {
+		mb := tx.Bucket([]byte(MappingTableName))
+		if mb == nil {
+			return errors.New("Bucket 'mba' mcreation failed")
 		}
 
-		if len(w.UUID) > 0 {
-			mb := tx.Bucket([]byte(MappingTableName))
-			if mb == nil {
-				return errors.New("Bucket 'mba' mcreation failed")
-			}
+		err := mb.Delete([]byte(w.UUID))
+		if err != nil {
+			return errors.New("Failed to delete mapping for given UUID")
+		}
+	}
 
-			err := mb.Delete([]byte(w.UUID))
-			if err != nil {
-				return errors.New("Failed to delete mapping for given UUID")
+	if err := indexWorkload(tx, bucket.Get([]byte(w.ID)), nil); err != nil {
+		return err
+	}
+	return bucket.Delete([]byte(w.ID))
+}
+
+// CommitWorkloads creates and deletes workloads in single transaction,
+// none of the changes is stored if any of them fails
+func (b *BoltDB) CommitWorkloads(created, deleted []*wltypes.RDTWorkLoad) error {
+	ids := make([]string, len(created))
+	err := b.session.Update(func(tx *bolt.Tx) error {
+		for _, w := range deleted {
+			if w == nil {
+				return errors.New("NIL workload given")
+			}
+			if err := removeWorkload(tx, w); err != nil {
+				return err
 			}
 		}
-
-		return bucket.Delete([]byte(w.ID))
+		for i, w := range created {
+			if w == nil {
+				return errors.New("NIL workload given")
+			}
+			ids[i] = w.ID
+			if err := putWorkload(tx, w); err != nil {
+				return err
+			}
+		}
+		return nil
 	})
+	if err != nil {
+		// IDs generated in rolled back transaction are not valid
+		for i, w := range created {
+			if w != nil {
+				w.ID = ids[i]
+			}
+		}
+	}
+	return err
 }
 
 // UpdateWorkload updates
@@ -185,6 +254,9 @@ func (b *BoltDB) UpdateWorkload(w *wltypes.RDTWorkLoad) error {
 		if err != nil {
 			return err
 		}
+		if err = indexWorkload(tx, bucket.Get([]byte(w.ID)), w); err != nil {
+			return err
+		}
 
 		return bucket.Put([]byte(w.ID), buf)
 	})
@@ -261,6 +333,136 @@ func (b *BoltDB) QueryWorkload(query map[string]interface{}) ([]wltypes.RDTWorkL
 	return rws, nil
 }
 
+// idKeyLen is length of numeric ID in index key (max length of uint64 number)
+const idKeyLen = 20
+
+// indexKey returns key of workload in index of sort field. Keys are ordered by value
+// of the field and then by ID, numeric IDs are zero padded so they are ordered as numbers.
+func indexKey(w *wltypes.RDTWorkLoad, field string) []byte {
+	id := w.ID
+	if isNumeric(id) && len(id) < idKeyLen {
+		id = strings.Repeat("0", idKeyLen-len(id)) + id
+	}
+	return []byte(sortValue(w, field) + "\x00" + id)
+}
+
+// indexWorkload replaces index entries of stored workload (old, if any) with entries
+// of workload w (removes them only if w is nil)
+func indexWorkload(tx *bolt.Tx, old []byte, w *wltypes.RDTWorkLoad) error {
+	ib := tx.Bucket([]byte(WorkloadIndexTableName))
+	if ib == nil {
+		return errors.New("Bucket fetching failed")
+	}
+	prev := &wltypes.RDTWorkLoad{}
+	if old != nil {
+		if err := json.Unmarshal(old, prev); err != nil {
+			return err
+		}
+	}
+	for _, field := range SortFields {
+		fb := ib.Bucket([]byte(field))
+		if fb == nil {
+			return errors.New("Bucket fetching failed")
+		}
+		if old != nil {
+			if err := fb.Delete(indexKey(prev, field)); err != nil {
+				return err
+			}
+		}
+		if w != nil {
+			if err := fb.Put(indexKey(w, field), []byte(w.ID)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// buildIndexes creates indexes of all sort fields for stored workloads
+func buildIndexes(tx *bolt.Tx) error {
+	ib, err := tx.CreateBucket([]byte(WorkloadIndexTableName))
+	if err != nil {
+		return err
+	}
+	for _, field := range SortFields {
+		if _, err = ib.CreateBucket([]byte(field)); err != nil {
+			return err
+		}
+	}
+	return tx.Bucket([]byte(WorkloadTableName)).ForEach(func(k, v []byte) error {
+		w := &wltypes.RDTWorkLoad{}
+		if err := json.Unmarshal(v, w); err != nil {
+			return err
+		}
+		return indexWorkload(tx, nil, w)
+	})
+}
+
+// ListWorkloads returns page of workloads matching filter (in order of index of sort field)
+// and index key to continue from if there are more matching workloads
+func (b *BoltDB) ListWorkloads(opts ListOptions) ([]wltypes.RDTWorkLoad, string, error) {
+	ws := []wltypes.RDTWorkLoad{}
+	next := ""
+	field := opts.Sort
+	if len(field) == 0 {
+		field = "id"
+	}
+	err := b.session.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(WorkloadTableName))
+		ib := tx.Bucket([]byte(WorkloadIndexTableName))
+		if bucket == nil || ib == nil {
+			return errors.New("Bucket fetching failed")
+		}
+		fb := ib.Bucket([]byte(field))
+		if fb == nil {
+			return errors.New("Unsupported sort field " + field)
+		}
+		cursor := fb.Cursor()
+
+		first, step := cursor.First, cursor.Next
+		if opts.Descending {
+			first, step = cursor.Last, cursor.Prev
+		}
+		k, v := first()
+		if len(opts.Continue) > 0 {
+			k, v = cursor.Seek([]byte(opts.Continue))
+			if opts.Descending && k == nil {
+				// continue key is greater than any key
+				k, v = cursor.Last()
+			}
+			// skip the last workload of previous page
+			// (and the following key when seeking back in descending order)
+			for k != nil && (string(k) == opts.Continue ||
+				(opts.Descending && string(k) > opts.Continue)) {
+				k, v = step()
+			}
+		}
+
+		last := ""
+		for ; k != nil; k, v = step() {
+			data := bucket.Get(v)
+			if data == nil {
+				continue
+			}
+			w := wltypes.RDTWorkLoad{}
+			if err := json.Unmarshal(data, &w); err != nil {
+				return err
+			}
+			if !opts.Filter.matches(&w) {
+				continue
+			}
+			if opts.Limit > 0 && len(ws) == opts.Limit {
+				next = last
+				break
+			}
+			ws = append(ws, w)
+			last = string(k)
+		}
+		return nil
+	})
+	return ws, next, err
+}
+
 // GetWorkloadByUUID Returns workload specified by UUID (if such exists in DB)
 func (b *BoltDB) GetWorkloadByUUID(id string) (wltypes.RDTWorkLoad, error) {
 	w := wltypes.RDTWorkLoad{}
@@ -289,3 +491,139 @@ func (b *BoltDB) GetWorkloadByUUID(id string) (wltypes.RDTWorkLoad, error) {
 	})
 	return w, err
 }
+
+// revisionKey returns big endian key of event revision so that events are
+// ordered by revision in bucket
+func revisionKey(rev uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, rev)
+	return key
+}
+
+// AppendEvent stores event with next revision, only MaxEvents latest events are kept
+func (b *BoltDB) AppendEvent(e *evtypes.Event) error {
+	if e == nil {
+		return errors.New("NIL event given")
+	}
+
+	return b.session.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(EventTableName))
+		if bucket == nil {
+			return errors.New("Bucket fetching failed")
+		}
+		rev, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		e.Revision = rev
+		buf, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if err = bucket.Put(revisionKey(rev), buf); err != nil {
+			return err
+		}
+
+		// remove oldest events
+		if rev <= MaxEvents {
+			return nil
+		}
+		old := [][]byte{}
+		cursor := bucket.Cursor()
+		for k, _ := cursor.First(); k != nil && binary.BigEndian.Uint64(k) <= rev-MaxEvents; k, _ = cursor.Next() {
+			old = append(old, k)
+		}
+		for _, k := range old {
+			if err = bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetEvents returns stored events with revision greater than since
+func (b *BoltDB) GetEvents(since uint64) ([]evtypes.Event, error) {
+	es := []evtypes.Event{}
+	err := b.session.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(EventTableName))
+		if bucket == nil {
+			return errors.New("Bucket fetching failed")
+		}
+		cursor := bucket.Cursor()
+		for k, v := cursor.Seek(revisionKey(since + 1)); k != nil; k, v = cursor.Next() {
+			e := evtypes.Event{}
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			es = append(es, e)
+		}
+		return nil
+	})
+	return es, err
+}
+
+// policyKey returns key of policy for given CPU architecture
+func policyKey(arch, name string) []byte {
+	return []byte(arch + "/" + name)
+}
+
+// SavePolicy creates or replaces policy of given CPU architecture
+func (b *BoltDB) SavePolicy(arch, name string, m ptypes.Module) error {
+	if len(arch) == 0 || len(name) == 0 {
+		return errors.New("Missing policy architecture or name")
+	}
+
+	return b.session.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(PolicyTableName))
+		if bucket == nil {
+			return errors.New("Bucket fetching failed")
+		}
+		buf, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(policyKey(arch, name), buf)
+	})
+}
+
+// DeletePolicy removes policy of given CPU architecture
+func (b *BoltDB) DeletePolicy(arch, name string) error {
+	return b.session.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(PolicyTableName))
+		if bucket == nil {
+			return errors.New("Bucket fetching failed")
+		}
+		if bucket.Get(policyKey(arch, name)) == nil {
+			return errors.New("No policy found for given name")
+		}
+		return bucket.Delete(policyKey(arch, name))
+	})
+}
+
+// GetAllPolicies returns all policies stored in db grouped by CPU architecture
+func (b *BoltDB) GetAllPolicies() (ptypes.CPUArchitecture, error) {
+	result := ptypes.CPUArchitecture{}
+	err := b.session.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(PolicyTableName))
+		if bucket == nil {
+			return errors.New("Bucket fetching failed")
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			key := strings.SplitN(string(k), "/", 2)
+			if len(key) != 2 {
+				return errors.New("Invalid policy key")
+			}
+			m := ptypes.Module{}
+			if err := json.Unmarshal(v, &m); err != nil {
+				return err
+			}
+			if _, ok := result[key[0]]; !ok {
+				result[key[0]] = ptypes.Policy{}
+			}
+			result[key[0]][key[1]] = m
+			return nil
+		})
+	})
+	return result, err
+}
@@ -2,8 +2,12 @@ package db
 
 import (
 	"os"
+	"reflect"
+	"strconv"
 	"testing"
 
+	evtypes "github.com/intel/rmd/modules/events/types"
+	ptypes "github.com/intel/rmd/modules/policy/types"
 	workload "github.com/intel/rmd/modules/workload/types"
 	"github.com/spf13/viper"
 )
@@ -231,3 +235,285 @@ func TestBoltDB_CreateGetDeleteWorkload(t *testing.T) {
 		t.Errorf("Failed to remove 2nd workload")
 	}
 }
+
+func TestBoltDB_CommitWorkloads(t *testing.T) {
+	db := Setup(t)
+	err := db.Initialize("unused", "unused")
+	if err != nil {
+		t.Fatal("DB initialization failure - exiting test")
+	}
+
+	wrkld1 := workload.RDTWorkLoad{UUID: testuuid1 + "-batch", CoreIDs: []string{"20"}, Policy: "gold"}
+	wrkld2 := workload.RDTWorkLoad{CoreIDs: []string{"21"}, Policy: "silver"}
+	if err = db.CommitWorkloads([]*workload.RDTWorkLoad{&wrkld1, &wrkld2}, nil); err != nil {
+		t.Fatalf("Failed to commit new workloads: %v", err)
+	}
+	if len(wrkld1.ID) == 0 || len(wrkld2.ID) == 0 {
+		t.Fatalf("Workload IDs not generated: %q, %q", wrkld1.ID, wrkld2.ID)
+	}
+
+	// failed commit does not change anything
+	wrkld3 := workload.RDTWorkLoad{CoreIDs: []string{"22"}}
+	err = db.CommitWorkloads([]*workload.RDTWorkLoad{&wrkld3, nil}, []*workload.RDTWorkLoad{&wrkld1})
+	if err == nil {
+		t.Fatal("Commit with NIL workload succeeded")
+	}
+	if len(wrkld3.ID) != 0 {
+		t.Errorf("Workload ID %q left after failed commit", wrkld3.ID)
+	}
+	if _, err = db.GetWorkloadByUUID(wrkld1.UUID); err != nil {
+		t.Errorf("Workload deleted by failed commit: %v", err)
+	}
+
+	if err = db.CommitWorkloads(nil, []*workload.RDTWorkLoad{&wrkld1, &wrkld2}); err != nil {
+		t.Fatalf("Failed to commit deleted workloads: %v", err)
+	}
+	for _, w := range []workload.RDTWorkLoad{wrkld1, wrkld2} {
+		if _, err = db.GetWorkloadByID(w.ID); err == nil {
+			t.Errorf("Workload %s not deleted", w.ID)
+		}
+	}
+}
+
+func TestBoltDB_ListWorkloads(t *testing.T) {
+	db := Setup(t)
+	err := db.Initialize("unused", "unused")
+	if err != nil {
+		t.Fatal("DB initialization failure - exiting test")
+	}
+
+	for i := 1; i <= 5; i++ {
+		w := workload.RDTWorkLoad{ID: "list-" + strconv.Itoa(i), Origin: "LIST", Status: workload.Successful,
+			CoreIDs: []string{strconv.Itoa(30 + i)}, Policy: "gold"}
+		if i%2 == 0 {
+			w.Policy = "silver"
+		}
+		if err = db.CreateWorkload(&w); err != nil {
+			t.Fatalf("Failed to create workload: %v", err)
+		}
+	}
+
+	filter := WorkloadFilter{Origin: "LIST"}
+	silver := WorkloadFilter{Origin: "LIST", Policy: "silver"}
+	// key returns index key of workload with given value of sort field and ID
+	key := func(value, id string) string {
+		return value + "\x00" + id
+	}
+	tests := []struct {
+		name     string
+		opts     ListOptions
+		wantIDs  []string
+		wantNext string
+	}{
+		{"All", ListOptions{Filter: filter}, []string{"list-1", "list-2", "list-3", "list-4", "list-5"}, ""},
+		{"First page", ListOptions{Filter: filter, Limit: 2}, []string{"list-1", "list-2"}, key("", "list-2")},
+		{"Next page", ListOptions{Filter: filter, Limit: 2, Continue: key("", "list-2")},
+			[]string{"list-3", "list-4"}, key("", "list-4")},
+		{"Last page", ListOptions{Filter: filter, Limit: 2, Continue: key("", "list-4")}, []string{"list-5"}, ""},
+		{"Exact last page", ListOptions{Filter: filter, Limit: 1, Continue: key("", "list-4")}, []string{"list-5"}, ""},
+		{"Descending", ListOptions{Filter: filter, Limit: 2, Descending: true},
+			[]string{"list-5", "list-4"}, key("", "list-4")},
+		{"Descending next page", ListOptions{Filter: filter, Limit: 2, Continue: key("", "list-4"), Descending: true},
+			[]string{"list-3", "list-2"}, key("", "list-2")},
+		{"Continue from deleted", ListOptions{Filter: filter, Continue: key("", "list-35"), Descending: true},
+			[]string{"list-3", "list-2", "list-1"}, ""},
+		{"Filtered page", ListOptions{Filter: silver, Limit: 1}, []string{"list-2"}, key("", "list-2")},
+		{"Filtered next page", ListOptions{Filter: silver, Limit: 1, Continue: key("", "list-2")}, []string{"list-4"}, ""},
+		{"Core filter", ListOptions{Filter: WorkloadFilter{Origin: "LIST", CoreID: "33"}}, []string{"list-3"}, ""},
+		{"Match filter", ListOptions{Filter: WorkloadFilter{Origin: "LIST",
+			Match: func(w *workload.RDTWorkLoad) bool { return w.ID == "list-1" }}}, []string{"list-1"}, ""},
+		{"Sort by policy", ListOptions{Filter: filter, Limit: 3, Sort: "policy"},
+			[]string{"list-1", "list-3", "list-5"}, key("gold", "list-5")},
+		{"Sort by policy next page", ListOptions{Filter: filter, Sort: "policy", Continue: key("gold", "list-5")},
+			[]string{"list-2", "list-4"}, ""},
+		{"Sort by policy descending", ListOptions{Filter: filter, Limit: 2, Sort: "policy", Descending: true},
+			[]string{"list-4", "list-2"}, key("silver", "list-2")},
+		{"Sort by policy descending next page", ListOptions{Filter: filter, Limit: 2, Sort: "policy",
+			Continue: key("silver", "list-2"), Descending: true}, []string{"list-5", "list-3"}, key("gold", "list-3")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws, next, err := db.ListWorkloads(tt.opts)
+			if err != nil {
+				t.Fatalf("ListWorkloads() error = %v", err)
+			}
+			ids := []string{}
+			for _, w := range ws {
+				ids = append(ids, w.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) || next != tt.wantNext {
+				t.Errorf("ListWorkloads() = %v, %q, want %v, %q", ids, next, tt.wantIDs, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestValidateWorkloads(t *testing.T) {
+	existing := []workload.RDTWorkLoad{
+		{ID: "1", CoreIDs: []string{"1"}},
+		{ID: "2", TaskIDs: []string{"100"}},
+	}
+	tests := []struct {
+		name    string
+		ws      []workload.RDTWorkLoad
+		wantErr bool
+	}{
+		{"Independent workloads", []workload.RDTWorkLoad{{CoreIDs: []string{"2"}}, {TaskIDs: []string{"101"}}}, false},
+		{"Core used by existing workload", []workload.RDTWorkLoad{{CoreIDs: []string{"1"}}}, true},
+		{"Task used by existing workload", []workload.RDTWorkLoad{{TaskIDs: []string{"100"}}}, true},
+		{"Same cores in batch", []workload.RDTWorkLoad{{CoreIDs: []string{"3"}}, {CoreIDs: []string{"3-4"}}}, true},
+		{"Same task in batch", []workload.RDTWorkLoad{{TaskIDs: []string{"102"}}, {TaskIDs: []string{"102"}}}, true},
+		{"Same UUID in batch", []workload.RDTWorkLoad{{UUID: testuuid1, CoreIDs: []string{"5"}},
+			{UUID: testuuid1, CoreIDs: []string{"6"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateWorkloads(tt.ws, existing); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateWorkloads() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(existing) != 2 {
+				t.Errorf("ValidateWorkloads() changed existing workloads: %v", existing)
+			}
+		})
+	}
+}
+
+func TestBoltDB_Events(t *testing.T) {
+	db := Setup(t)
+	err := db.Initialize("unused", "unused")
+	if err != nil {
+		t.Fatal("DB initialization failure - exiting test")
+	}
+
+	// add more events than kept in db
+	var last uint64
+	for i := 0; i < MaxEvents+5; i++ {
+		e := evtypes.Event{Type: evtypes.WorkloadCreated, WorkloadID: strconv.Itoa(i)}
+		if err := db.AppendEvent(&e); err != nil {
+			t.Fatalf("Failed to add event: %v", err.Error())
+		}
+		if e.Revision != last+1 && last != 0 {
+			t.Fatalf("Event revision %d, expected %d", e.Revision, last+1)
+		}
+		last = e.Revision
+	}
+
+	tests := []struct {
+		name      string
+		since     uint64
+		wantCount int
+		wantFirst uint64
+	}{
+		{"All kept events", 0, MaxEvents, last - MaxEvents + 1},
+		{"Events since revision", last - 2, 2, last - 1},
+		{"No new events", last, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es, err := db.GetEvents(tt.since)
+			if err != nil {
+				t.Fatalf("BoltDB.GetEvents() error = %v", err)
+			}
+			if len(es) != tt.wantCount {
+				t.Fatalf("BoltDB.GetEvents() returned %d events, want %d", len(es), tt.wantCount)
+			}
+			if len(es) > 0 && es[0].Revision != tt.wantFirst {
+				t.Errorf("BoltDB.GetEvents() first revision = %d, want %d", es[0].Revision, tt.wantFirst)
+			}
+		})
+	}
+}
+
+func TestBoltDB_Policies(t *testing.T) {
+	db := Setup(t)
+	err := db.Initialize("unused", "unused")
+	if err != nil {
+		t.Fatal("DB initialization failure - exiting test")
+	}
+
+	gold := ptypes.Module{"cache": ptypes.Param{"max": float64(4), "min": float64(4)}}
+	silver := ptypes.Module{"cache": ptypes.Param{"max": float64(2), "min": float64(1)}}
+	if err := db.SavePolicy("skylake", "gold", gold); err != nil {
+		t.Fatalf("Failed to save policy: %v", err)
+	}
+	if err := db.SavePolicy("skylake", "silver", silver); err != nil {
+		t.Fatalf("Failed to save policy: %v", err)
+	}
+	if err := db.SavePolicy("broadwell", "gold", silver); err != nil {
+		t.Fatalf("Failed to save policy: %v", err)
+	}
+	if err := db.DeletePolicy("skylake", "silver"); err != nil {
+		t.Errorf("Failed to delete policy: %v", err)
+	}
+	if err := db.DeletePolicy("skylake", "bronze"); err == nil {
+		t.Errorf("Not existing policy deleted")
+	}
+
+	got, err := db.GetAllPolicies()
+	if err != nil {
+		t.Fatalf("Failed to get policies: %v", err)
+	}
+	want := ptypes.CPUArchitecture{
+		"skylake":   ptypes.Policy{"gold": gold},
+		"broadwell": ptypes.Policy{"gold": silver},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BoltDB.GetAllPolicies() = %v, want %v", got, want)
+	}
+}
+
+func TestBoltDB_ListWorkloads_index(t *testing.T) {
+	db := Setup(t)
+	err := db.Initialize("unused", "unused")
+	if err != nil {
+		t.Fatal("DB initialization failure - exiting test")
+	}
+
+	// generated numeric IDs are listed as numbers
+	ws := make([]workload.RDTWorkLoad, 11)
+	for i := range ws {
+		ws[i] = workload.RDTWorkLoad{Origin: "INDEX", Status: workload.Successful}
+		if err = db.CreateWorkload(&ws[i]); err != nil {
+			t.Fatalf("Failed to create workload: %v", err)
+		}
+	}
+	// index follows changed and removed workloads
+	ws[10].Status = workload.Failed
+	if err = db.UpdateWorkload(&ws[10]); err != nil {
+		t.Fatalf("Failed to update workload: %v", err)
+	}
+	if err = db.DeleteWorkload(&ws[0]); err != nil {
+		t.Fatalf("Failed to delete workload: %v", err)
+	}
+
+	filter := WorkloadFilter{Origin: "INDEX"}
+	tests := []struct {
+		name    string
+		opts    ListOptions
+		wantIDs []string
+	}{
+		{"Numeric IDs", ListOptions{Filter: filter, Limit: 3, Descending: true},
+			[]string{ws[10].ID, ws[9].ID, ws[8].ID}},
+		{"Changed status", ListOptions{Filter: filter, Limit: 2, Sort: "status"}, []string{ws[10].ID, ws[1].ID}},
+		{"Removed workload", ListOptions{Filter: filter, Limit: 1}, []string{ws[1].ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := db.ListWorkloads(tt.opts)
+			if err != nil {
+				t.Fatalf("ListWorkloads() error = %v", err)
+			}
+			ids := []string{}
+			for _, w := range got {
+				ids = append(ids, w.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("ListWorkloads() = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+
+	if _, _, err = db.ListWorkloads(ListOptions{Sort: "cos_name"}); err == nil {
+		t.Error("ListWorkloads() sorted by unsupported field, want error")
+	}
+}
@@ -7,6 +7,8 @@ import (
 	// from app import an config is really not a good idea.
 	// uncouple it from APP. Or we can add it in a rmd/config
 	"github.com/intel/rmd/internal/db/config"
+	evtypes "github.com/intel/rmd/modules/events/types"
+	ptypes "github.com/intel/rmd/modules/policy/types"
 	wltypes "github.com/intel/rmd/modules/workload/types"
 	util "github.com/intel/rmd/utils"
 	libutil "github.com/intel/rmd/utils/bitmap"
@@ -18,17 +20,108 @@ const WorkloadTableName = "workload"
 // MappingTableName contains mapping between UUID and WorkloadID
 const MappingTableName = "mapping"
 
+// EventTableName is the table name for workload and resource events
+const EventTableName = "event"
+
+// MaxEvents is number of latest events kept in db
+const MaxEvents = 1000
+
+// PolicyTableName is the table name for policies defined at runtime
+const PolicyTableName = "policy"
+
+// WorkloadIndexTableName contains indexes of workloads per sort field
+const WorkloadIndexTableName = "workload_index"
+
 // DB is the interface for a db engine
 type DB interface {
 	Initialize(transport, dbname string) error
 	CreateWorkload(w *wltypes.RDTWorkLoad) error
 	DeleteWorkload(w *wltypes.RDTWorkLoad) error
 	UpdateWorkload(w *wltypes.RDTWorkLoad) error
+	CommitWorkloads(created, deleted []*wltypes.RDTWorkLoad) error
 	GetAllWorkload() ([]wltypes.RDTWorkLoad, error)
 	GetWorkloadByID(id string) (wltypes.RDTWorkLoad, error)
 	GetWorkloadByUUID(id string) (wltypes.RDTWorkLoad, error)
 	ValidateWorkload(w *wltypes.RDTWorkLoad) error
 	QueryWorkload(query map[string]interface{}) ([]wltypes.RDTWorkLoad, error)
+	ListWorkloads(opts ListOptions) ([]wltypes.RDTWorkLoad, string, error)
+	AppendEvent(e *evtypes.Event) error
+	GetEvents(since uint64) ([]evtypes.Event, error)
+	SavePolicy(arch, name string, m ptypes.Module) error
+	DeletePolicy(arch, name string) error
+	GetAllPolicies() (ptypes.CPUArchitecture, error)
+}
+
+// WorkloadFilter selects workloads returned by ListWorkloads,
+// empty fields match all workloads
+type WorkloadFilter struct {
+	Status string
+	Origin string
+	Policy string
+	// CoreID and TaskID have to be one of workload core (task) ids
+	CoreID string
+	TaskID string
+	// Match is additional condition checked for every workload (if set)
+	Match func(w *wltypes.RDTWorkLoad) bool
+}
+
+// ListOptions define page of workloads returned by ListWorkloads
+type ListOptions struct {
+	Filter WorkloadFilter
+	// Limit is max number of returned workloads, 0 means no limit
+	Limit int
+	// Continue is position (index key) of the last workload of previous page returned by ListWorkloads
+	Continue string
+	// Sort is workload field (id, status, policy or origin) defining order of workloads,
+	// workloads with the same value of the field are ordered by ID (empty means id)
+	Sort string
+	// Descending reverses order of workloads
+	Descending bool
+}
+
+// SortFields are workload fields supported by ListOptions.Sort
+var SortFields = []string{"id", "status", "policy", "origin"}
+
+// sortValue returns value of workload field used to sort workloads
+func sortValue(w *wltypes.RDTWorkLoad, field string) string {
+	switch field {
+	case "status":
+		return w.Status
+	case "policy":
+		return w.Policy
+	case "origin":
+		return w.Origin
+	}
+	return ""
+}
+
+// isNumeric checks if string has only decimal digits
+func isNumeric(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// matches checks if workload meets all conditions of filter
+func (f *WorkloadFilter) matches(w *wltypes.RDTWorkLoad) bool {
+	if (len(f.Status) > 0 && w.Status != f.Status) ||
+		(len(f.Origin) > 0 && w.Origin != f.Origin) ||
+		(len(f.Policy) > 0 && w.Policy != f.Policy) {
+		return false
+	}
+	if len(f.CoreID) > 0 && !util.HasElem(w.CoreIDs, f.CoreID) {
+		return false
+	}
+	if len(f.TaskID) > 0 && !util.HasElem(w.TaskIDs, f.TaskID) {
+		return false
+	}
+	return f.Match == nil || f.Match(w)
 }
 
 // NewDB return DB connection
@@ -44,6 +137,20 @@ func NewDB() (DB, error) {
 	}
 }
 
+// ValidateWorkloads validates workloads created together against existing
+// workloads and against each other
+func ValidateWorkloads(ws, existing []wltypes.RDTWorkLoad) error {
+	all := make([]wltypes.RDTWorkLoad, len(existing), len(existing)+len(ws))
+	copy(all, existing)
+	for i, w := range ws {
+		if err := validateWorkload(w, all); err != nil {
+			return fmt.Errorf("Workload %d: %v", i, err)
+		}
+		all = append(all, w)
+	}
+	return nil
+}
+
 // this function does 3 things to validate a user request workload is
 // validate at data base layer
 func validateWorkload(w wltypes.RDTWorkLoad, ws []wltypes.RDTWorkLoad) error {
@@ -103,24 +210,8 @@ func validateWorkload(w wltypes.RDTWorkLoad, ws []wltypes.RDTWorkLoad) error {
 	}
 
 	bminter := bm.And(bmsum)
-
-	var coreBits uint32
-	if bminter.Len > 0 {
-		coreBits = uint32(bminter.Bits[0])
-	}
-	var cores = []string{}
-	if bminter.Len > 0 && coreBits > 0 {
-		var bit uint32
-		for bit = 0; bit < uint32(bminter.Len); bit++ {
-			if (coreBits % 2) == 1 {
-				cores = append(cores, fmt.Sprint(bit))
-			}
-			coreBits = coreBits / 2
-		}
-	}
-
 	if !bminter.IsEmpty() {
-		return fmt.Errorf("CPU list %s has been assigned", cores)
+		return fmt.Errorf("CPU list %s has been assigned", bminter.ToHumanString())
 	}
 
 	return nil
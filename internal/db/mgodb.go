@@ -8,6 +8,8 @@ import (
 	"github.com/globalsign/mgo/bson"
 
 	"github.com/intel/rmd/internal/db/config"
+	evtypes "github.com/intel/rmd/modules/events/types"
+	ptypes "github.com/intel/rmd/modules/policy/types"
 	wltypes "github.com/intel/rmd/modules/workload/types"
 )
 
@@ -94,6 +96,16 @@ func (m *MgoDB) DeleteWorkload(w *wltypes.RDTWorkLoad) error {
 	return nil
 }
 
+// CommitWorkloads creates and deletes workloads in single transaction
+func (m *MgoDB) CommitWorkloads(created, deleted []*wltypes.RDTWorkLoad) error {
+	return errors.New("Transactions not supported by mongo backend")
+}
+
+// ListWorkloads returns page of workloads matching filter
+func (m *MgoDB) ListWorkloads(opts ListOptions) ([]wltypes.RDTWorkLoad, string, error) {
+	return nil, "", errors.New("Listing workloads not supported by mongo backend")
+}
+
 // UpdateWorkload updates
 func (m *MgoDB) UpdateWorkload(w *wltypes.RDTWorkLoad) error {
 	// not implement yet
@@ -138,3 +150,28 @@ func (m *MgoDB) GetWorkloadByUUID(id string) (wltypes.RDTWorkLoad, error) {
 	w := wltypes.RDTWorkLoad{}
 	return w, errors.New("Not yet implemented")
 }
+
+// AppendEvent stores event in db
+func (m *MgoDB) AppendEvent(e *evtypes.Event) error {
+	return errors.New("Not yet implemented")
+}
+
+// GetEvents returns events with revision greater than since
+func (m *MgoDB) GetEvents(since uint64) ([]evtypes.Event, error) {
+	return []evtypes.Event{}, errors.New("Not yet implemented")
+}
+
+// SavePolicy creates or replaces policy of given CPU architecture
+func (m *MgoDB) SavePolicy(arch, name string, policy ptypes.Module) error {
+	return errors.New("Not yet implemented")
+}
+
+// DeletePolicy removes policy of given CPU architecture
+func (m *MgoDB) DeletePolicy(arch, name string) error {
+	return errors.New("Not yet implemented")
+}
+
+// GetAllPolicies returns all policies stored in db grouped by CPU architecture
+func (m *MgoDB) GetAllPolicies() (ptypes.CPUArchitecture, error) {
+	return ptypes.CPUArchitecture{}, errors.New("Not yet implemented")
+}
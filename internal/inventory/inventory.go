@@ -18,6 +18,61 @@ type Capability struct {
 	Available bool   `json:"available"`
 }
 
+// Platform is the full platform capability inventory
+type Platform struct {
+	CPU CPUInfo `json:"cpu"`
+	// Features maps feature name (ex. l3cat, cdp, mba) to its availability
+	Features map[string]bool `json:"features"`
+	// Resources maps resctrl resource name (ex. L3, L3CODE, L2, MB) to its info
+	Resources  map[string]ResourceInfo `json:"resources"`
+	Monitoring *MonitoringInfo         `json:"monitoring,omitempty"`
+	Topology   Topology                `json:"topology"`
+	// Plugins maps loaded plugin name to capabilities reported by the plugin
+	Plugins map[string][]string `json:"plugins"`
+}
+
+// CPUInfo describes host processor
+type CPUInfo struct {
+	Signature string `json:"signature"`
+	MicroArch string `json:"microarch"`
+}
+
+// ResourceInfo is resctrl allocation resource info (/sys/fs/resctrl/info/<resource>)
+type ResourceInfo struct {
+	NumClosids    int    `json:"num_closids"`
+	CbmMask       string `json:"cbm_mask,omitempty"`
+	MinCbmBits    int    `json:"min_cbm_bits,omitempty"`
+	ShareableBits string `json:"shareable_bits,omitempty"`
+	// MBA only
+	BandwidthGran int  `json:"bandwidth_gran,omitempty"`
+	MinBandwidth  int  `json:"min_bandwidth,omitempty"`
+	DelayLinear   bool `json:"delay_linear,omitempty"`
+}
+
+// MonitoringInfo is resctrl monitoring info (/sys/fs/resctrl/info/L3_MON)
+type MonitoringInfo struct {
+	NumRmids    int      `json:"num_rmids"`
+	MonFeatures []string `json:"mon_features"`
+}
+
+// Topology groups host CPUs by socket, NUMA node and last level cache
+type Topology struct {
+	// CPU lists (ex. "0-15,32-47") per socket id
+	Sockets map[string]string `json:"sockets"`
+	// CPU lists per NUMA node id
+	NUMANodes map[string]string `json:"numa_nodes"`
+	// last level caches per cache id
+	LLCs map[string]LLC `json:"llcs"`
+}
+
+// LLC describes single last level cache
+type LLC struct {
+	Level string `json:"level"`
+	CPUs  string `json:"cpus"`
+	Size  string `json:"size"`
+	Ways  int    `json:"ways"`
+}
+
 // Register registers REST endpoint
 func (i *Inventory) Register(container *restful.Container) {
 	ws := new(restful.WebService)
@@ -29,19 +84,13 @@ func (i *Inventory) Register(container *restful.Container) {
 
 	// register handlers for supported GET actions
 	ws.Route(ws.GET("/").To(i.GetCapabilities).
-		Doc("Get the pstates for all CPU cores").
-		Operation("PstateGetAll"))
+		Doc("Get RDT features, resctrl resources, topology and plugin capabilities").
+		Operation("InventoryGet"))
 
 	container.Add(ws)
 }
 
 // GetCapabilities return inventory capabilites
 func (i *Inventory) GetCapabilities(request *restful.Request, response *restful.Response) {
-	// response.WriteError(http.StatusNoContent, nil)
-	result := []Capability{}
-
-	// call each inventory checking function and add returned data to result
-	result = append(result, CheckRDT(), CheckScaling())
-
-	response.WriteHeaderAndEntity(http.StatusOK, result)
+	response.WriteHeaderAndEntity(http.StatusOK, GetPlatform())
 }
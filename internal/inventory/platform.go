@@ -0,0 +1,202 @@
+package inventory
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intel/rmd/internal/plugins"
+	"github.com/intel/rmd/utils/cpu"
+	"github.com/intel/rmd/utils/proc"
+	"github.com/intel/rmd/utils/resctrl"
+)
+
+// sysCPUPath is the path to cpu devices, variable to allow testing
+var sysCPUPath = cpu.SysCPU
+
+// GetPlatform collects full platform inventory
+func GetPlatform() Platform {
+	sig := cpu.GetSignature()
+	return Platform{
+		CPU: CPUInfo{
+			Signature: fmt.Sprintf("0x%x", sig),
+			MicroArch: cpu.GetMicroArch(sig),
+		},
+		Features:   getFeatures(),
+		Resources:  getResources(filepath.Join(resctrl.SysResctrl, "info")),
+		Monitoring: getMonitoring(filepath.Join(resctrl.SysResctrl, "info")),
+		Topology:   getTopology(sysCPUPath),
+		Plugins:    getPluginCapabilities(),
+	}
+}
+
+func getFeatures() map[string]bool {
+	checks := map[string]func() (bool, error){
+		"rdt":   proc.IsRdtAvailable,
+		"l3cat": proc.IsL3CatAvailable,
+		"l2cat": proc.IsL2CatAvailable,
+		"cdp":   proc.IsCdpAvailable,
+		"mba":   proc.IsMbaAvailable,
+		"cmt":   proc.IsCqmAvailable,
+		"mbm":   proc.IsMbmAvailable,
+	}
+	features := make(map[string]bool, len(checks)+3)
+	for name, check := range checks {
+		available, err := check()
+		if err != nil {
+			log.Errorf("Failed to check %s feature: %v", name, err)
+		}
+		features[name] = available
+	}
+	// features enabled in resctrl mount options
+	features["cdp_enabled"] = proc.IsEnableCdp()
+	features["mba_mbps"] = proc.IsEnableMbaMbps()
+	features["pstate"] = CheckScaling().Available
+	return features
+}
+
+// readInfoFile returns trimmed content of resctrl info file, empty if missing
+func readInfoFile(dir, name string) string {
+	data, err := ioutil.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func readInfoInt(dir, name string) int {
+	val, _ := strconv.Atoi(readInfoFile(dir, name))
+	return val
+}
+
+// getResources reads info of all allocation resources (L3, L3CODE, L3DATA, L2, MB...)
+func getResources(infoPath string) map[string]ResourceInfo {
+	resources := make(map[string]ResourceInfo)
+	dirs, err := ioutil.ReadDir(infoPath)
+	if err != nil {
+		log.Errorf("Failed to read resctrl info: %v", err)
+		return resources
+	}
+	for _, d := range dirs {
+		dir := filepath.Join(infoPath, d.Name())
+		// monitoring resources (ex. L3_MON) have no CLOS ids
+		if !d.IsDir() || len(readInfoFile(dir, "num_closids")) == 0 {
+			continue
+		}
+		resources[d.Name()] = ResourceInfo{
+			NumClosids:    readInfoInt(dir, "num_closids"),
+			CbmMask:       readInfoFile(dir, "cbm_mask"),
+			MinCbmBits:    readInfoInt(dir, "min_cbm_bits"),
+			ShareableBits: readInfoFile(dir, "shareable_bits"),
+			BandwidthGran: readInfoInt(dir, "bandwidth_gran"),
+			MinBandwidth:  readInfoInt(dir, "min_bandwidth"),
+			DelayLinear:   readInfoFile(dir, "delay_linear") == "1",
+		}
+	}
+	return resources
+}
+
+// getMonitoring reads L3 monitoring info, nil if monitoring is not available
+func getMonitoring(infoPath string) *MonitoringInfo {
+	dir := filepath.Join(infoPath, "L3_MON")
+	if len(readInfoFile(dir, "num_rmids")) == 0 {
+		return nil
+	}
+	return &MonitoringInfo{
+		NumRmids:    readInfoInt(dir, "num_rmids"),
+		MonFeatures: strings.Fields(readInfoFile(dir, "mon_features")),
+	}
+}
+
+// getTopology groups CPUs found in sysfs by socket, NUMA node and last level cache
+func getTopology(cpuPath string) Topology {
+	topo := Topology{
+		Sockets:   map[string]string{},
+		NUMANodes: map[string]string{},
+		LLCs:      map[string]LLC{},
+	}
+	cpuDirs, _ := filepath.Glob(filepath.Join(cpuPath, "cpu[0-9]*"))
+	sockets := map[string][]int{}
+	nodes := map[string][]int{}
+	for _, dir := range cpuDirs {
+		id, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "cpu"))
+		if err != nil {
+			continue
+		}
+		// offline CPUs have no topology
+		socket := readInfoFile(dir, "topology/physical_package_id")
+		if len(socket) == 0 {
+			continue
+		}
+		sockets[socket] = append(sockets[socket], id)
+		if nodeDirs, _ := filepath.Glob(filepath.Join(dir, "node[0-9]*")); len(nodeDirs) > 0 {
+			node := strings.TrimPrefix(filepath.Base(nodeDirs[0]), "node")
+			nodes[node] = append(nodes[node], id)
+		}
+
+		// last level cache is the cache index with the highest level
+		llc := LLC{}
+		llcID := ""
+		indexDirs, _ := filepath.Glob(filepath.Join(dir, "cache/index[0-9]*"))
+		for _, index := range indexDirs {
+			level := readInfoFile(index, "level")
+			if level > llc.Level {
+				ways, _ := strconv.Atoi(readInfoFile(index, "ways_of_associativity"))
+				llc = LLC{Level: level, CPUs: readInfoFile(index, "shared_cpu_list"),
+					Size: readInfoFile(index, "size"), Ways: ways}
+				llcID = readInfoFile(index, "id")
+			}
+		}
+		if len(llc.Level) > 0 && len(llcID) > 0 {
+			topo.LLCs[llcID] = llc
+		}
+	}
+	for socket, cpus := range sockets {
+		topo.Sockets[socket] = formatCPUList(cpus)
+	}
+	for node, cpus := range nodes {
+		topo.NUMANodes[node] = formatCPUList(cpus)
+	}
+	return topo
+}
+
+// formatCPUList returns CPU list in kernel format (ex. "0-3,8,10-11")
+func formatCPUList(cpus []int) string {
+	sort.Ints(cpus)
+	ranges := []string{}
+	for i := 0; i < len(cpus); {
+		j := i
+		for j+1 < len(cpus) && cpus[j+1] == cpus[j]+1 {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, strconv.Itoa(cpus[i]))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", cpus[i], cpus[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, ",")
+}
+
+func getPluginCapabilities() map[string][]string {
+	result := make(map[string][]string, len(plugins.Interfaces))
+	for name, iface := range plugins.Interfaces {
+		if iface == nil {
+			continue
+		}
+		caps := []string{}
+		for _, c := range strings.Split(iface.GetCapabilities(), ",") {
+			if c = strings.TrimSpace(c); len(c) > 0 {
+				caps = append(caps, c)
+			}
+		}
+		result[name] = caps
+	}
+	return result
+}
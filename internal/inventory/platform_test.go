@@ -0,0 +1,121 @@
+package inventory
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func Test_formatCPUList(t *testing.T) {
+	tests := []struct {
+		name string
+		cpus []int
+		want string
+	}{
+		{"Single CPU", []int{3}, "3"},
+		{"Range", []int{0, 1, 2, 3}, "0-3"},
+		{"Unsorted ranges and single CPUs", []int{11, 8, 0, 1, 10, 2, 3}, "0-3,8,10-11"},
+		{"No CPUs", []int{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatCPUList(tt.cpus); got != tt.want {
+				t.Errorf("formatCPUList() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getResourcesAndMonitoring(t *testing.T) {
+	root, err := ioutil.TempDir("", "resctrl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	writeFiles(t, root, map[string]string{
+		"L3/num_closids":      "16",
+		"L3/cbm_mask":         "7ff",
+		"L3/min_cbm_bits":     "1",
+		"L3/shareable_bits":   "600",
+		"MB/num_closids":      "8",
+		"MB/bandwidth_gran":   "10",
+		"MB/min_bandwidth":    "10",
+		"MB/delay_linear":     "1",
+		"L3_MON/num_rmids":    "224",
+		"L3_MON/mon_features": "llc_occupancy\nmbm_total_bytes\nmbm_local_bytes",
+		"last_cmd_status":     "ok",
+	})
+
+	want := map[string]ResourceInfo{
+		"L3": {NumClosids: 16, CbmMask: "7ff", MinCbmBits: 1, ShareableBits: "600"},
+		"MB": {NumClosids: 8, BandwidthGran: 10, MinBandwidth: 10, DelayLinear: true},
+	}
+	if got := getResources(root); !reflect.DeepEqual(got, want) {
+		t.Errorf("getResources() = %v, want %v", got, want)
+	}
+
+	wantMon := &MonitoringInfo{NumRmids: 224,
+		MonFeatures: []string{"llc_occupancy", "mbm_total_bytes", "mbm_local_bytes"}}
+	if got := getMonitoring(root); !reflect.DeepEqual(got, wantMon) {
+		t.Errorf("getMonitoring() = %v, want %v", got, wantMon)
+	}
+	if got := getMonitoring(filepath.Join(root, "none")); got != nil {
+		t.Errorf("getMonitoring() = %v, want nil", got)
+	}
+}
+
+func Test_getTopology(t *testing.T) {
+	root, err := ioutil.TempDir("", "cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	files := map[string]string{}
+	for cpu, socket := range map[string]string{"0": "0", "1": "0", "2": "1", "3": "1"} {
+		dir := "cpu" + cpu + "/"
+		files[dir+"topology/physical_package_id"] = socket
+		files[dir+"node"+socket+"/cpulist"] = ""
+		files[dir+"cache/index2/level"] = "2"
+		files[dir+"cache/index2/id"] = cpu
+		files[dir+"cache/index3/level"] = "3"
+		files[dir+"cache/index3/id"] = socket
+		files[dir+"cache/index3/size"] = "16384K"
+		files[dir+"cache/index3/ways_of_associativity"] = "11"
+		if socket == "0" {
+			files[dir+"cache/index3/shared_cpu_list"] = "0-1"
+		} else {
+			files[dir+"cache/index3/shared_cpu_list"] = "2-3"
+		}
+	}
+	// offline CPU
+	files["cpu4/online"] = "0"
+	writeFiles(t, root, files)
+
+	want := Topology{
+		Sockets:   map[string]string{"0": "0-1", "1": "2-3"},
+		NUMANodes: map[string]string{"0": "0-1", "1": "2-3"},
+		LLCs: map[string]LLC{
+			"0": {Level: "3", CPUs: "0-1", Size: "16384K", Ways: 11},
+			"1": {Level: "3", CPUs: "2-3", Size: "16384K", Ways: 11},
+		},
+	}
+	if got := getTopology(root); !reflect.DeepEqual(got, want) {
+		t.Errorf("getTopology() = %+v, want %+v", got, want)
+	}
+}
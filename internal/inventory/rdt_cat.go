@@ -26,7 +26,7 @@ var rdtResult Capability
 // CheckRDT checks if CAT_RDT support exist on host
 func CheckRDT() Capability {
 	rdtOneGuard.Do(func() {
-		rdtResult = Capability{Name: "pstate", Available: false}
+		rdtResult = Capability{Name: "rdt", Available: false}
 		// check platform
 		rdtResult.Available = checkKernelCmd() && checkCPUFlag() && checkResctrlFs()
 	})
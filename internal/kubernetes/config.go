@@ -0,0 +1,36 @@
+package kubernetes
+
+import (
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Config represents configuration for Kubernetes integration
+type Config struct {
+	// Socket is path of unix socket on which container events are received
+	Socket string `toml:"socket"`
+	// PolicyAnnotation is pod annotation holding name of RMD policy
+	PolicyAnnotation string `toml:"policyAnnotation"`
+	// CgroupRoot is mount point of cgroup filesystem
+	CgroupRoot string `toml:"cgroupRoot"`
+}
+
+var k8scfg = &Config{
+	Socket:           "/var/run/rmd/kubernetes.sock",
+	PolicyAnnotation: "rmd.intel.com/policy",
+	CgroupRoot:       "/sys/fs/cgroup",
+}
+var runOnce sync.Once
+
+// GetConfig reads Kubernetes integration configuration from configuration file
+func GetConfig() *Config {
+	runOnce.Do(func() {
+		err := viper.UnmarshalKey("kubernetes", k8scfg)
+		if err != nil {
+			log.Error("kubernetes.GetConfig() error:", err)
+		}
+	})
+	return k8scfg
+}
@@ -0,0 +1,165 @@
+package kubernetes
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	wres "github.com/intel/rmd/modules/workload"
+	wltypes "github.com/intel/rmd/modules/workload/types"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	eventStartType = "container.start"
+	eventStopType  = "container.stop"
+
+	// origin of workloads created for containers
+	origin = "KUBERNETES"
+)
+
+// ContainerEvent is container lifecycle event
+type ContainerEvent struct {
+	EventType   string            `json:"event_type"`
+	ContainerID string            `json:"container_id"`
+	PodName     string            `json:"pod_name,omitempty"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// cgroup of container relative to cgroup root (ex. kubepods/pod<uid>/<container id>)
+	CgroupsPath string `json:"cgroups_path,omitempty"`
+	// PID of container process, used if cgroup path is not given
+	Pid int `json:"pid,omitempty"`
+}
+
+// events are handled one by one as each of them can change allocation of other containers
+var eventLock sync.Mutex
+
+// handleContainerEvent handles container lifecycle event
+func handleContainerEvent(event *ContainerEvent) {
+	eventLock.Lock()
+	defer eventLock.Unlock()
+
+	switch event.EventType {
+	case eventStartType:
+		log.Debug("Event about container start")
+		processCreateEvent(event)
+	case eventStopType:
+		log.Debug("Event about container stop")
+		processDeleteEvent(event)
+	default:
+		log.Error("Invalid container event type: ", event.EventType)
+		return
+	}
+}
+
+func processCreateEvent(event *ContainerEvent) {
+	err := validateCreateEvent(event)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	policy, ok := event.Annotations[GetConfig().PolicyAnnotation]
+	if !ok || len(policy) == 0 {
+		log.Debugf("No RMD policy annotation for container %s (pod %s/%s) - skipping container",
+			event.ContainerID, event.Namespace, event.PodName)
+		return
+	}
+
+	// get PIDs ...
+	pids, err := getContainerTasks(event)
+	if err != nil {
+		log.Error("Failed to get PIDs for container (ID: ", event.ContainerID, " ): ", err)
+		return
+	}
+	// ... and cpuset for given container
+	cpus, err := getContainerCpuset(event)
+	if err != nil {
+		log.Error("Failed to get cpuset for container (ID: ", event.ContainerID, " ): ", err)
+		return
+	}
+
+	wrkld := &wltypes.RDTWorkLoad{}
+	wrkld.UUID = event.ContainerID
+	wrkld.Policy = policy
+	wrkld.Origin = origin
+	// containers pinned to CPUs (ex. by static CPU manager) are handled like
+	// OpenStack instances - by cores, others by tasks
+	if len(cpus) > 0 {
+		wrkld.CoreIDs = cpus
+	} else {
+		wrkld.TaskIDs = pids
+	}
+
+	log.Info("Container ID: ", event.ContainerID, " pod: ", event.Namespace, "/", event.PodName, " policy: ", policy)
+	log.Debug("Workload created based on policy and container event: ", wrkld)
+
+	err = wres.Validate(wrkld)
+	if err != nil {
+		log.Error("Failed to validate new workload for container: ", err.Error())
+		return
+	}
+
+	err = wres.Enforce(wrkld)
+	if err != nil {
+		log.Error("Failed to enforce new workload for container: ", err.Error())
+		return
+	}
+
+	err = wres.Create(wrkld)
+	if err != nil {
+		log.Error("Failed to add new workload for container: ", err.Error())
+	}
+
+	return
+}
+
+func processDeleteEvent(event *ContainerEvent) {
+	if len(event.ContainerID) == 0 {
+		log.Error("Missing data in container event: ContainerID")
+		return
+	}
+
+	wrkld, err := wres.GetByUUID(event.ContainerID)
+	if err != nil {
+		log.Error("Failed to fetch workload for given container (", event.ContainerID, "): ", err.Error())
+		return
+	}
+
+	// workloads created by KUBERNETES should be handled only by KUBERNETES
+	if wrkld.Origin == origin {
+		log.Debug("Origin set as KUBERNETES - Trying to release and delete workload...")
+		log.Debug("Releasing...")
+		err = wres.Release(&wrkld)
+		if err != nil {
+			log.Error("Failed to release workload for container: ", err.Error())
+			return
+		}
+		log.Debug("Deleting...")
+		err = wres.Delete(&wrkld)
+		if err != nil {
+			log.Error("Failed to delete workload for stopped container: ", err.Error())
+			return
+		}
+	} else {
+		log.Debug("KUBERNETES origin cannot delete non-KUBERNETES workload")
+	}
+	log.Debug("Deletion done")
+	return
+}
+
+func validateCreateEvent(event *ContainerEvent) error {
+	missing := make([]string, 0)
+	if len(event.ContainerID) == 0 {
+		missing = append(missing, "ContainerID")
+	}
+	if len(event.CgroupsPath) == 0 && event.Pid <= 0 {
+		missing = append(missing, "CgroupsPath/Pid")
+	}
+
+	if len(missing) > 0 {
+		return errors.New("Missing data in container event: " + strings.Join(missing, ","))
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package kubernetes
+
+// Container lifecycle events are received on unix socket as a stream of JSON
+// objects (one per event). The socket is written by NRI plugin or any other
+// component forwarding CRI container events (ex. simple stand-in script).
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var initOnce sync.Once
+var listener net.Listener
+
+// ListenerStart starts listening for container events on given unix socket
+func ListenerStart(socket string) error {
+	var err error
+	initOnce.Do(func() {
+		// remove socket left by previous RMD instance
+		if _, statErr := os.Stat(socket); statErr == nil {
+			os.Remove(socket)
+		}
+		listener, err = net.Listen("unix", socket)
+		if err != nil {
+			err = fmt.Errorf("Listen: %s", err)
+			return
+		}
+		log.Infof("Listening for container events on %s", socket)
+		go accept(listener)
+	})
+	return err
+}
+
+// Close listener
+func Close() error {
+	if listener == nil {
+		return nil
+	}
+	return listener.Close()
+}
+
+func accept(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			log.Infof("Container events listener closed: %s", err)
+			return
+		}
+		go handleConnection(conn)
+	}
+}
+
+// handleConnection decodes events sent over single connection until it is closed
+func handleConnection(conn net.Conn) {
+	defer conn.Close()
+	dec := json.NewDecoder(conn)
+	for {
+		event := ContainerEvent{}
+		err := dec.Decode(&event)
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			log.Errorf("Container event unmarshal error: %s", err)
+			return
+		}
+		handleContainerEvent(&event)
+	}
+}
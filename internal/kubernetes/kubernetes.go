@@ -0,0 +1,28 @@
+package kubernetes
+
+import (
+	"errors"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Init function initializes Kubernetes integration features
+func Init() error {
+	// events listener creates workloads so it has to run in rmd user process
+	if os.Geteuid() == 0 {
+		return nil
+	}
+
+	k8scfg := GetConfig()
+	if len(k8scfg.Socket) == 0 {
+		log.Error("No container events socket path available")
+		return errors.New("Missing container events socket path")
+	}
+
+	if err := ListenerStart(k8scfg.Socket); err != nil {
+		log.Error("Failed to launch container events listener")
+		return err
+	}
+	return nil
+}
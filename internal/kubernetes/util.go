@@ -0,0 +1,95 @@
+package kubernetes
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cgroupFile is a file of cgroup (unified or cpuset controller hierarchy)
+type cgroupFile struct {
+	dir  string
+	name string
+}
+
+// cgroupFiles returns candidates of cgroup file for given cgroup path.
+// Unified (v2) hierarchy is checked first, then v1 cpuset controller.
+func cgroupFiles(cgroupsPath, v2name, v1name string) []cgroupFile {
+	root := GetConfig().CgroupRoot
+	return []cgroupFile{
+		{filepath.Join(root, cgroupsPath), v2name},
+		{filepath.Join(root, "cpuset", cgroupsPath), v1name},
+	}
+}
+
+// readCgroupFile returns content of first existing cgroup file candidate
+func readCgroupFile(cgroupsPath, v2name, v1name string) (string, error) {
+	for _, f := range cgroupFiles(cgroupsPath, v2name, v1name) {
+		data, err := ioutil.ReadFile(filepath.Join(f.dir, f.name))
+		if err == nil {
+			return strings.TrimSpace(string(data)), nil
+		}
+	}
+	return "", fmt.Errorf("No cgroup %s found", cgroupsPath)
+}
+
+// getContainerTasks returns PIDs of all container processes
+func getContainerTasks(event *ContainerEvent) ([]string, error) {
+	if len(event.CgroupsPath) == 0 {
+		return []string{strconv.Itoa(event.Pid)}, nil
+	}
+	procs, err := readCgroupFile(event.CgroupsPath, "cgroup.procs", "cgroup.procs")
+	if err != nil {
+		return nil, err
+	}
+	pids := strings.Fields(procs)
+	if len(pids) == 0 {
+		return nil, fmt.Errorf("No process in cgroup %s", event.CgroupsPath)
+	}
+	return pids, nil
+}
+
+// getContainerCpuset returns list of cpus container is pinned to.
+// Empty list is returned if container can run on all cpus.
+func getContainerCpuset(event *ContainerEvent) ([]string, error) {
+	var cpus string
+	var err error
+	if len(event.CgroupsPath) == 0 {
+		cpus, err = getPIDCpus(strconv.Itoa(event.Pid))
+	} else {
+		cpus, err = readCgroupFile(event.CgroupsPath, "cpuset.cpus.effective", "cpuset.cpus")
+	}
+	if err != nil {
+		return nil, err
+	}
+	all, err := readCgroupFile("", "cpuset.cpus.effective", "cpuset.cpus")
+	if err != nil {
+		return nil, err
+	}
+	if len(cpus) == 0 || cpus == all {
+		return []string{}, nil
+	}
+	return strings.Split(cpus, ","), nil
+}
+
+// getPIDCpus returns list of cpus (in cpuset format) process is allowed to run on
+func getPIDCpus(pid string) (string, error) {
+	f, err := os.Open(filepath.Join("/proc", pid, "status"))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "Cpus_allowed_list:") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "Cpus_allowed_list:")), nil
+		}
+	}
+	return "", fmt.Errorf("No allowed cpus found for PID %s", pid)
+}
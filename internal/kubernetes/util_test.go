@@ -0,0 +1,96 @@
+package kubernetes
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	. "github.com/prashantv/gostub"
+)
+
+func writeCgroupFile(t *testing.T, path, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_getContainerCpusetAndTasks(t *testing.T) {
+	root, err := ioutil.TempDir("", "cgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	// unified hierarchy: root cgroup and pinned container
+	writeCgroupFile(t, filepath.Join(root, "cpuset.cpus.effective"), "0-15\n")
+	writeCgroupFile(t, filepath.Join(root, "kubepods/pod1/c1/cpuset.cpus.effective"), "2-3,8\n")
+	writeCgroupFile(t, filepath.Join(root, "kubepods/pod1/c1/cgroup.procs"), "100\n101\n")
+	writeCgroupFile(t, filepath.Join(root, "kubepods/pod2/c2/cpuset.cpus.effective"), "0-15\n")
+	writeCgroupFile(t, filepath.Join(root, "kubepods/pod2/c2/cgroup.procs"), "200\n")
+	// v1 cpuset controller hierarchy
+	writeCgroupFile(t, filepath.Join(root, "cpuset/kubepods/pod3/c3/cpuset.cpus"), "4\n")
+	writeCgroupFile(t, filepath.Join(root, "cpuset/kubepods/pod3/c3/cgroup.procs"), "300\n")
+
+	GetConfig()
+	stubs := Stub(&k8scfg.CgroupRoot, root)
+	defer stubs.Reset()
+
+	tests := []struct {
+		name      string
+		path      string
+		wantCpus  []string
+		wantTasks []string
+		wantErr   bool
+	}{
+		{"Pinned container", "kubepods/pod1/c1", []string{"2-3", "8"}, []string{"100", "101"}, false},
+		{"Not pinned container", "kubepods/pod2/c2", []string{}, []string{"200"}, false},
+		{"Container in cpuset controller", "kubepods/pod3/c3", []string{"4"}, []string{"300"}, false},
+		{"Unknown cgroup", "kubepods/pod4/c4", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &ContainerEvent{CgroupsPath: tt.path}
+			cpus, err := getContainerCpuset(event)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getContainerCpuset() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(cpus, tt.wantCpus) {
+				t.Errorf("getContainerCpuset() = %v, want %v", cpus, tt.wantCpus)
+			}
+			tasks, err := getContainerTasks(event)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getContainerTasks() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(tasks, tt.wantTasks) {
+				t.Errorf("getContainerTasks() = %v, want %v", tasks, tt.wantTasks)
+			}
+		})
+	}
+}
+
+func Test_validateCreateEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   ContainerEvent
+		wantErr bool
+	}{
+		{"Cgroup path given", ContainerEvent{ContainerID: "c1", CgroupsPath: "kubepods/c1"}, false},
+		{"PID given", ContainerEvent{ContainerID: "c1", Pid: 123}, false},
+		{"No container ID", ContainerEvent{Pid: 123}, true},
+		{"No cgroup path and PID", ContainerEvent{ContainerID: "c1"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateCreateEvent(&tt.event); (err != nil) != tt.wantErr {
+				t.Errorf("validateCreateEvent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -5,9 +5,13 @@ package openstack
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"strings"
 
+	"github.com/intel/rmd/modules/events"
+	evtypes "github.com/intel/rmd/modules/events/types"
 	wres "github.com/intel/rmd/modules/workload"
+	wltypes "github.com/intel/rmd/modules/workload/types"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -85,6 +89,7 @@ func processCreateNotification(notif *NovaNotification) {
 	err := validateCreateNoti(notif)
 	if err != nil {
 		log.Error(err)
+		publishResult(notif, nil, err.Error())
 		return
 	}
 
@@ -93,6 +98,7 @@ func processCreateNotification(notif *NovaNotification) {
 	if err != nil {
 		// failed to get PID
 		log.Error("Failed to get PID for Instance (ID: ", notif.Payload.Data.InstanceID, " ): ", err)
+		publishResult(notif, nil, fmt.Sprint("Failed to get PID for instance: ", err))
 		return
 	}
 	// ... and taskset for given instance
@@ -100,12 +106,14 @@ func processCreateNotification(notif *NovaNotification) {
 	if err != nil {
 		// failed to get PIDs
 		log.Error("Failed to get Taskset for PID ", pid, " : ", err)
+		publishResult(notif, nil, fmt.Sprint("Failed to get taskset for instance: ", err))
 		return
 	}
 
 	if len(tset) == 0 {
 		// no CPU affinity set: we're not supporting this situation
 		log.Error("Instance no pinned to any CPU core - skipping instance")
+		publishResult(notif, nil, "Instance not pinned to any CPU core - skipping instance")
 		return
 	}
 
@@ -114,6 +122,7 @@ func processCreateNotification(notif *NovaNotification) {
 	wrkld, err := getSwiftWorkloadPolicyByURL(notif.Payload.Data.Flavor.Data.Extra.SwiftURL)
 	if err != nil {
 		log.Error("Failed to get policy (", notif.Payload.Data.Flavor.Data.Extra.SwiftURL, ") for instance: ", notif.Payload.Data.InstanceID)
+		publishResult(notif, nil, fmt.Sprint("Failed to get policy for instance: ", err))
 		return
 	}
 
@@ -128,19 +137,24 @@ func processCreateNotification(notif *NovaNotification) {
 	err = wres.Validate(wrkld)
 	if err != nil {
 		log.Error("Failed to validate new workload for OpenStack instance: ", err.Error())
+		publishResult(notif, wrkld, "Failed to validate new workload for instance: "+err.Error())
 		return
 	}
 
 	err = wres.Enforce(wrkld)
 	if err != nil {
 		log.Error("Failed to enforce new workload for OpenStack instance: ", err.Error())
+		publishResult(notif, wrkld, "Failed to enforce new workload for instance: "+err.Error())
 		return
 	}
 
 	err = wres.Create(wrkld)
 	if err != nil {
 		log.Error("Failed to add new workload for OpenStack instance: ", err.Error())
+		publishResult(notif, wrkld, "Failed to add new workload for instance: "+err.Error())
+		return
 	}
+	publishResult(notif, wrkld, "Workload created for instance")
 
 	return
 }
@@ -154,6 +168,7 @@ func processDeleteNotification(notif *NovaNotification) {
 	wrkld, err := wres.GetByUUID(notif.Payload.Data.InstanceID)
 	if err != nil {
 		log.Error("Failed to fetch workload for given UUID (", notif.Payload.Data.InstanceID, "): ", err.Error())
+		publishResult(notif, nil, "Failed to fetch workload for instance: "+err.Error())
 		return
 	}
 
@@ -164,16 +179,20 @@ func processDeleteNotification(notif *NovaNotification) {
 		err = wres.Release(&wrkld)
 		if err != nil {
 			log.Error("Failed to release workload for OpenStack instance: ", err.Error())
+			publishResult(notif, &wrkld, "Failed to release workload for instance: "+err.Error())
 			return
 		}
 		log.Debug("Deleting...")
 		err = wres.Delete(&wrkld)
 		if err != nil {
 			log.Error("Failed to delete workload for deleted OpenStack instance: ", err.Error())
+			publishResult(notif, &wrkld, "Failed to delete workload for instance: "+err.Error())
 			return
 		}
+		publishResult(notif, &wrkld, "Workload deleted for instance")
 	} else {
 		log.Debug("OPENSTACK origin cannot delete non-OPENSTACK workload")
+		publishResult(notif, &wrkld, "Workload of instance not created by OpenStack - not deleted")
 	}
 	log.Debug("Deletion done")
 	return
@@ -194,3 +213,9 @@ func validateCreateNoti(notif *NovaNotification) error {
 
 	return nil
 }
+
+// publishResult reports result of notification handling as an event
+func publishResult(notif *NovaNotification, w *wltypes.RDTWorkLoad, message string) {
+	events.Publish(evtypes.OpenStackNotification, w,
+		fmt.Sprintf("%s (instance %s): %s", notif.EventType, notif.Payload.Data.InstanceID, message))
+}
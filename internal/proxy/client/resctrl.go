@@ -67,3 +67,39 @@ func ResetCOSParamsToDefaults(cosName string) error {
 	// Call PQOS Wrapper
 	return Client.Call("Proxy.ResetCOSParamsToDefaults", cosName, nil)
 }
+
+// SetMba sets MBA values (socket id -> value in current MBA mode) of resource group
+func SetMba(name string, mba map[int]int) error {
+	req := types.MbaRequest{
+		Name: name,
+		Mba:  mba,
+	}
+	return Client.Call("Proxy.SetMba", req, nil)
+}
+
+// CreateMonGroup creates monitoring group with given name inside resource group
+// and assigns tasks and cpus to it
+func CreateMonGroup(group, name string, tasks []string, cpus string) error {
+	req := types.MonGroupRequest{
+		Group: group,
+		Name:  name,
+		Tasks: tasks,
+		CPUs:  cpus,
+	}
+	return Client.Call("Proxy.CreateMonGroup", req, nil)
+}
+
+// DestroyMonGroup removes monitoring group with given name from resource group
+func DestroyMonGroup(group, name string) error {
+	req := types.MonGroupRequest{
+		Group: group,
+		Name:  name,
+	}
+	return Client.Call("Proxy.DestroyMonGroup", req, nil)
+}
+
+// GetMonData returns monitoring data of given monitoring group
+// mon_data files are world readable so no need to call root process here
+func GetMonData(group, name string) (map[string]resctrl.MonData, error) {
+	return resctrl.GetMonData(group, name)
+}
@@ -42,3 +42,19 @@ func (*Proxy) ResetCOSParamsToDefaults(cosName string, dummy *int) error {
 	// Call PQOS Wrapper
 	return pqos.ResetCOSParamsToDefaults(cosName)
 }
+
+// SetMba sets MBA values of resource group
+func (*Proxy) SetMba(r types.MbaRequest, dummy *int) error {
+	// Call PQOS Wrapper
+	return pqos.SetMbaForCOS(r.Name, r.Mba)
+}
+
+// CreateMonGroup creates (or updates) monitoring group inside resource group
+func (*Proxy) CreateMonGroup(r types.MonGroupRequest, dummy *int) error {
+	return resctrl.CreateMonGroup(r.Group, r.Name, r.Tasks, r.CPUs)
+}
+
+// DestroyMonGroup removes monitoring group from resource group
+func (*Proxy) DestroyMonGroup(r types.MonGroupRequest, dummy *int) error {
+	return resctrl.DestroyMonGroup(r.Group, r.Name)
+}
@@ -9,3 +9,19 @@ type ResctrlRequest struct {
 	Name string
 	Res  resctrl.ResAssociation
 }
+
+// MonGroupRequest struct of monitoring group request to rpc server
+type MonGroupRequest struct {
+	Group string
+	Name  string
+	Tasks []string
+	CPUs  string
+}
+
+// MbaRequest struct of MBA change request to rpc server
+type MbaRequest struct {
+	// Name of resource group (COS)
+	Name string
+	// MBA values per socket id
+	Mba map[int]int
+}
@@ -79,6 +79,8 @@ type Info struct {
 type Infos struct {
 	Num    uint32          `json:"number"`
 	Caches map[uint32]Info `json:"Caches"`
+	// CdpEnable is true if last level cache works in Code/Data Prioritization mode
+	CdpEnable bool `json:"cdp_enable"`
 }
 
 // Summary is summary of cache
@@ -268,6 +270,7 @@ func (c *Infos) GetByLevel(level uint32) error {
 	}
 
 	c.Caches = make(map[uint32]Info)
+	c.CdpEnable = proc.IsEnableCdp()
 
 	for _, sc := range syscaches {
 		id, _ := strconv.Atoi(sc.ID)
@@ -451,7 +454,13 @@ func GetCosInfo() CosInfo {
 		targetLev := strconv.FormatUint(uint64(level), 10)
 		cacheLevel := "l" + targetLev
 
-		if value, ok := rcinfo[cacheLevel]; ok {
+		value, ok := rcinfo[cacheLevel]
+		if !ok {
+			// with CDP enabled there's no common info for cache level
+			// but code and data share the same capacity bitmask
+			value, ok = rcinfo[cacheLevel+"code"]
+		}
+		if ok {
 			log.Debugf("rcinfo[cacheLevel]: %v", value)
 			catCosInfo.CbmMaskLen = util.CbmLen(value.CbmMask)
 			catCosInfo.MinCbmBits = value.MinCbmBits
@@ -585,6 +594,25 @@ func AvailableCacheLevel() ([]string, error) {
 	return levels, nil
 }
 
+// GetLLCWaySize returns size in bytes of single way of last level cache
+// capacity bitmask
+func GetLLCWaySize() (uint32, error) {
+	ways := GetCosInfo().CbmMaskLen
+	if ways == 0 {
+		return 0, fmt.Errorf("Unknown number of last level cache ways")
+	}
+	caches, err := GetSysCaches(int(GetLLC()))
+	if err != nil {
+		return 0, err
+	}
+	for _, c := range caches {
+		if size := convertCacheSize(c.Size); size > 0 {
+			return size / uint32(ways), nil
+		}
+	}
+	return 0, fmt.Errorf("Unknown last level cache size")
+}
+
 // GetLLC return the last level of the cache on the host
 func GetLLC() uint32 {
 	avl, err := AvailableCacheLevel()
@@ -17,7 +17,8 @@ func getReservedCache(
 	wayCandidate int,
 	wayOffset, osCacheWays uint,
 	osCPUbm *util.Bitmap,
-	sysc map[string]SysCache) (*Reserved, error) {
+	sysc map[string]SysCache,
+	wrapper bitmapWrapper) (*Reserved, error) {
 
 	r := &Reserved{}
 
@@ -35,7 +36,7 @@ func getReservedCache(
 		}
 		wc = wc << wayOffset
 		mask := strconv.FormatUint(uint64(wc), 16)
-		schemata[sc.ID], err = BitmapsCacheWrapper(mask)
+		schemata[sc.ID], err = wrapper(mask)
 		if err != nil {
 			return r, err
 		}
@@ -75,7 +76,8 @@ func GetCachePoolLayout() (map[string]*Reserved, error) {
 				0,
 				osConf.CacheWays,
 				osCPUbm,
-				syscaches)
+				syscaches,
+				BitmapsCacheWrapper)
 			if err != nil {
 				returnErr = err
 				return
@@ -89,7 +91,8 @@ func GetCachePoolLayout() (map[string]*Reserved, error) {
 				poolConf.Guarantee,
 				osConf.CacheWays,
 				osCPUbm,
-				syscaches)
+				syscaches,
+				BitmapsCacheWrapper)
 
 			if err != nil {
 				returnErr = err
@@ -105,7 +108,8 @@ func GetCachePoolLayout() (map[string]*Reserved, error) {
 				poolConf.Guarantee+poolConf.Besteffort,
 				osConf.CacheWays,
 				osCPUbm,
-				syscaches)
+				syscaches,
+				BitmapsCacheWrapper)
 
 			if err != nil {
 				returnErr = err
@@ -11,6 +11,8 @@ import (
 type OSGroup struct {
 	CacheWays uint   `toml:"cacheways"`
 	CPUSet    string `toml:"cpuset"`
+	// L2CacheWays is used only on platforms with L2 CAT (when L2 is not the last level cache)
+	L2CacheWays uint `toml:"l2cacheways"`
 }
 
 // InfraGroup represents infra group configuration
@@ -27,11 +29,32 @@ type CachePool struct {
 	Besteffort       uint `toml:"besteffort"`
 	Shared           uint `toml:"shared"`
 	Shrink           bool `toml:"shrink"`
+	// Rebalance enables growing/shrinking of besteffort workloads ways according to cache occupancy
+	Rebalance bool `toml:"rebalance"`
+	// RebalanceInterval is time in seconds between occupancy samples
+	RebalanceInterval uint `toml:"rebalance_interval" mapstructure:"rebalance_interval"`
+	// GrowThreshold and ShrinkThreshold are percentage of allocated ways occupied by workload
+	GrowThreshold   uint `toml:"grow_threshold" mapstructure:"grow_threshold"`
+	ShrinkThreshold uint `toml:"shrink_threshold" mapstructure:"shrink_threshold"`
+	// Hysteresis is number of consecutive samples above/below threshold needed to change ways
+	Hysteresis uint `toml:"hysteresis"`
 }
 
 // RDTConfig contains RDT related configuration flags from rmd.toml
 type RDTConfig struct {
 	MBAMode string `toml:"mbaMode"`
+	// MBAPeakMbps is measured peak memory bandwidth per socket used to convert
+	// MBA params between percentage and Mbps (0 if not known)
+	MBAPeakMbps uint32 `toml:"mbaPeakMbps"`
+	// ThrottleMbps is memory bandwidth per socket of shared or besteffort workload
+	// that triggers throttling of the workload (0 disables throttling)
+	ThrottleMbps uint32 `toml:"throttleMbps"`
+	// ThrottleMba is MBA value (in units of MBA mode) applied to throttled workload
+	ThrottleMba uint32 `toml:"throttleMba"`
+	// ThrottleCooldown is time in seconds after which MBA cap is lifted
+	ThrottleCooldown uint `toml:"throttleCooldown"`
+	// ThrottleInterval is time in seconds between bandwidth samples
+	ThrottleInterval uint `toml:"throttleInterval"`
 }
 
 // MBAModeToInt converts suppored MBA modes (none, percentage or mbps) into PQOS compatible values (-1, 0 and 1 respectively)
@@ -53,12 +76,16 @@ func MBAModeToInt(mode string) (int, error) {
 var infraConfigOnce sync.Once
 var osConfigOnce sync.Once
 var cachePoolConfigOnce sync.Once
+var l2CachePoolConfigOnce sync.Once
 
 var infragroup = &InfraGroup{}
-var osgroup = &OSGroup{1, "0"}
+var osgroup = &OSGroup{1, "0", 1}
 
 // FIXME: the default may not work on some platform
-var cachepool = &CachePool{10, 10, 7, 2, false}
+var cachepool = &CachePool{10, 10, 7, 2, false, false, 10, 90, 50, 3}
+
+// L2 caches have less ways than LLC, shrink and rebalance are not supported for L2
+var l2cachepool = &CachePool{10, 4, 4, 2, false, false, 0, 0, 0, 0}
 
 // NewInfraConfig reads InfraGroup configuration
 func NewInfraConfig() *InfraGroup {
@@ -88,3 +115,11 @@ func NewCachePoolConfig() *CachePool {
 	})
 	return cachepool
 }
+
+// NewL2CachePoolConfig reads L2 cache pool layout configuration
+func NewL2CachePoolConfig() *CachePool {
+	l2CachePoolConfigOnce.Do(func() {
+		viper.UnmarshalKey("L2CachePool", l2cachepool)
+	})
+	return l2cachepool
+}
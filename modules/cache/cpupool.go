@@ -36,7 +36,7 @@ func GetCPUPools() (map[string]map[string]*util.Bitmap, error) {
 				return
 			}
 		} else {
-			infraCPUbm, _ = BitmapsCPUWrapper("Ox0")
+			infraCPUbm, _ = BitmapsCPUWrapper([]string{})
 		}
 
 		level := GetLLC()
@@ -51,7 +51,7 @@ func GetCPUPools() (map[string]map[string]*util.Bitmap, error) {
 		if isocpu != "" {
 			isolatedCPUbm, _ = BitmapsCPUWrapper([]string{isocpu})
 		} else {
-			isolatedCPUbm, _ = BitmapsCPUWrapper("Ox0")
+			isolatedCPUbm, _ = BitmapsCPUWrapper([]string{})
 		}
 
 		for _, sc := range syscaches {
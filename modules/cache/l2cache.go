@@ -0,0 +1,205 @@
+package cache
+
+// L2 cache allocation (L2 CAT) support.
+// On platforms where L2 is not the last level cache, L2 ways are managed
+// in separate pools (os group, guarantee, besteffort and shared) per L2 cache id.
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	proxyclient "github.com/intel/rmd/internal/proxy/client"
+	"github.com/intel/rmd/modules/cache/config"
+	util "github.com/intel/rmd/utils/bitmap"
+	"github.com/intel/rmd/utils/proc"
+	log "github.com/sirupsen/logrus"
+)
+
+// bitmapWrapper converts cache mask into Bitmap of proper length
+type bitmapWrapper func(bitmask interface{}) (*util.Bitmap, error)
+
+var l2CatCosInfo = &CosInfo{0, 0, 0, ""}
+var l2InfoOnce sync.Once
+
+// L2ReservedInfo is all reserved L2 cache information
+var L2ReservedInfo map[string]*Reserved
+var l2RevinfoOnce sync.Once
+
+var l2OSGroupReserve = &Reserved{}
+var l2OSOnce sync.Once
+
+var l2CachePoolReserved = make(map[string]*Reserved, 0)
+var l2CachePoolOnce sync.Once
+
+// IsL2CatEnabled returns true if L2 cache can be allocated separately from the last level cache
+var IsL2CatEnabled = func() bool {
+	if GetLLC() == 2 {
+		// L2 is the last level cache, handled as LLC
+		return false
+	}
+	flag, err := proc.IsL2CatAvailable()
+	if err != nil || !flag {
+		return false
+	}
+	return GetL2CosInfo().CbmMaskLen > 0
+}
+
+// GetL2CosInfo is Concurrency-safe.
+func GetL2CosInfo() CosInfo {
+	l2InfoOnce.Do(func() {
+		rcinfo := proxyclient.GetRdtCosInfo()
+		if value, ok := rcinfo["l2"]; ok {
+			log.Debugf("rcinfo[l2]: %v", value)
+			l2CatCosInfo.CbmMaskLen = util.CbmLen(value.CbmMask)
+			l2CatCosInfo.MinCbmBits = value.MinCbmBits
+			l2CatCosInfo.NumClosids = value.NumClosids
+			l2CatCosInfo.CbmMask = value.CbmMask
+		} else {
+			log.Debugf("No L2 CAT information in resctrl")
+		}
+	})
+	return *l2CatCosInfo
+}
+
+// BitmapsL2CacheWrapper is a wrapper for L2 Cache bitmap
+func BitmapsL2CacheWrapper(bitmask interface{}) (*util.Bitmap, error) {
+	len := GetL2CosInfo().CbmMaskLen
+	if len == 0 {
+		var bm *util.Bitmap
+		return bm, fmt.Errorf("Unable to get Total L2 cache ways on Host")
+	}
+	return util.NewBitmap(len, bitmask)
+}
+
+// GetL2OSGroupReserve returns os reserved L2 cache
+func GetL2OSGroupReserve() (Reserved, error) {
+	var returnErr error
+	l2OSOnce.Do(func() {
+		conf := config.NewOSConfig()
+		osCPUbm, err := BitmapsCPUWrapper([]string{conf.CPUSet})
+		if err != nil {
+			returnErr = err
+			return
+		}
+		l2OSGroupReserve.AllCPUs = osCPUbm
+
+		syscaches, err := GetSysCaches(2)
+		if err != nil {
+			returnErr = err
+			return
+		}
+
+		ways := GetL2CosInfo().CbmMaskLen
+		if conf.L2CacheWays > uint(ways) {
+			returnErr = fmt.Errorf("The request OSGroup L2 cache ways %d is larger than available %d",
+				conf.L2CacheWays, ways)
+			return
+		}
+
+		schemata := map[string]*util.Bitmap{}
+		osCPUs := map[string]*util.Bitmap{}
+
+		for _, sc := range syscaches {
+			bm, _ := BitmapsCPUWrapper([]string{sc.SharedCPUList})
+			osCPUs[sc.ID] = osCPUbm.And(bm)
+			mask := "0"
+			if !osCPUs[sc.ID].IsEmpty() {
+				mask = strconv.FormatUint(1<<conf.L2CacheWays-1, 16)
+			}
+			schemata[sc.ID], returnErr = BitmapsL2CacheWrapper(mask)
+			if returnErr != nil {
+				return
+			}
+		}
+		l2OSGroupReserve.CPUsPerNode = osCPUs
+		l2OSGroupReserve.Schemata = schemata
+	})
+
+	return *l2OSGroupReserve, returnErr
+}
+
+// GetL2CachePoolLayout returns L2 cache pool layout based on configuration
+func GetL2CachePoolLayout() (map[string]*Reserved, error) {
+	var returnErr error
+	l2CachePoolOnce.Do(func() {
+		poolConf := config.NewL2CachePoolConfig()
+		osConf := config.NewOSConfig()
+		ways := GetL2CosInfo().CbmMaskLen
+
+		if osConf.L2CacheWays+poolConf.Guarantee+poolConf.Besteffort+poolConf.Shared > uint(ways) {
+			returnErr = fmt.Errorf(
+				"Error config: L2 Guarantee + Besteffort + Shared + OS reserved ways should be less or equal to %d", ways)
+			return
+		}
+
+		syscaches, err := GetSysCaches(2)
+		if err != nil {
+			returnErr = err
+			return
+		}
+		osCPUbm, err := BitmapsCPUWrapper([]string{osConf.CPUSet})
+		if err != nil {
+			returnErr = err
+			return
+		}
+
+		pools := []struct {
+			name   string
+			ways   uint
+			offset uint
+		}{
+			{Guarantee, poolConf.Guarantee, 0},
+			{Besteffort, poolConf.Besteffort, poolConf.Guarantee},
+			{Shared, poolConf.Shared, poolConf.Guarantee + poolConf.Besteffort},
+		}
+		for _, p := range pools {
+			if p.ways == 0 {
+				continue
+			}
+			resev, err := getReservedCache(1<<p.ways-1,
+				p.offset,
+				osConf.L2CacheWays,
+				osCPUbm,
+				syscaches,
+				BitmapsL2CacheWrapper)
+			if err != nil {
+				returnErr = err
+				return
+			}
+			l2CachePoolReserved[p.name] = resev
+		}
+		if resev, ok := l2CachePoolReserved[Shared]; ok {
+			resev.Name = Shared
+			resev.Quota = poolConf.MaxAllowedShared
+		}
+	})
+
+	return l2CachePoolReserved, returnErr
+}
+
+// GetL2ReservedInfo returns all reserved L2 cache information
+func GetL2ReservedInfo() map[string]*Reserved {
+
+	l2RevinfoOnce.Do(func() {
+		L2ReservedInfo = make(map[string]*Reserved, 10)
+
+		r, err := GetL2OSGroupReserve()
+		if err == nil {
+			L2ReservedInfo[OS] = &r
+		} else {
+			log.Errorf("Failed to get L2 os group reserve: %v", err)
+		}
+
+		poolinfo, err := GetL2CachePoolLayout()
+		if err == nil {
+			for k, v := range poolinfo {
+				L2ReservedInfo[k] = v
+			}
+		} else {
+			log.Errorf("Failed to get L2 cache pool layout: %v", err)
+		}
+	})
+
+	return L2ReservedInfo
+}
@@ -11,6 +11,7 @@ import (
 	util "github.com/intel/rmd/utils/bitmap"
 	"github.com/intel/rmd/utils/pqos"
 	"github.com/intel/rmd/utils/proc"
+	"github.com/intel/rmd/utils/resctrl"
 )
 
 var osGroupReserve = &Reserved{}
@@ -118,19 +119,50 @@ func SetOSGroup() error {
 		return err
 	}
 
-	for i, v := range osGroup.CacheSchemata[cacheLevel] {
+	// with CDP enabled os group uses the same ways for code and data
+	for _, level := range []string{cacheLevel, cacheLevel + "CODE", cacheLevel + "DATA"} {
+		for i, v := range osGroup.CacheSchemata[level] {
+			cacheID := strconv.Itoa(int(v.ID))
+			// OSGroup is the first Group, use the edge cache ways.
+			// FIXME , left or right cache ways, need to be check.
+			conf := config.NewOSConfig()
+			request, _ := BitmapsCacheWrapper(strconv.FormatUint(1<<conf.CacheWays-1, 16))
+			// NOTE , simpleness, brutal. Reset Cache for OS Group,
+			// even the cache is occupied by other group.
+			availableWays := schemata[cacheID].Or(request)
+			expectWays := availableWays.ToBinStrings()[0]
+
+			osGroup.CacheSchemata[level][i].Mask = strconv.FormatUint(1<<uint(len(expectWays))-1, 16)
+		}
+	}
+
+	if IsL2CatEnabled() {
+		if err = setOSGroupL2(allres, osGroup); err != nil {
+			return err
+		}
+	}
+
+	return proxyclient.Commit(osGroup, pqos.OSGroupCOS)
+}
+
+// setOSGroupL2 sets L2 cache ways of os group in the same way as for last level cache
+func setOSGroupL2(allres map[string]*resctrl.ResAssociation, osGroup *resctrl.ResAssociation) error {
+	schemata, err := GetAvailableCacheSchemata(allres, []string{pqos.InfraGoupCOS, pqos.OSGroupCOS}, "none", "L2")
+	if err != nil {
+		return err
+	}
+
+	conf := config.NewOSConfig()
+	for i, v := range osGroup.CacheSchemata["L2"] {
 		cacheID := strconv.Itoa(int(v.ID))
-		// OSGroup is the first Group, use the edge cache ways.
-		// FIXME , left or right cache ways, need to be check.
-		conf := config.NewOSConfig()
-		request, _ := BitmapsCacheWrapper(strconv.FormatUint(1<<conf.CacheWays-1, 16))
-		// NOTE , simpleness, brutal. Reset Cache for OS Group,
-		// even the cache is occupied by other group.
+		if _, ok := schemata[cacheID]; !ok {
+			continue
+		}
+		request, _ := BitmapsL2CacheWrapper(strconv.FormatUint(1<<conf.L2CacheWays-1, 16))
 		availableWays := schemata[cacheID].Or(request)
 		expectWays := availableWays.ToBinStrings()[0]
 
-		osGroup.CacheSchemata[cacheLevel][i].Mask = strconv.FormatUint(1<<uint(len(expectWays))-1, 16)
+		osGroup.CacheSchemata["L2"][i].Mask = strconv.FormatUint(1<<uint(len(expectWays))-1, 16)
 	}
-
-	return proxyclient.Commit(osGroup, pqos.OSGroupCOS)
+	return nil
 }
@@ -82,20 +82,40 @@ func GetAvailableCacheSchemata(allres map[string]*resctrl.ResAssociation,
 	pool string,
 	cacheLevel string) (map[string]*libutil.Bitmap, error) {
 
-	GetReservedInfo()
+	// L2 CAT has its own pools (unless L2 is the last level cache)
+	if cacheLevel == "L2" && IsL2CatEnabled() {
+		return getAvailableSchemata(allres, ignoreGroups, pool, cacheLevel,
+			GetL2ReservedInfo(), GetL2CosInfo(), BitmapsL2CacheWrapper)
+	}
+	return getAvailableSchemata(allres, ignoreGroups, pool, cacheLevel,
+		GetReservedInfo(), GetCosInfo(), BitmapsCacheWrapper)
+}
+
+func getAvailableSchemata(allres map[string]*resctrl.ResAssociation,
+	ignoreGroups []string,
+	pool string,
+	cacheLevel string,
+	reserved map[string]*Reserved,
+	cosInfo CosInfo,
+	wrapper bitmapWrapper) (map[string]*libutil.Bitmap, error) {
+
 	// FIXME  A central util to generate schemata Bitmap
 	schemata := map[string]*libutil.Bitmap{}
 
-	if len(allres) >= GetCosInfo().NumClosids {
+	if len(allres) >= cosInfo.NumClosids {
 		return nil, fmt.Errorf("error, not enough CLOS on host, %d used", len(allres))
 	}
 
 	if pool == "none" {
-		for k := range ReservedInfo[OS].Schemata {
-			schemata[k], _ = BitmapsCacheWrapper(GetCosInfo().CbmMask)
+		osResv, ok := reserved[OS]
+		if !ok {
+			return nil, fmt.Errorf("error, no os group reserved for %s", cacheLevel)
+		}
+		for k := range osResv.Schemata {
+			schemata[k], _ = wrapper(cosInfo.CbmMask)
 		}
 	} else {
-		resv, ok := ReservedInfo[pool]
+		resv, ok := reserved[pool]
 		if !ok {
 			return nil, fmt.Errorf("error doesn't support pool %s", pool)
 		}
@@ -105,11 +125,17 @@ func GetAvailableCacheSchemata(allres map[string]*resctrl.ResAssociation,
 		}
 	}
 
+	// with CDP enabled both code and data ways of resource group are in use
+	levels := []string{cacheLevel, cacheLevel + "CODE", cacheLevel + "DATA"}
 	for k, v := range allres {
 		if util.HasElem(ignoreGroups, k) {
 			continue
 		}
-		if sv, ok := v.CacheSchemata[cacheLevel]; ok {
+		for _, level := range levels {
+			sv, ok := v.CacheSchemata[level]
+			if !ok {
+				continue
+			}
 			if len(schemata) <= 0 {
 				// skip rest of iteration execution
 				// (without data in schemata[] there will be failure below)
@@ -117,9 +143,9 @@ func GetAvailableCacheSchemata(allres map[string]*resctrl.ResAssociation,
 			}
 			for _, cv := range sv {
 				k := strconv.Itoa(int(cv.ID))
-				bm, _ := BitmapsCacheWrapper(cv.Mask)
+				bm, _ := wrapper(cv.Mask)
 				// And check cpu list is empty
-				if cv.Mask == GetCosInfo().CbmMask {
+				if cv.Mask == cosInfo.CbmMask {
 					continue
 				}
 				schemata[k] = schemata[k].Axor(bm)
@@ -0,0 +1,122 @@
+package events
+
+// Events are stored in database with increasing revision. Clients watching
+// event stream can resume from the last received revision as long as it is
+// still kept in database (db.MaxEvents latest events are kept).
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intel/rmd/internal/db"
+	evtypes "github.com/intel/rmd/modules/events/types"
+	wltypes "github.com/intel/rmd/modules/workload/types"
+)
+
+// size of subscriber channel, subscriber is dropped when it's full
+const subscriberBuffer = 64
+
+// ErrCompacted is returned when events after requested revision are not kept anymore
+var ErrCompacted = errors.New("Requested revision is not kept anymore")
+
+var eventsDatabase db.DB
+var dbOnce sync.Once
+
+// subscribers of new events, also protects order of revisions sent to them
+var subscribers = map[chan evtypes.Event]bool{}
+var subLock sync.Mutex
+
+// Init creates database connection for events
+func Init() error {
+	var err error
+	dbOnce.Do(func() {
+		eventsDatabase, err = db.NewDB()
+		if err != nil {
+			eventsDatabase = nil
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if eventsDatabase == nil {
+		return errors.New("Events database not initialized")
+	}
+	return nil
+}
+
+// Publish stores event and sends it to all subscribers. Errors are only logged
+// as event reporting should never break operation that generated the event.
+func Publish(eventType string, w *wltypes.RDTWorkLoad, message string) {
+	if err := Init(); err != nil {
+		log.Errorf("Failed to publish %s event: %v", eventType, err)
+		return
+	}
+
+	e := evtypes.Event{Time: time.Now(), Type: eventType, Message: message}
+	if w != nil {
+		// workload can be modified by caller after event is published
+		wcopy := wltypes.RDTWorkLoad{}
+		if buf, err := json.Marshal(w); err == nil && json.Unmarshal(buf, &wcopy) == nil {
+			e.Workload = &wcopy
+		}
+		e.WorkloadID = w.ID
+	}
+
+	subLock.Lock()
+	defer subLock.Unlock()
+	if err := eventsDatabase.AppendEvent(&e); err != nil {
+		log.Errorf("Failed to store %s event: %v", eventType, err)
+		return
+	}
+	for ch := range subscribers {
+		select {
+		case ch <- e:
+		default:
+			// slow subscriber, it has to resume from the last received revision
+			log.Warn("Events subscriber is too slow - dropping it")
+			delete(subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe returns events after since revision (only if replay is true) and
+// channel of new events. Channel is closed when subscriber is dropped.
+func Subscribe(since uint64, replay bool) ([]evtypes.Event, chan evtypes.Event, error) {
+	if err := Init(); err != nil {
+		return nil, nil, err
+	}
+
+	subLock.Lock()
+	defer subLock.Unlock()
+
+	backlog := []evtypes.Event{}
+	if replay {
+		es, err := eventsDatabase.GetEvents(since)
+		if err != nil {
+			return nil, nil, err
+		}
+		if since > 0 && len(es) > 0 && es[0].Revision > since+1 {
+			return nil, nil, ErrCompacted
+		}
+		backlog = es
+	}
+
+	ch := make(chan evtypes.Event, subscriberBuffer)
+	subscribers[ch] = true
+	return backlog, ch, nil
+}
+
+// Unsubscribe removes subscriber and closes its channel
+func Unsubscribe(ch chan evtypes.Event) {
+	subLock.Lock()
+	defer subLock.Unlock()
+	if _, ok := subscribers[ch]; ok {
+		delete(subscribers, ch)
+		close(ch)
+	}
+}
@@ -0,0 +1,138 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/emicklei/go-restful"
+	log "github.com/sirupsen/logrus"
+
+	evtypes "github.com/intel/rmd/modules/events/types"
+	"github.com/intel/rmd/utils/acl"
+)
+
+// keep alive comment interval, prevents proxies from closing idle stream
+var keepAliveInterval = 30 * time.Second
+
+// Register add handler for /v1/events endpoint
+func Register(prefix string, container *restful.Container) {
+	if err := Init(); err != nil {
+		log.Errorf("Failed to initialize events module: %s", err.Error())
+	}
+
+	ws := new(restful.WebService)
+	ws.
+		Path(prefix + "events").
+		Doc("Stream of workload and resource events").
+		Produces("text/event-stream")
+
+	ws.Route(ws.GET("/").To(Watch).
+		Doc("Watch events as server-sent events, only new events are sent unless since parameter or Last-Event-ID header is given").
+		Param(ws.QueryParameter("since", "Send also kept events with revision greater than given one").DataType("integer")).
+		Operation("EventsWatch"))
+
+	container.Add(ws)
+}
+
+// Watch handles GET /v1/events
+func Watch(request *restful.Request, response *restful.Response) {
+	since, replay, err := getSince(request)
+	if err != nil {
+		response.WriteErrorString(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	flusher, ok := response.ResponseWriter.(http.Flusher)
+	if !ok {
+		response.WriteErrorString(http.StatusInternalServerError, "Streaming not supported")
+		return
+	}
+
+	backlog, ch, err := Subscribe(since, replay)
+	if err == ErrCompacted {
+		// client has to list workloads again and watch new events
+		response.WriteErrorString(http.StatusGone, err.Error())
+		return
+	}
+	if err != nil {
+		response.WriteErrorString(http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer Unsubscribe(ch)
+
+	response.AddHeader("Content-Type", "text/event-stream")
+	response.AddHeader("Cache-Control", "no-cache")
+	response.WriteHeader(http.StatusOK)
+
+	// events of workloads of other tenants are not sent
+	tenant, admin := acl.GetTenant(request)
+	for _, e := range backlog {
+		if !isVisible(e, tenant, admin) {
+			continue
+		}
+		if err := writeEvent(response, e); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				// subscriber dropped
+				return
+			}
+			if !isVisible(e, tenant, admin) {
+				continue
+			}
+			if err := writeEvent(response, e); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(response, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-request.Request.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// getSince returns revision to resume from (since parameter takes precedence
+// over Last-Event-ID header) and whether it was given at all
+func getSince(request *restful.Request) (uint64, bool, error) {
+	value := request.QueryParameter("since")
+	if len(value) == 0 {
+		value = request.HeaderParameter("Last-Event-ID")
+	}
+	if len(value) == 0 {
+		return 0, false, nil
+	}
+	since, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("Invalid revision: %s", value)
+	}
+	return since, true, nil
+}
+
+// isVisible checks if event can be seen by tenant of request
+func isVisible(e evtypes.Event, tenant string, admin bool) bool {
+	return admin || (e.Workload != nil && e.Workload.Tenant == tenant)
+}
+
+// writeEvent writes event in server-sent events format
+func writeEvent(response *restful.Response, e evtypes.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(response, "id: %d\nevent: %s\ndata: %s\n\n", e.Revision, e.Type, data)
+	return err
+}
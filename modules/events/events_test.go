@@ -0,0 +1,73 @@
+package events
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/intel/rmd/internal/db"
+	evtypes "github.com/intel/rmd/modules/events/types"
+	wltypes "github.com/intel/rmd/modules/workload/types"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "events")
+	if err != nil {
+		os.Exit(1)
+	}
+	viper.Set("database.backend", "bolt")
+	viper.Set("database.transport", filepath.Join(dir, "rmd.db"))
+	viper.Set("database.dbname", "rmd")
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	// new subscriber gets only new events
+	backlog, ch, err := Subscribe(0, false)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	defer Unsubscribe(ch)
+	if len(backlog) != 0 {
+		t.Errorf("Subscribe() returned %d events without replay", len(backlog))
+	}
+
+	w := &wltypes.RDTWorkLoad{ID: "1", TaskIDs: []string{"10"}}
+	Publish(evtypes.WorkloadCreated, w, "")
+	// published workload is a copy
+	w.TaskIDs[0] = "11"
+	Publish(evtypes.WorkloadDeleted, w, "")
+
+	first := <-ch
+	second := <-ch
+	if first.Type != evtypes.WorkloadCreated || first.WorkloadID != "1" || first.Workload.TaskIDs[0] != "10" {
+		t.Errorf("Unexpected first event %+v", first)
+	}
+	if second.Type != evtypes.WorkloadDeleted || second.Revision != first.Revision+1 {
+		t.Errorf("Unexpected second event %+v", second)
+	}
+
+	// resume after first event
+	backlog, resumed, err := Subscribe(first.Revision, true)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	defer Unsubscribe(resumed)
+	if len(backlog) != 1 || backlog[0].Revision != second.Revision {
+		t.Errorf("Subscribe() replayed %+v, want only revision %d", backlog, second.Revision)
+	}
+}
+
+func TestSubscribeCompacted(t *testing.T) {
+	for i := 0; i < db.MaxEvents+1; i++ {
+		Publish(evtypes.WorkloadUpdated, nil, "")
+	}
+	if _, _, err := Subscribe(1, true); err != ErrCompacted {
+		t.Errorf("Subscribe() error = %v, want %v", err, ErrCompacted)
+	}
+}
@@ -0,0 +1,48 @@
+package types
+
+import (
+	"time"
+
+	wltypes "github.com/intel/rmd/modules/workload/types"
+)
+
+const (
+	// WorkloadCreated workload was created
+	WorkloadCreated = "workload.created"
+	// WorkloadUpdated workload was updated (patched, re-enforced or its tasks changed)
+	WorkloadUpdated = "workload.updated"
+	// WorkloadDeleted workload was deleted
+	WorkloadDeleted = "workload.deleted"
+	// WorkloadFailed workload enforcement failed
+	WorkloadFailed = "workload.failed"
+	// WorkloadRemoved workload was removed by database validator (tasks or cgroup do not exist anymore)
+	WorkloadRemoved = "workload.removed"
+	// WorkloadExpired workload was released and removed after its lease expired
+	WorkloadExpired = "workload.expired"
+	// WorkloadPolicyDrift workload differs from its policy after policy file reload
+	WorkloadPolicyDrift = "workload.policy_drift"
+	// WorkloadDemoted workload moved out of guarantee pool for workload with higher priority
+	WorkloadDemoted = "workload.demoted"
+	// WorkloadRestored demoted workload enforced again with requested cache
+	WorkloadRestored = "workload.restored"
+	// WorkloadThrottled workload MBA capped for exceeding memory bandwidth threshold
+	WorkloadThrottled = "workload.throttled"
+	// WorkloadUnthrottled MBA cap of workload lifted after cooldown
+	WorkloadUnthrottled = "workload.unthrottled"
+	// PoolShrunk besteffort pool workloads were shrunk to make place for new workload
+	PoolShrunk = "pool.shrunk"
+	// OpenStackNotification result of OpenStack notification handling
+	OpenStackNotification = "openstack.notification"
+)
+
+// Event is workload or resource event
+type Event struct {
+	// Revision is increased by one for every event
+	Revision uint64    `json:"revision"`
+	Time     time.Time `json:"time"`
+	Type     string    `json:"type"`
+	// WorkloadID is empty if workload was not created
+	WorkloadID string               `json:"workload_id,omitempty"`
+	Workload   *wltypes.RDTWorkLoad `json:"workload,omitempty"`
+	Message    string               `json:"message,omitempty"`
+}
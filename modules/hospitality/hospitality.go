@@ -23,6 +23,10 @@ type Request struct {
 	MinCache uint32  `json:"min_cache,omitempty"`
 	Policy   string  `json:"policy,omitempty"`
 	CacheID  *uint32 `json:"cache_id,omitempty"`
+	// L2 cache request, scored only if both values are provided (platforms with L2 CAT)
+	L2MaxCache *uint32 `json:"l2_max_cache,omitempty"`
+	L2MinCache *uint32 `json:"l2_min_cache,omitempty"`
+	L2CacheID  *uint32 `json:"l2_cache_id,omitempty"`
 }
 
 // CacheScore represents the score on specific cache id
@@ -35,6 +39,10 @@ type CacheScore map[string]uint32
 		"l3": {
 			"0": 30
 			"1": 30
+		},
+		"l2": {
+			"0": 100
+			"1": 0
 		}
 	}
 }
@@ -54,6 +62,12 @@ func (h *Hospitality) GetByRequest(req *Request) error {
 	max := req.MaxCache
 	min := req.MinCache
 
+	useL2 := req.L2MaxCache != nil || req.L2MinCache != nil
+	if useL2 && (req.L2MaxCache == nil || req.L2MinCache == nil) {
+		return rmderror.AppErrorf(http.StatusBadRequest,
+			"Bad request, need to provide both l2_max_cache and l2_min_cache")
+	}
+
 	if req.Policy != "" {
 		tier, err := policy.GetDefaultPolicy(req.Policy)
 		if err != nil {
@@ -89,7 +103,18 @@ func (h *Hospitality) GetByRequest(req *Request) error {
 		max = uint32(m)
 		min = uint32(n)
 	}
-	return h.GetByRequestMaxMin(max, min, req.CacheID, targetLev)
+	if err := h.GetByRequestMaxMin(max, min, req.CacheID, targetLev); err != nil {
+		return err
+	}
+
+	if useL2 {
+		if !cache.IsL2CatEnabled() {
+			return rmderror.AppErrorf(http.StatusBadRequest,
+				"Bad request, L2 cache allocation is not supported on this host")
+		}
+		return h.GetByRequestMaxMin(*req.L2MaxCache, *req.L2MinCache, req.L2CacheID, "2")
+	}
+	return nil
 }
 
 // GetByRequestMaxMin constructs Hospitality struct by max and min cache ways
@@ -117,9 +142,15 @@ func (h *Hospitality) GetByRequestMaxMin(max, min uint32, cacheIDuint *uint32, t
 	}
 
 	cacheS := make(map[string]uint32)
-	h.SC = map[string]CacheScore{"l" + targetLev: cacheS}
+	if h.SC == nil {
+		h.SC = make(map[string]CacheScore)
+	}
+	h.SC["l"+targetLev] = cacheS
 
 	reserved := cache.GetReservedInfo()
+	if targetLev == "2" && cache.IsL2CatEnabled() {
+		reserved = cache.GetL2ReservedInfo()
+	}
 
 	if reqType == cache.Shared {
 		dbc, err := db.NewDB()
@@ -29,6 +29,14 @@ func TestGetByRequest(t *testing.T) {
 			err := h.GetByRequest(req)
 			c.So(err, ShouldNotBeNil)
 		})
+		c.Convey("Test get hospitality score bad request only l2 max", func(c C) {
+			var l2max uint32 = 2
+			req.MaxCache = 1
+			req.MinCache = 1
+			req.L2MaxCache = &l2max
+			err := h.GetByRequest(req)
+			c.So(err, ShouldNotBeNil)
+		})
 
 	})
 
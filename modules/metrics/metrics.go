@@ -0,0 +1,184 @@
+package metrics
+
+// Prometheus metrics of RMD: allocation state and REST API statistics
+
+import (
+	"math/bits"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emicklei/go-restful"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	proxyclient "github.com/intel/rmd/internal/proxy/client"
+	"github.com/intel/rmd/modules/cache"
+	"github.com/intel/rmd/modules/workload"
+	"github.com/intel/rmd/utils/pqos"
+)
+
+const namespace = "rmd"
+
+var (
+	// registry used by /metrics endpoint, built once and shared by all containers
+	registry     *prometheus.Registry
+	registryOnce sync.Once
+
+	requestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of REST requests handled by RMD.",
+		},
+		[]string{"method", "route", "code"},
+	)
+	requestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of REST requests handled by RMD.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"method", "route"},
+	)
+
+	workloadInfoDesc = prometheus.NewDesc(
+		namespace+"_workload_info",
+		"Workload information, value is always 1.",
+		[]string{"id", "cos_name", "policy", "origin", "status"}, nil)
+	workloadCacheWaysDesc = prometheus.NewDesc(
+		namespace+"_workload_cache_ways",
+		"Number of last level cache ways allocated to workload COS.",
+		[]string{"id", "cos_name", "cache_id"}, nil)
+	workloadMbaPercentageDesc = prometheus.NewDesc(
+		namespace+"_workload_mba_percentage",
+		"Memory bandwidth allocation of workload in percentage mode.",
+		[]string{"id", "cos_name"}, nil)
+	workloadMbaMbpsDesc = prometheus.NewDesc(
+		namespace+"_workload_mba_mbps",
+		"Memory bandwidth allocation of workload in MBps mode.",
+		[]string{"id", "cos_name"}, nil)
+	freeCLOSDesc = prometheus.NewDesc(
+		namespace+"_free_clos",
+		"Number of CLOSes available for new workloads.",
+		nil, nil)
+	poolWaysDesc = prometheus.NewDesc(
+		namespace+"_cache_pool_ways",
+		"Number of cache ways configured for cache pool.",
+		[]string{"pool", "cache_id"}, nil)
+	poolFreeWaysDesc = prometheus.NewDesc(
+		namespace+"_cache_pool_free_ways",
+		"Number of cache ways not allocated yet in cache pool.",
+		[]string{"pool", "cache_id"}, nil)
+)
+
+// numeric path segments (workload ids) are replaced to keep route label cardinality low
+var idSegment = regexp.MustCompile(`/[0-9]+(/|$)`)
+
+// routeLabel converts request path to route label, e.g. /v1/workloads/12 -> /v1/workloads/{id}
+func routeLabel(path string) string {
+	return idSegment.ReplaceAllString(path, "/{id}$1")
+}
+
+// Filter is a container filter that counts REST requests and measures their latency
+func Filter(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+	start := time.Now()
+	chain.ProcessFilter(req, resp)
+
+	route := routeLabel(req.Request.URL.Path)
+	code := strconv.Itoa(resp.StatusCode())
+	requestsTotal.WithLabelValues(req.Request.Method, route, code).Inc()
+	requestDuration.WithLabelValues(req.Request.Method, route).Observe(time.Since(start).Seconds())
+}
+
+// rmdCollector collects allocation state at scrape time
+type rmdCollector struct{}
+
+// Describe implements prometheus.Collector
+func (rmdCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- workloadInfoDesc
+	ch <- workloadCacheWaysDesc
+	ch <- workloadMbaPercentageDesc
+	ch <- workloadMbaMbpsDesc
+	ch <- freeCLOSDesc
+	ch <- poolWaysDesc
+	ch <- poolFreeWaysDesc
+}
+
+// Collect implements prometheus.Collector
+func (rmdCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(freeCLOSDesc, prometheus.GaugeValue,
+		float64(pqos.GetNumberOfFreeCLOSes()))
+
+	// resource groups of used CLOSes only
+	resaall := proxyclient.GetResAssociation(pqos.GetAvailableCLOSes())
+	level := "L" + strconv.FormatUint(uint64(cache.GetLLC()), 10)
+
+	ws, err := workload.GetAll()
+	if err != nil {
+		log.Errorf("Failed to get workloads for metrics: %v", err)
+	}
+	for _, w := range ws {
+		ch <- prometheus.MustNewConstMetric(workloadInfoDesc, prometheus.GaugeValue, 1,
+			w.ID, w.CosName, w.Policy, w.Origin, w.Status)
+		if w.Rdt.Mba.Percentage != nil {
+			ch <- prometheus.MustNewConstMetric(workloadMbaPercentageDesc, prometheus.GaugeValue,
+				float64(*w.Rdt.Mba.Percentage), w.ID, w.CosName)
+		}
+		if w.Rdt.Mba.Mbps != nil {
+			ch <- prometheus.MustNewConstMetric(workloadMbaMbpsDesc, prometheus.GaugeValue,
+				float64(*w.Rdt.Mba.Mbps), w.ID, w.CosName)
+		}
+		res, ok := resaall[w.CosName]
+		if !ok {
+			continue
+		}
+		for _, c := range res.CacheSchemata[level] {
+			ch <- prometheus.MustNewConstMetric(workloadCacheWaysDesc, prometheus.GaugeValue,
+				float64(maskWays(c.Mask)), w.ID, w.CosName, strconv.Itoa(int(c.ID)))
+		}
+	}
+
+	layout, err := cache.GetCachePoolLayout()
+	if err != nil {
+		log.Errorf("Failed to get cache pool layout for metrics: %v", err)
+		return
+	}
+	for pool, r := range layout {
+		for id, bm := range r.Schemata {
+			ch <- prometheus.MustNewConstMetric(poolWaysDesc, prometheus.GaugeValue,
+				float64(strings.Count(bm.ToBinString(), "1")), pool, id)
+		}
+		free, err := cache.GetAvailableCacheSchemata(resaall,
+			[]string{pqos.InfraGoupCOS, pqos.OSGroupCOS}, pool, level)
+		if err != nil {
+			log.Debugf("Failed to get available schemata of %s pool: %v", pool, err)
+			continue
+		}
+		for id, bm := range free {
+			ch <- prometheus.MustNewConstMetric(poolFreeWaysDesc, prometheus.GaugeValue,
+				float64(strings.Count(bm.ToBinString(), "1")), pool, id)
+		}
+	}
+}
+
+// maskWays returns number of ways (set bits) in hex cache mask
+func maskWays(mask string) int {
+	val, err := strconv.ParseUint(strings.TrimSpace(mask), 16, 64)
+	if err != nil {
+		return 0
+	}
+	return bits.OnesCount64(val)
+}
+
+// getRegistry returns registry with all RMD collectors
+func getRegistry() *prometheus.Registry {
+	registryOnce.Do(func() {
+		registry = prometheus.NewRegistry()
+		registry.MustRegister(requestsTotal, requestDuration, rmdCollector{})
+	})
+	return registry
+}
@@ -0,0 +1,26 @@
+package metrics
+
+import (
+	"github.com/emicklei/go-restful"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Register add handler for /metrics endpoint
+// Endpoint is not versioned as it follows Prometheus convention
+func Register(container *restful.Container) {
+	handler := promhttp.HandlerFor(getRegistry(), promhttp.HandlerOpts{})
+
+	ws := new(restful.WebService)
+	ws.
+		Path("/metrics").
+		Doc("Show metrics in Prometheus text format").
+		Produces("*/*")
+
+	ws.Route(ws.GET("/").To(func(request *restful.Request, response *restful.Response) {
+		handler.ServeHTTP(response, request.Request)
+	}).
+		Doc("Get metrics").
+		Operation("MetricsGet"))
+
+	container.Add(ws)
+}
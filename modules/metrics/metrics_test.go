@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/emicklei/go-restful"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRouteLabel(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"collection", "/v1/workloads", "/v1/workloads"},
+		{"single workload", "/v1/workloads/12", "/v1/workloads/{id}"},
+		{"workload subresource", "/v1/workloads/12/metrics", "/v1/workloads/{id}/metrics"},
+		{"cache level", "/v1/cache/l3", "/v1/cache/l3"},
+		{"cache id", "/v1/cache/l3/0", "/v1/cache/l3/{id}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := routeLabel(tt.path); got != tt.want {
+				t.Errorf("routeLabel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskWays(t *testing.T) {
+	tests := []struct {
+		name string
+		mask string
+		want int
+	}{
+		{"full mask", "fffff", 20},
+		{"single way", "1", 1},
+		{"middle ways", "7f8", 8},
+		{"trailing new line", "3\n", 2},
+		{"invalid mask", "xyz", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := maskWays(tt.mask); got != tt.want {
+				t.Errorf("maskWays() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	container := restful.NewContainer()
+	container.Filter(Filter)
+	container.Router(restful.CurlyRouter{})
+
+	ws := new(restful.WebService)
+	ws.Path("/v1/test")
+	ws.Route(ws.GET("/{id}").To(func(request *restful.Request, response *restful.Response) {
+		response.WriteHeader(http.StatusAccepted)
+	}))
+	container.Add(ws)
+
+	for _, path := range []string{"/v1/test/1", "/v1/test/2"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		container.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	got := testutil.ToFloat64(requestsTotal.WithLabelValues(http.MethodGet, "/v1/test/{id}", "202"))
+	if got != 2 {
+		t.Errorf("Filter() counted %v requests, want 2", got)
+	}
+}
@@ -0,0 +1,153 @@
+package policy
+
+// Cache params can be given as number of ways (platform specific) or as
+// percentage of last level cache or size in MB (portable between platforms).
+// MBA params can be given as percentage and/or Mbps, the one matching MBA mode
+// of platform is used.
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+
+	rmderror "github.com/intel/rmd/internal/error"
+)
+
+const (
+	// DefaultArch is name of policy file section used for CPU architectures
+	// without own section
+	DefaultArch = "default"
+
+	// PercentSuffix marks cache param given as percentage of cache ways
+	PercentSuffix = "_percent"
+	// MBSuffix marks cache param given as cache size in MB
+	MBSuffix = "_mb"
+
+	// maximum MBA percentage value
+	maxMBAPercentage = 100
+	// maximum MBA Mbps value
+	maxMBAMbps = 4294967290
+)
+
+// ParamToUint32 converts numeric policy param (int from YAML, int64 from TOML
+// or float64 from JSON) to uint32
+func ParamToUint32(v interface{}) (uint32, bool) {
+	val, ok := ParamToFloat64(v)
+	if !ok || val != math.Trunc(val) || val < 0 || val > math.MaxUint32 {
+		return 0, false
+	}
+	return uint32(val), true
+}
+
+// ParamToFloat64 converts numeric policy param to float64
+func ParamToFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// cacheParamKey returns name of param used to define cache bound ("max" or
+// "min") in cache params, error if none or more than one is used
+func cacheParamKey(params Param, bound string) (string, error) {
+	key := ""
+	for _, k := range []string{bound, bound + PercentSuffix, bound + MBSuffix} {
+		if _, ok := params[k]; ok {
+			if key != "" {
+				return "", fmt.Errorf("Policy cache params %s and %s are exclusive", key, k)
+			}
+			key = k
+		}
+	}
+	if key == "" {
+		return "", fmt.Errorf("Policy cache param %s is missing", bound)
+	}
+	return key, nil
+}
+
+// validateCacheParams checks cache params of policy
+func validateCacheParams(params Param) error {
+	values := map[string]float64{}
+	for _, bound := range []string{"max", "min"} {
+		key, err := cacheParamKey(params, bound)
+		if err != nil {
+			return err
+		}
+		switch key {
+		case bound:
+			val, ok := ParamToUint32(params[key])
+			if !ok {
+				return fmt.Errorf("Policy cache param %s has to be non negative integer", key)
+			}
+			values[key] = float64(val)
+		case bound + PercentSuffix:
+			val, ok := ParamToFloat64(params[key])
+			if !ok || val < 0 || val > 100 {
+				return fmt.Errorf("Policy cache param %s has to be number from 0 to 100", key)
+			}
+			values[key] = val
+		case bound + MBSuffix:
+			val, ok := ParamToFloat64(params[key])
+			if !ok || val < 0 {
+				return fmt.Errorf("Policy cache param %s has to be non negative number", key)
+			}
+			values[key] = val
+		}
+	}
+	// min and max given in different units can be compared only on platform
+	for _, suffix := range []string{"", PercentSuffix, MBSuffix} {
+		max, okMax := values["max"+suffix]
+		min, okMin := values["min"+suffix]
+		if okMax && okMin && min > max {
+			return fmt.Errorf("Policy cache min is greater than max")
+		}
+	}
+	return nil
+}
+
+// validateMBAParams checks MBA params of policy
+func validateMBAParams(params Param) error {
+	_, hasPercentage := params["percentage"]
+	_, hasMbps := params["mbps"]
+	if !hasPercentage && !hasMbps {
+		return fmt.Errorf("Policy mba params percentage or mbps are missing")
+	}
+	if hasPercentage {
+		val, ok := ParamToUint32(params["percentage"])
+		if !ok || val < 1 || val > maxMBAPercentage {
+			return fmt.Errorf("Policy mba percentage has to be integer from 1 to %d", maxMBAPercentage)
+		}
+	}
+	if hasMbps {
+		val, ok := ParamToUint32(params["mbps"])
+		if !ok || val < 1 || val > maxMBAMbps {
+			return fmt.Errorf("Policy mba mbps has to be integer from 1 to %d", uint32(maxMBAMbps))
+		}
+	}
+	return nil
+}
+
+// validateParams checks values of cache and MBA params without checking
+// platform capabilities
+func validateParams(m Module) error {
+	for mod, params := range m {
+		var err error
+		switch mod {
+		case "cache":
+			err = validateCacheParams(params)
+		case "mba":
+			err = validateMBAParams(params)
+		}
+		if err != nil {
+			return rmderror.NewAppError(http.StatusBadRequest, err.Error())
+		}
+	}
+	return nil
+}
@@ -8,9 +8,9 @@ import (
 	"strings"
 	"sync"
 
+	ptypes "github.com/intel/rmd/modules/policy/types"
 	util "github.com/intel/rmd/utils"
 	appConf "github.com/intel/rmd/utils/config"
-	"github.com/intel/rmd/utils/cpu"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
@@ -21,43 +21,24 @@ var supportedConfigType = map[string]int{
 }
 
 // Param represents single policy attribute
-// example: "MaxCache: 4"
-//
-type Param map[string]interface{}
+type Param = ptypes.Param
 
 // Module represents attributes for single module
-// example:
-//     Cache:
-//          Min: 1
-//          Max: 1
-//
-type Module map[string]Param
+type Module = ptypes.Module
 
 // Policy represents policy type
-// example:
-//       gold:
-//           Cache:
-//                Min: 1
-//                Max: 1
-//           Pstate:
-//                Ratio: 0.1
-//
-type Policy map[string]Module
+type Policy = ptypes.Policy
 
 // CPUArchitecture represents cpu model
-// example:
-//    broadwell:
-//       gold:
-//           Cache:
-//                Min: 1
-//                Max: 1
-//           Pstate:
-//                Ratio: 0.1
-//
-type CPUArchitecture map[string]Policy
+type CPUArchitecture = ptypes.CPUArchitecture
 
 var lock sync.Mutex
 
+// policyPath returns path of policy file
+var policyPath = func() string {
+	return appConf.NewConfig().Def.PolicyPath
+}
+
 // GetDefaultPlatformPolicy gets policy for default platform
 func GetDefaultPlatformPolicy() (Policy, error) {
 	platform, err := LoadPolicyInfo()
@@ -80,25 +61,31 @@ func LoadPolicyInfo() (Policy, error) {
 	}
 
 	// 2. check architecure (need only stuff related with used CPU)
-	cpu := strings.ToLower(cpu.GetMicroArch(cpu.GetSignature()))
-	if cpu == "" {
-		return Policy{}, fmt.Errorf("Unknown platform, please update the cpu_map.toml conf file")
-	}
+	cpu := currentArch()
 
 	// 3. Get main element of policy for your cpu
-	platform, ok := rmdpolicy[cpu]
+	// (policies defined at runtime override the ones from file and
+	// default policies are used for CPUs without own policies)
+	runtime, err := loadRuntimePolicies()
+	if err != nil {
+		log.Debugf("Runtime policies not available: %v", err)
+	}
+	platform, ok := platformPolicies(rmdpolicy, runtime, cpu)
 	if !ok {
+		if cpu == "" {
+			return Policy{}, fmt.Errorf("Unknown platform, please update the cpu_map.toml conf file or define default policies")
+		}
 		return Policy{}, fmt.Errorf("Error while get platform policy: %s", cpu)
 	}
 
 	return platform, nil
 }
 
-// loadPolicy loads pre-defined policies from configure file
-func loadPolicy() (CPUArchitecture, error) {
+// readPolicyFile reads and parses pre-defined policies from configure file
+func readPolicyFile() (CPUArchitecture, error) {
 
-	appconf := appConf.NewConfig()
-	configFileExt := filepath.Ext(appconf.Def.PolicyPath)
+	path := policyPath()
+	configFileExt := filepath.Ext(path)
 
 	if !strings.HasPrefix(configFileExt, ".") {
 		err := fmt.Errorf("Unknown policy file type extension %s", configFileExt)
@@ -114,12 +101,12 @@ func loadPolicy() (CPUArchitecture, error) {
 		return nil, err
 	}
 
-	isfile, err := util.IsRegularFile(appconf.Def.PolicyPath)
+	isfile, err := util.IsRegularFile(path)
 	if err != nil || !isfile {
-		return nil, fmt.Errorf("Invalid policy file path %s", appconf.Def.PolicyPath)
+		return nil, fmt.Errorf("Invalid policy file path %s", path)
 	}
 
-	text, err := ioutil.ReadFile(appconf.Def.PolicyPath)
+	text, err := ioutil.ReadFile(path)
 	if err != nil { // Handle errors reading the config file
 		err := fmt.Errorf("Error during config file reading: %s", err)
 		log.Errorf("error: %v", err)
@@ -2,10 +2,22 @@ package policy
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/emicklei/go-restful"
+
+	rmderror "github.com/intel/rmd/internal/error"
 )
 
+// Result is returned by runtime policy handlers
+type Result struct {
+	Arch      string            `json:"arch"`
+	Name      string            `json:"name"`
+	Params    Module            `json:"params,omitempty"`
+	Reapplied []string          `json:"reapplied,omitempty"`
+	Failed    map[string]string `json:"failed,omitempty"`
+}
+
 // Register handlers
 func Register(prefix string, container *restful.Container) {
 	ws := new(restful.WebService)
@@ -20,6 +32,51 @@ func Register(prefix string, container *restful.Container) {
 		Operation("PolicyGet"))
 
 	container.Add(ws)
+
+	pws := new(restful.WebService)
+	pws.
+		Path(prefix + "policies").
+		Doc("Manage policies at runtime").
+		Consumes(restful.MIME_JSON).
+		Produces(restful.MIME_JSON)
+
+	archParam := pws.QueryParameter("arch", "CPU architecture (architecture of host policies by default)").DataType("string")
+	reapplyParam := pws.QueryParameter("reapply", "Re-enforce workloads using policy").DataType("boolean")
+
+	pws.Route(pws.GET("/").To(GetPolicyList).
+		Doc("Get all policies for CPU architecture").
+		Param(archParam).
+		Operation("PoliciesGet"))
+
+	pws.Route(pws.GET("/{name}").To(GetPolicyByName).
+		Doc("Get policy by name").
+		Param(pws.PathParameter("name", "policy name").DataType("string")).
+		Param(archParam).
+		Operation("PolicyGetByName"))
+
+	pws.Route(pws.POST("/{name}").To(NewPolicy).
+		Doc("Create new policy").
+		Param(pws.PathParameter("name", "policy name").DataType("string")).
+		Param(archParam).
+		Operation("PolicyNew").
+		Reads(Module{}))
+
+	pws.Route(pws.PUT("/{name}").To(PutPolicy).
+		Doc("Create or replace policy").
+		Param(pws.PathParameter("name", "policy name").DataType("string")).
+		Param(archParam).
+		Param(reapplyParam).
+		Operation("PolicyPut").
+		Reads(Module{}))
+
+	pws.Route(pws.DELETE("/{name}").To(DeletePolicyByName).
+		Doc("Remove runtime policy").
+		Param(pws.PathParameter("name", "policy name").DataType("string")).
+		Param(archParam).
+		Param(reapplyParam).
+		Operation("PolicyDelete"))
+
+	container.Add(pws)
 }
 
 // Get is handler to for GET
@@ -33,3 +90,117 @@ func Get(request *restful.Request, response *restful.Response) {
 
 	response.WriteEntity(result)
 }
+
+// GetPolicyList handles GET /policies
+func GetPolicyList(request *restful.Request, response *restful.Response) {
+	result, err := GetPolicies(getArch(request))
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+	response.WriteEntity(result)
+}
+
+// GetPolicyByName handles GET /policies/{name}
+func GetPolicyByName(request *restful.Request, response *restful.Response) {
+	arch := getArch(request)
+	name := request.PathParameter("name")
+
+	platform, err := GetPolicies(arch)
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+	m, ok := platform[name]
+	if !ok {
+		writeError(response, rmderror.AppErrorf(http.StatusNotFound, "Policy %s not found", name))
+		return
+	}
+	response.WriteEntity(Result{Arch: arch, Name: name, Params: m})
+}
+
+// NewPolicy handles POST /policies/{name}
+func NewPolicy(request *restful.Request, response *restful.Response) {
+	arch := getArch(request)
+	name := request.PathParameter("name")
+
+	m, err := readModule(request)
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+	if err := CreatePolicy(arch, name, m); err != nil {
+		writeError(response, err)
+		return
+	}
+	response.WriteHeaderAndEntity(http.StatusCreated, Result{Arch: arch, Name: name, Params: m})
+}
+
+// PutPolicy handles PUT /policies/{name}
+func PutPolicy(request *restful.Request, response *restful.Response) {
+	arch := getArch(request)
+	name := request.PathParameter("name")
+
+	m, err := readModule(request)
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+
+	status := http.StatusOK
+	if platform, err := GetPolicies(arch); err != nil || platform[name] == nil {
+		status = http.StatusCreated
+	}
+	if err := SetPolicy(arch, name, m); err != nil {
+		writeError(response, err)
+		return
+	}
+
+	result := Result{Arch: arch, Name: name, Params: m}
+	if request.QueryParameter("reapply") == "true" {
+		result.Reapplied, result.Failed = Reapply(arch, name)
+	}
+	response.WriteHeaderAndEntity(status, result)
+}
+
+// DeletePolicyByName handles DELETE /policies/{name}
+func DeletePolicyByName(request *restful.Request, response *restful.Response) {
+	arch := getArch(request)
+	name := request.PathParameter("name")
+
+	if err := DeletePolicy(arch, name); err != nil {
+		writeError(response, err)
+		return
+	}
+
+	// file policy with the same name (if any) is used from now on
+	result := Result{Arch: arch, Name: name}
+	if request.QueryParameter("reapply") == "true" {
+		result.Reapplied, result.Failed = Reapply(arch, name)
+	}
+	response.WriteHeaderAndEntity(http.StatusOK, result)
+}
+
+func getArch(request *restful.Request) string {
+	if arch := request.QueryParameter("arch"); arch != "" {
+		return strings.ToLower(arch)
+	}
+	return hostArch()
+}
+
+func readModule(request *restful.Request) (Module, error) {
+	m := Module{}
+	if err := request.ReadEntity(&m); err != nil {
+		return nil, rmderror.NewAppError(http.StatusBadRequest, "Failed to parse policy", err)
+	}
+	return normalizeModule(m), nil
+}
+
+func writeError(response *restful.Response, err error) {
+	httpStatus := http.StatusInternalServerError
+	if appErr, ok := err.(*rmderror.AppError); ok && appErr.Code >= http.StatusBadRequest {
+		httpStatus = appErr.Code
+	}
+	response.AddHeader("Content-Type", "text/plain")
+	response.WriteErrorString(httpStatus, err.Error())
+}
@@ -1,9 +1,13 @@
 package policy
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
+	"github.com/prashantv/gostub"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
@@ -149,3 +153,221 @@ func TestGetDefaultPolicy(t *testing.T) {
 		})
 	}
 }
+
+func TestParamToUint32(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  interface{}
+		want   uint32
+		wantOk bool
+	}{
+		{"int from yaml", 6, 6, true},
+		{"int64 from toml", int64(4), 4, true},
+		{"float64 from json", float64(10), 10, true},
+		{"fractional float64", 1.5, 0, false},
+		{"negative value", -1, 0, false},
+		{"string value", "2", 0, false},
+		{"missing value", nil, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParamToUint32(tt.value)
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("ParamToUint32() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_normalizeModule(t *testing.T) {
+	got := normalizeModule(Module{
+		"Cache":  Param{"MAX": float64(4), "min": float64(2)},
+		"pstate": Param{"ratio": 1.5},
+	})
+	want := Module{
+		"cache":  Param{"max": int64(4), "min": int64(2)},
+		"pstate": Param{"ratio": 1.5},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("normalizeModule() = %v, want %v", got, want)
+	}
+}
+
+func Test_mergePolicies(t *testing.T) {
+	gold := Module{"cache": Param{"max": 4, "min": 4}}
+	runtimeGold := Module{"cache": Param{"max": int64(6), "min": int64(6)}}
+	silver := Module{"cache": Param{"max": int64(2), "min": int64(1)}}
+	file := CPUArchitecture{"skylake": Policy{"gold": gold}}
+
+	tests := []struct {
+		name    string
+		runtime CPUArchitecture
+		arch    string
+		want    Policy
+		wantOk  bool
+	}{
+		{"file only", nil, "skylake", Policy{"gold": gold}, true},
+		{"runtime overrides file", CPUArchitecture{"skylake": Policy{"gold": runtimeGold}},
+			"skylake", Policy{"gold": runtimeGold}, true},
+		{"runtime adds policy", CPUArchitecture{"skylake": Policy{"silver": silver}},
+			"skylake", Policy{"gold": gold, "silver": silver}, true},
+		{"runtime only arch", CPUArchitecture{"icelake": Policy{"silver": silver}},
+			"icelake", Policy{"silver": silver}, true},
+		{"unknown arch", nil, "broadwell", Policy{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := mergePolicies(file, tt.runtime, tt.arch)
+			if ok != tt.wantOk {
+				t.Errorf("mergePolicies() ok = %v, want %v", ok, tt.wantOk)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergePolicies() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePolicy(t *testing.T) {
+	// use architecture other than host one so platform is not checked
+	arch := "testarch"
+	tests := []struct {
+		name    string
+		module  Module
+		wantErr bool
+	}{
+		{"valid cache", Module{"cache": Param{"max": int64(4), "min": int64(2)}}, false},
+		{"valid mba", Module{"mba": Param{"percentage": int64(50)}}, false},
+		{"empty policy", Module{}, true},
+		{"missing cache min", Module{"cache": Param{"max": int64(4)}}, true},
+		{"cache min greater than max", Module{"cache": Param{"max": int64(2), "min": int64(4)}}, true},
+		{"mba out of range", Module{"mba": Param{"percentage": int64(120)}}, true},
+		{"plugin not loaded", Module{"pstate": Param{"ratio": 1.5}}, true},
+		{"valid cache percentage", Module{"cache": Param{"max_percent": int64(50), "min_percent": 12.5}}, false},
+		{"valid cache size", Module{"cache": Param{"max_mb": 8.5, "min_mb": int64(4)}}, false},
+		{"mixed cache units", Module{"cache": Param{"max_percent": int64(50), "min_mb": int64(4)}}, false},
+		{"exclusive cache params", Module{"cache": Param{"max": int64(4), "max_percent": int64(50),
+			"min": int64(2)}}, true},
+		{"cache percentage out of range", Module{"cache": Param{"max_percent": int64(150), "min_percent": int64(10)}}, true},
+		{"cache percentage min greater than max", Module{"cache": Param{"max_percent": int64(10), "min_percent": int64(20)}}, true},
+		{"valid mba mbps", Module{"mba": Param{"mbps": int64(2000)}}, false},
+		{"valid mba both modes", Module{"mba": Param{"percentage": int64(50), "mbps": int64(2000)}}, false},
+		{"mba mbps zero", Module{"mba": Param{"mbps": int64(0)}}, true},
+		{"mba without value", Module{"mba": Param{}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePolicy(arch, tt.module)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_changedPolicies(t *testing.T) {
+	gold := Module{"cache": Param{"max": 4, "min": 4}}
+	silver := Module{"cache": Param{"max": 2, "min": 1}}
+	old := CPUArchitecture{"skylake": Policy{"gold": gold, "silver": silver}}
+
+	tests := []struct {
+		name string
+		new  CPUArchitecture
+		want []string
+	}{
+		{"no change", CPUArchitecture{"skylake": Policy{"gold": gold, "silver": silver}}, []string{}},
+		{"policy changed", CPUArchitecture{"skylake": Policy{"gold": silver, "silver": silver}}, []string{"gold"}},
+		{"policy removed", CPUArchitecture{"skylake": Policy{"gold": gold}}, []string{"silver"}},
+		{"policy added", CPUArchitecture{"skylake": Policy{"gold": gold, "silver": silver, "bronze": silver}},
+			[]string{"bronze"}},
+		{"other arch changed", CPUArchitecture{"skylake": Policy{"gold": gold, "silver": silver},
+			"broadwell": Policy{"gold": silver}}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := changedPolicies(old, tt.new, "skylake")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changedPolicies() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rmd-policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "policy.yaml")
+	stubs := gostub.Stub(&policyPath, func() string { return path })
+	defer func() {
+		stubs.Reset()
+		filePolicies = nil
+	}()
+
+	valid := CPUArchitecture{"skylake": Policy{"gold": Module{"cache": Param{"max": 4, "min": 2}}}}
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"valid file", "skylake:\n  gold:\n    cache:\n      max: 4\n      min: 2\n", false},
+		{"parse error", "skylake:\n  gold: [\n", true},
+		{"invalid params", "skylake:\n  gold:\n    cache:\n      max: 2\n      min: 4\n", true},
+		{"empty file", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			err := Reload()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Reload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			// previous policies are kept on error
+			got, err := loadPolicy()
+			if err != nil {
+				t.Errorf("loadPolicy() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, valid) {
+				t.Errorf("loadPolicy() = %v, want %v", got, valid)
+			}
+		})
+	}
+}
+
+func Test_platformPolicies(t *testing.T) {
+	gold := Module{"cache": Param{"max": 4, "min": 4}}
+	portableGold := Module{"cache": Param{"max_percent": 40, "min_percent": 40}}
+	file := CPUArchitecture{"skylake": Policy{"gold": gold}, DefaultArch: Policy{"gold": portableGold}}
+
+	tests := []struct {
+		name   string
+		file   CPUArchitecture
+		arch   string
+		want   Policy
+		wantOk bool
+	}{
+		{"own section", file, "skylake", Policy{"gold": gold}, true},
+		{"default section", file, "icelake", Policy{"gold": portableGold}, true},
+		{"unknown platform", file, "", Policy{"gold": portableGold}, true},
+		{"no default section", CPUArchitecture{"skylake": Policy{"gold": gold}}, "icelake", Policy{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := platformPolicies(tt.file, nil, tt.arch)
+			if ok != tt.wantOk {
+				t.Errorf("platformPolicies() ok = %v, want %v", ok, tt.wantOk)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("platformPolicies() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package policy
+
+// Policy file is read once and kept in memory when it is watched. Changed
+// file is reloaded only if its whole content is valid, otherwise previous
+// policies are kept.
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+// DriftHook is called with names of host CPU architecture policies changed by
+// policy file reload to report workloads that differ from new policy
+// definition (set by workload module)
+var DriftHook func(names []string)
+
+// policies from last valid policy file, nil until first reload
+var filePolicies CPUArchitecture
+var fileLock sync.RWMutex
+
+// time to wait for further changes of policy file before reload
+var reloadDelay = 500 * time.Millisecond
+
+// loadPolicy returns pre-defined policies from policy file
+func loadPolicy() (CPUArchitecture, error) {
+	fileLock.RLock()
+	defer fileLock.RUnlock()
+
+	if filePolicies != nil {
+		return filePolicies, nil
+	}
+	return readPolicyFile()
+}
+
+// validateFilePolicies checks params of all policies from policy file
+func validateFilePolicies(c CPUArchitecture) error {
+	if len(c) == 0 {
+		return fmt.Errorf("No policies defined")
+	}
+	for arch, p := range c {
+		for name, m := range p {
+			if err := validateParams(m); err != nil {
+				return fmt.Errorf("Invalid policy %s for %s: %v", name, arch, err)
+			}
+		}
+	}
+	return nil
+}
+
+// changedPolicies returns sorted names of arch policies that differ in old
+// and new policy file content
+func changedPolicies(old, new CPUArchitecture, arch string) []string {
+	names := map[string]bool{}
+	for name, m := range old[arch] {
+		if !reflect.DeepEqual(m, new[arch][name]) {
+			names[name] = true
+		}
+	}
+	for name, m := range new[arch] {
+		if !reflect.DeepEqual(m, old[arch][name]) {
+			names[name] = true
+		}
+	}
+
+	result := []string{}
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Reload reads policy file again. New policies are used only if whole file
+// is valid, on error previous policies are kept.
+func Reload() error {
+	newPolicies, err := readPolicyFile()
+	if err == nil {
+		err = validateFilePolicies(newPolicies)
+	}
+	if err != nil {
+		log.Errorf("Failed to reload policy file, keeping previous policies. Reason: %v", err)
+		return err
+	}
+
+	fileLock.Lock()
+	oldPolicies := filePolicies
+	filePolicies = newPolicies
+	fileLock.Unlock()
+	log.Infof("Policy file reloaded")
+
+	if oldPolicies == nil {
+		return nil
+	}
+
+	// policies overridden at runtime do not change for workloads
+	runtime, _ := loadRuntimePolicies()
+	arch := effectiveArch(newPolicies, runtime, currentArch())
+	changed := []string{}
+	for _, name := range changedPolicies(oldPolicies, newPolicies, arch) {
+		if _, ok := runtime[arch][name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	if len(changed) > 0 {
+		log.Infof("Policies changed by reload: %v", changed)
+		if DriftHook != nil {
+			DriftHook(changed)
+		}
+	}
+	return nil
+}
+
+// Watch loads policy file and reloads it each time it is changed
+func Watch() error {
+	if err := Reload(); err != nil {
+		return err
+	}
+
+	path := filepath.Clean(policyPath())
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	// directory is watched as editors often replace file instead of writing it
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		var timer *time.Timer
+		for {
+			select {
+			case event := <-watcher.Events:
+				if filepath.Clean(event.Name) != path || event.Op&(fsnotify.Create|fsnotify.Write) == 0 {
+					continue
+				}
+				log.Debugf("Policy file changed. Event: %s", event)
+				// wait until file is completely written
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(reloadDelay, func() { Reload() })
+			case err := <-watcher.Errors:
+				log.Errorf("Error to watch policy file. Error: %s", err)
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,309 @@
+package policy
+
+// Policies defined at runtime (with REST API) are stored in database and
+// override policies with the same name defined in policy file.
+
+import (
+	"math"
+	"net/http"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	rmderror "github.com/intel/rmd/internal/error"
+	"github.com/intel/rmd/internal/plugins"
+	proxyclient "github.com/intel/rmd/internal/proxy/client"
+	util "github.com/intel/rmd/utils/bitmap"
+	"github.com/intel/rmd/utils/cpu"
+	"github.com/intel/rmd/utils/proc"
+)
+
+// ReapplyHook re-enforces workloads using given policy and returns ids of
+// re-enforced workloads and failure reasons per workload id.
+// It is set by workload module as it cannot be imported here.
+var ReapplyHook func(name string) ([]string, map[string]string)
+
+// UsersHook returns ids of workloads using given policy (set by workload module)
+var UsersHook func(name string) []string
+
+// Storage persists runtime policies (implemented by internal/db which cannot
+// be imported here due to import cycle)
+type Storage interface {
+	SavePolicy(arch, name string, m Module) error
+	DeletePolicy(arch, name string) error
+	GetAllPolicies() (CPUArchitecture, error)
+}
+
+// Database used to store runtime policies (set by workload module)
+var Database Storage
+
+// policy and architecture names are used as database keys
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// getLLCWays returns number of last level cache ways (0 if unknown)
+var getLLCWays = func() int {
+	rcinfo := proxyclient.GetRdtCosInfo()
+	for _, res := range []string{"l3", "l3code"} {
+		if info, ok := rcinfo[res]; ok {
+			mask, err := util.NewBitmap(info.CbmMask)
+			if err == nil {
+				return mask.Count()
+			}
+		}
+	}
+	return 0
+}
+
+func getDB() (Storage, error) {
+	if Database == nil {
+		return nil, rmderror.NewAppError(http.StatusInternalServerError, "Policy database not initialized")
+	}
+	return Database, nil
+}
+
+// currentArch returns micro architecture of host CPU as used in policy file
+func currentArch() string {
+	return strings.ToLower(cpu.GetMicroArch(cpu.GetSignature()))
+}
+
+// hostArch returns CPU architecture which policies are used on host
+// (DefaultArch if there are no policies for host CPU architecture)
+func hostArch() string {
+	file, _ := loadPolicy()
+	runtime, _ := loadRuntimePolicies()
+	return effectiveArch(file, runtime, currentArch())
+}
+
+// normalizeModule lower cases module and param names (as viper does for
+// policy file) and converts integer numbers decoded from JSON to int64
+func normalizeModule(m Module) Module {
+	result := Module{}
+	for mod, params := range m {
+		p := Param{}
+		for k, v := range params {
+			if f, ok := v.(float64); ok && f == math.Trunc(f) {
+				v = int64(f)
+			}
+			p[strings.ToLower(k)] = v
+		}
+		result[strings.ToLower(mod)] = p
+	}
+	return result
+}
+
+// loadRuntimePolicies returns policies stored in database
+func loadRuntimePolicies() (CPUArchitecture, error) {
+	d, err := getDB()
+	if err != nil {
+		return CPUArchitecture{}, err
+	}
+	result, err := d.GetAllPolicies()
+	if err != nil {
+		return CPUArchitecture{}, err
+	}
+	for _, p := range result {
+		for name, m := range p {
+			p[name] = normalizeModule(m)
+		}
+	}
+	return result, nil
+}
+
+// mergePolicies returns policies of arch from policy file overridden by
+// runtime ones, ok is false if none of them defines arch
+func mergePolicies(file, runtime CPUArchitecture, arch string) (Policy, bool) {
+	filePolicy, inFile := file[arch]
+	runtimePolicy, inRuntime := runtime[arch]
+	merged := Policy{}
+	for name, m := range filePolicy {
+		merged[name] = m
+	}
+	for name, m := range runtimePolicy {
+		merged[name] = m
+	}
+	return merged, inFile || inRuntime
+}
+
+// effectiveArch returns arch if policy file or runtime policies define it,
+// DefaultArch otherwise
+func effectiveArch(file, runtime CPUArchitecture, arch string) string {
+	if _, ok := file[arch]; ok {
+		return arch
+	}
+	if _, ok := runtime[arch]; ok {
+		return arch
+	}
+	return DefaultArch
+}
+
+// platformPolicies returns policies of arch or DefaultArch policies if arch
+// is not defined
+func platformPolicies(file, runtime CPUArchitecture, arch string) (Policy, bool) {
+	return mergePolicies(file, runtime, effectiveArch(file, runtime, arch))
+}
+
+// loadArchPolicies returns policies of given architecture
+func loadArchPolicies(arch string) (Policy, error) {
+	file, err := loadPolicy()
+	if err != nil {
+		return Policy{}, err
+	}
+	runtime, err := loadRuntimePolicies()
+	if err != nil {
+		// runtime policies are optional
+		log.Debugf("Runtime policies not available: %v", err)
+	}
+	platform, ok := platformPolicies(file, runtime, arch)
+	if !ok {
+		return Policy{}, rmderror.AppErrorf(http.StatusNotFound, "No policies for platform: %s", arch)
+	}
+	return platform, nil
+}
+
+// ValidatePolicy checks policy params, cache and MBA params are checked
+// against host platform only for policies of host CPU architecture
+func ValidatePolicy(arch string, m Module) error {
+	if len(m) == 0 {
+		return rmderror.NewAppError(http.StatusBadRequest, "Policy does not define any params")
+	}
+	if err := validateParams(m); err != nil {
+		return err
+	}
+	host := arch == hostArch()
+
+	for mod, params := range m {
+		switch mod {
+		case "cache":
+			max, _ := ParamToUint32(params["max"])
+			if !host || max == 0 {
+				continue
+			}
+			if ok, _ := proc.IsL3CatAvailable(); !ok {
+				return rmderror.NewAppError(http.StatusBadRequest, "Platform does not support cache allocation")
+			}
+			if ways := getLLCWays(); ways > 0 && max > uint32(ways) {
+				return rmderror.AppErrorf(http.StatusBadRequest,
+					"Policy cache max %d exceeds number of platform cache ways (%d)", max, ways)
+			}
+		case "mba":
+			if !host {
+				continue
+			}
+			if ok, _ := proc.IsMbaAvailable(); !ok {
+				return rmderror.NewAppError(http.StatusBadRequest, "Platform does not support MBA")
+			}
+		default:
+			if iface, ok := plugins.Interfaces[mod]; !ok || iface == nil {
+				return rmderror.AppErrorf(http.StatusBadRequest, "Plugin %s used in policy is not loaded", mod)
+			}
+		}
+	}
+	return nil
+}
+
+func validateNames(arch, name string) error {
+	if !namePattern.MatchString(arch) {
+		return rmderror.AppErrorf(http.StatusBadRequest, "Invalid CPU architecture name: %s", arch)
+	}
+	if !namePattern.MatchString(name) {
+		return rmderror.AppErrorf(http.StatusBadRequest, "Invalid policy name: %s", name)
+	}
+	return nil
+}
+
+// GetPolicies returns policies of given CPU architecture
+func GetPolicies(arch string) (Policy, error) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	return loadArchPolicies(arch)
+}
+
+// CreatePolicy creates new runtime policy for given CPU architecture
+func CreatePolicy(arch, name string, m Module) error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := validateNames(arch, name); err != nil {
+		return err
+	}
+	if platform, err := loadArchPolicies(arch); err == nil {
+		if _, ok := platform[name]; ok {
+			return rmderror.AppErrorf(http.StatusConflict, "Policy %s already exists", name)
+		}
+	}
+	return savePolicy(arch, name, m)
+}
+
+// SetPolicy creates or replaces runtime policy for given CPU architecture
+func SetPolicy(arch, name string, m Module) error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := validateNames(arch, name); err != nil {
+		return err
+	}
+	return savePolicy(arch, name, m)
+}
+
+func savePolicy(arch, name string, m Module) error {
+	if err := ValidatePolicy(arch, m); err != nil {
+		return err
+	}
+	d, err := getDB()
+	if err != nil {
+		return err
+	}
+	if err := d.SavePolicy(arch, name, m); err != nil {
+		return rmderror.NewAppError(http.StatusInternalServerError, "Failed to save policy in database", err)
+	}
+	log.Infof("Policy %s for %s saved: %v", name, arch, m)
+	return nil
+}
+
+// DeletePolicy removes runtime policy of given CPU architecture.
+// Policies defined in policy file cannot be removed, after removal of
+// runtime policy that overrides file policy the file one is used again.
+func DeletePolicy(arch, name string) error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	runtime, err := loadRuntimePolicies()
+	if err != nil {
+		return err
+	}
+	if _, ok := runtime[arch][name]; !ok {
+		return rmderror.AppErrorf(http.StatusNotFound, "Runtime policy %s not found", name)
+	}
+
+	// policy cannot disappear while workloads are using it
+	file, err := loadPolicy()
+	if err != nil {
+		return err
+	}
+	filePlatform, _ := platformPolicies(file, nil, arch)
+	if _, inFile := filePlatform[name]; !inFile && arch == hostArch() && UsersHook != nil {
+		if users := UsersHook(name); len(users) > 0 {
+			return rmderror.AppErrorf(http.StatusConflict, "Policy %s is used by workloads %v", name, users)
+		}
+	}
+
+	d, err := getDB()
+	if err != nil {
+		return err
+	}
+	if err := d.DeletePolicy(arch, name); err != nil {
+		return rmderror.NewAppError(http.StatusInternalServerError, "Failed to remove policy from database", err)
+	}
+	log.Infof("Policy %s for %s removed", name, arch)
+	return nil
+}
+
+// Reapply re-enforces workloads using given policy if policy is used on host
+func Reapply(arch, name string) ([]string, map[string]string) {
+	if arch != hostArch() || ReapplyHook == nil {
+		return []string{}, map[string]string{}
+	}
+	return ReapplyHook(name)
+}
@@ -0,0 +1,37 @@
+package types
+
+// Param represents single policy attribute
+// example: "MaxCache: 4"
+//
+type Param map[string]interface{}
+
+// Module represents attributes for single module
+// example:
+//     Cache:
+//          Min: 1
+//          Max: 1
+//
+type Module map[string]Param
+
+// Policy represents policy type
+// example:
+//       gold:
+//           Cache:
+//                Min: 1
+//                Max: 1
+//           Pstate:
+//                Ratio: 0.1
+//
+type Policy map[string]Module
+
+// CPUArchitecture represents cpu model
+// example:
+//    broadwell:
+//       gold:
+//           Cache:
+//                Min: 1
+//                Max: 1
+//           Pstate:
+//                Ratio: 0.1
+//
+type CPUArchitecture map[string]Policy
@@ -0,0 +1,246 @@
+package workload
+
+// Batch of workloads.
+// Workloads of batch are created and deleted as single unit. Deleted workloads
+// are released and new ones enforced one by one, then all changes are committed
+// to database in single transaction. On first failure all resctrl changes done
+// by the batch are rolled back.
+
+import (
+	"fmt"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intel/rmd/internal/db"
+	rmderror "github.com/intel/rmd/internal/error"
+	"github.com/intel/rmd/modules/events"
+	evtypes "github.com/intel/rmd/modules/events/types"
+	wltypes "github.com/intel/rmd/modules/workload/types"
+	"github.com/intel/rmd/utils/task"
+)
+
+// enforce and release done by batch tasks (caller holds the workload lock)
+var batchEnforce, batchRelease = enforce, release
+
+// releaseTask releases workload deleted by batch
+type releaseTask struct {
+	w    *wltypes.RDTWorkLoad
+	done bool
+}
+
+// Name of the task
+func (t *releaseTask) Name() string {
+	return "release workload " + t.w.ID
+}
+
+// Run releases workload
+func (t *releaseTask) Run() error {
+	if err := batchRelease(t.w); err != nil {
+		return rmderror.AppErrorf(http.StatusInternalServerError, "Failed to release workload %s: %v", t.w.ID, err)
+	}
+	t.done = true
+	return nil
+}
+
+// Rollback enforces released workload again
+// Workload can get different COS than before so it is updated in database too
+func (t *releaseTask) Rollback() error {
+	if !t.done {
+		return nil
+	}
+	t.done = false
+	err := batchEnforce(t.w)
+	if err != nil {
+		log.Errorf("Failed to enforce again workload %s on batch rollback: %v", t.w.ID, err)
+		events.Publish(evtypes.WorkloadFailed, t.w, err.Error())
+	}
+	if dbErr := updateInDB(t.w); dbErr != nil {
+		log.Errorf("Failed to update workload %s on batch rollback: %v", t.w.ID, dbErr)
+		if err == nil {
+			err = dbErr
+		}
+	}
+	return err
+}
+
+// enforceTask enforces workload created by batch
+type enforceTask struct {
+	index int
+	w     *wltypes.RDTWorkLoad
+	done  bool
+}
+
+// Name of the task
+func (t *enforceTask) Name() string {
+	return fmt.Sprintf("enforce workload %d", t.index)
+}
+
+// Run enforces workload
+func (t *enforceTask) Run() error {
+	if err := batchEnforce(t.w); err != nil {
+		return batchError(t.index, err)
+	}
+	t.done = true
+	return nil
+}
+
+// Rollback releases enforced workload
+func (t *enforceTask) Rollback() error {
+	if !t.done {
+		return nil
+	}
+	t.done = false
+	if err := batchRelease(t.w); err != nil {
+		log.Errorf("Failed to release workload %d on batch rollback: %v", t.index, err)
+		return err
+	}
+	return nil
+}
+
+// commitTask stores all changes of batch in database
+type commitTask struct {
+	created, deleted []*wltypes.RDTWorkLoad
+}
+
+// Name of the task
+func (t *commitTask) Name() string {
+	return "commit workloads"
+}
+
+// Run commits batch in database
+func (t *commitTask) Run() error {
+	if err := workloadDatabase.CommitWorkloads(t.created, t.deleted); err != nil {
+		return rmderror.NewAppError(rmderror.InternalServer, "Failed to commit workloads in database", err)
+	}
+	return nil
+}
+
+// Rollback does nothing, failed transaction is not committed
+func (t *commitTask) Rollback() error {
+	return nil
+}
+
+// batchError returns error of batch workload with given index (keeping error code)
+func batchError(index int, err error) error {
+	code := http.StatusInternalServerError
+	if appErr, ok := err.(*rmderror.AppError); ok {
+		code = appErr.Code
+	}
+	return rmderror.AppErrorf(code, "Workload %d: %v", index, err)
+}
+
+// containsWorkload checks if workload with given id is in list
+func containsWorkload(ws []*wltypes.RDTWorkLoad, id string) bool {
+	for _, w := range ws {
+		if w.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Batch creates and deletes workloads (given by ids) as single unit.
+// Either all changes are done or none of them.
+func Batch(created []*wltypes.RDTWorkLoad, deleted []string) error {
+	if workloadDatabase == nil {
+		return rmderror.NewAppError(http.StatusInternalServerError, "Service database not initialized")
+	}
+	if len(created) == 0 && len(deleted) == 0 {
+		return rmderror.AppErrorf(http.StatusBadRequest, "Empty batch")
+	}
+
+	removed, rolledBack, err := runBatch(created, deleted)
+	if rolledBack {
+		log.Errorf("Workload batch rolled back: %v", err)
+		// preempted workloads can get their cache back
+		restoreDemoted()
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, w := range removed {
+		events.Publish(evtypes.WorkloadDeleted, w, "")
+	}
+	for _, w := range created {
+		events.Publish(evtypes.WorkloadCreated, w, "")
+	}
+	if len(removed) > 0 {
+		// released resources may be enough for demoted workloads
+		restoreDemoted()
+	}
+	return nil
+}
+
+// runBatch validates batch and runs its tasks under the workload lock, so
+// quotas are checked against workloads that cannot change in the meantime.
+// Returns deleted workloads and whether changes done by batch were rolled back.
+func runBatch(created []*wltypes.RDTWorkLoad, deleted []string) ([]*wltypes.RDTWorkLoad, bool, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	all, err := GetAll()
+	if err != nil {
+		return nil, false, err
+	}
+	byID := make(map[string]*wltypes.RDTWorkLoad, len(all))
+	for i := range all {
+		byID[all[i].ID] = &all[i]
+	}
+
+	removed := []*wltypes.RDTWorkLoad{}
+	for _, id := range deleted {
+		w, ok := byID[id]
+		if !ok && containsWorkload(removed, id) {
+			return nil, false, rmderror.AppErrorf(http.StatusBadRequest, "Workload %s deleted more than once", id)
+		}
+		if !ok {
+			return nil, false, rmderror.AppErrorf(http.StatusNotFound, "Workload %s not found", id)
+		}
+		// workloads created by REST should be handled only by REST
+		if w.Origin != "REST" {
+			return nil, false, rmderror.AppErrorf(http.StatusForbidden,
+				"Workload %s can be deleted only by its origin (%s)", id, w.Origin)
+		}
+		removed = append(removed, w)
+		delete(byID, id)
+	}
+
+	// new workloads are validated against each other and workloads left in database
+	existing := make([]wltypes.RDTWorkLoad, 0, len(byID))
+	for i := range all {
+		if _, ok := byID[all[i].ID]; ok {
+			existing = append(existing, all[i])
+		}
+	}
+	ws := make([]wltypes.RDTWorkLoad, len(created))
+	for i, w := range created {
+		if err := validate(w); err != nil {
+			return nil, false, batchError(i, rmderror.NewAppError(http.StatusBadRequest, err.Error()))
+		}
+		ws[i] = *w
+	}
+	if err := db.ValidateWorkloads(ws, existing); err != nil {
+		return nil, false, rmderror.NewAppError(http.StatusBadRequest, err.Error())
+	}
+	for i, w := range created {
+		if err := validateQuota(w, existing, created[:i]); err != nil {
+			return nil, false, batchError(i, rmderror.NewAppError(http.StatusBadRequest, err.Error()))
+		}
+	}
+
+	tasks := []task.Task{}
+	for _, w := range removed {
+		tasks = append(tasks, &releaseTask{w: w})
+	}
+	for i, w := range created {
+		tasks = append(tasks, &enforceTask{index: i, w: w})
+	}
+	tasks = append(tasks, &commitTask{created: created, deleted: removed})
+
+	if err := task.NewTaskList(tasks).Start(); err != nil {
+		return nil, true, err
+	}
+	return removed, false, nil
+}
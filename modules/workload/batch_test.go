@@ -0,0 +1,114 @@
+package workload
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/intel/rmd/internal/db"
+	rmderror "github.com/intel/rmd/internal/error"
+	tw "github.com/intel/rmd/modules/workload/types"
+	. "github.com/prashantv/gostub"
+)
+
+func Test_batchError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{"Bad request", rmderror.AppErrorf(http.StatusBadRequest, "Not enough cache left"), http.StatusBadRequest},
+		{"Not application error", errors.New("failure"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := batchError(2, tt.err)
+			appErr, ok := err.(*rmderror.AppError)
+			if !ok || appErr.Code != tt.wantCode || appErr.Message != "Workload 2: "+tt.err.Error() {
+				t.Errorf("batchError() = %#v, want code %d", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func Test_batchTaskRollback(t *testing.T) {
+	// tasks which did not run successfully have nothing to roll back
+	w := &tw.RDTWorkLoad{ID: "1"}
+	if err := (&enforceTask{w: w}).Rollback(); err != nil {
+		t.Errorf("enforceTask.Rollback() error = %v", err)
+	}
+	if err := (&releaseTask{w: w}).Rollback(); err != nil {
+		t.Errorf("releaseTask.Rollback() error = %v", err)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	workloadDatabase = nil
+	err := Batch([]*tw.RDTWorkLoad{{CoreIDs: []string{"1"}}}, nil)
+	if appErr, ok := err.(*rmderror.AppError); !ok || appErr.Code != http.StatusInternalServerError {
+		t.Errorf("Batch() without database error = %v", err)
+	}
+}
+
+func TestBatch_rollback(t *testing.T) {
+	var err error
+	if workloadDatabase, err = db.NewDB(); err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	ws, _ := GetAll()
+	for i := range ws {
+		workloadDatabase.DeleteWorkload(&ws[i])
+	}
+
+	// resource groups (COS name -> workload id), every enforcement takes new COS
+	groups := map[string]string{}
+	cos := 0
+	enforce := func(w *tw.RDTWorkLoad) error {
+		if w.CoreIDs[0] == "3" {
+			return rmderror.NewAppError(http.StatusBadRequest, "Not enough cache left")
+		}
+		cos++
+		w.CosName = fmt.Sprintf("COS%d", cos)
+		w.Status = tw.Successful
+		groups[w.CosName] = w.ID
+		return nil
+	}
+	release := func(w *tw.RDTWorkLoad) error {
+		delete(groups, w.CosName)
+		return nil
+	}
+	stubs := Stub(&batchEnforce, enforce).Stub(&batchRelease, release)
+	stubs.Stub(&isL3CATSupported, true).Stub(&isMbaSupported, false)
+	defer stubs.Reset()
+
+	newWorkload := func(core string) *tw.RDTWorkLoad {
+		ways := uint32(2)
+		w := &tw.RDTWorkLoad{CoreIDs: []string{core}, Origin: "REST"}
+		w.Rdt.Cache.Max = &ways
+		w.Rdt.Cache.Min = &ways
+		return w
+	}
+	deleted := newWorkload("1")
+	if err := workloadDatabase.CreateWorkload(deleted); err != nil {
+		t.Fatalf("Failed to create workload: %v", err)
+	}
+	enforce(deleted)
+	if err := workloadDatabase.UpdateWorkload(deleted); err != nil {
+		t.Fatalf("Failed to update workload: %v", err)
+	}
+
+	// second created workload fails, first one is released and deleted one enforced again
+	err = Batch([]*tw.RDTWorkLoad{newWorkload("2"), newWorkload("3")}, []string{deleted.ID})
+	if appErr, ok := err.(*rmderror.AppError); !ok || appErr.Code != http.StatusBadRequest {
+		t.Fatalf("Batch() error = %v, want bad request", err)
+	}
+	if want := map[string]string{"COS3": deleted.ID}; !reflect.DeepEqual(groups, want) {
+		t.Errorf("resource groups after rollback = %v, want %v", groups, want)
+	}
+	ws, _ = GetAll()
+	if len(ws) != 1 || ws[0].ID != deleted.ID || ws[0].CosName != "COS3" {
+		t.Errorf("workloads in database after rollback = %+v, want only %s in COS3", ws, deleted.ID)
+	}
+}
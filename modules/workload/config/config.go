@@ -0,0 +1,46 @@
+package config
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// Quota limits resources used by all workloads of tenant (0 means no limit)
+type Quota struct {
+	// GuaranteedWays is total number of last level cache ways of guarantee pool workloads
+	GuaranteedWays uint32 `toml:"guaranteedWays"`
+	// Mba is total MBA (in units of MBA mode) of workloads
+	Mba uint32 `toml:"mba"`
+	// Cos is number of classes of service used by workloads (shared pool is not counted)
+	Cos uint32 `toml:"cos"`
+}
+
+// Tenants contains quotas of tenants from rmd.toml
+type Tenants struct {
+	// Default quota of tenants not listed in Quota
+	Default Quota `toml:"default"`
+	// Quota of tenants by name (names are lower case)
+	Quota map[string]Quota `toml:"quota"`
+}
+
+var tenantsConfigOnce sync.Once
+var tenants = &Tenants{}
+
+// NewTenantsConfig reads tenants configuration
+func NewTenantsConfig() *Tenants {
+	tenantsConfigOnce.Do(func() {
+		viper.UnmarshalKey("tenants", tenants)
+	})
+	return tenants
+}
+
+// GetQuota returns quota of given tenant
+func (t *Tenants) GetQuota(tenant string) Quota {
+	// configuration keys are case insensitive
+	if q, ok := t.Quota[strings.ToLower(tenant)]; ok {
+		return q
+	}
+	return t.Default
+}
@@ -0,0 +1,107 @@
+package workload
+
+// Workload expiry.
+// Workload can have a lease (ttl in seconds or absolute expires_at time).
+// Database validator releases and removes workloads which lease expired.
+// Lease of workload with ttl is extended by every PATCH (heartbeat).
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	evtypes "github.com/intel/rmd/modules/events/types"
+	wltypes "github.com/intel/rmd/modules/workload/types"
+)
+
+// startLease validates lease of new workload and sets its expiry time from ttl
+func startLease(w *wltypes.RDTWorkLoad, now time.Time) error {
+	if w.ExpiresAt != nil {
+		if !w.ExpiresAt.After(now) {
+			return fmt.Errorf("Expiry time %s is not in the future", w.ExpiresAt.Format(time.RFC3339))
+		}
+		return nil
+	}
+	if w.TTL > 0 {
+		expiresAt := now.Add(time.Duration(w.TTL) * time.Second)
+		w.ExpiresAt = &expiresAt
+	}
+	return nil
+}
+
+// renewLease extends lease of workload on patch. New ttl or expiry time is taken
+// from patch, otherwise lease is extended by ttl of workload.
+func renewLease(w, patched *wltypes.RDTWorkLoad, now time.Time) error {
+	if patched.TTL > 0 {
+		w.TTL = patched.TTL
+	}
+	if patched.ExpiresAt != nil {
+		if !patched.ExpiresAt.After(now) {
+			return fmt.Errorf("Expiry time %s is not in the future", patched.ExpiresAt.Format(time.RFC3339))
+		}
+		w.ExpiresAt = patched.ExpiresAt
+		return nil
+	}
+	if w.TTL > 0 {
+		expiresAt := now.Add(time.Duration(w.TTL) * time.Second)
+		w.ExpiresAt = &expiresAt
+	}
+	return nil
+}
+
+// isHeartbeat checks if patch only extends lease of workload (no other param is set)
+func isHeartbeat(w, patched *wltypes.RDTWorkLoad) bool {
+	if patched.TTL == 0 && patched.ExpiresAt == nil && w.ExpiresAt == nil {
+		return false
+	}
+	return !hasWorkloadParams(patched)
+}
+
+// hasWorkloadParams checks if patch changes any param of workload (other than lease and metadata)
+func hasWorkloadParams(patched *wltypes.RDTWorkLoad) bool {
+	return len(patched.CoreIDs) > 0 || len(patched.TaskIDs) > 0 || len(patched.CgroupPath) > 0 ||
+		patched.TaskSelector != nil || len(patched.Policy) > 0 || hasRdtParams(&patched.Rdt) ||
+		len(patched.Plugins) > 0 || patched.Schedule != nil
+}
+
+// isExpired checks if lease of workload expired
+func isExpired(w *wltypes.RDTWorkLoad, now time.Time) bool {
+	return w.ExpiresAt != nil && !now.Before(*w.ExpiresAt)
+}
+
+// expireWorkloads releases and removes workloads which lease expired
+func expireWorkloads(now time.Time) {
+	removed := 0
+	func() {
+		l.Lock()
+		defer l.Unlock()
+
+		ws, err := GetAll()
+		if err != nil {
+			log.Errorf("Failed to get workloads to check expiry: %v", err)
+			return
+		}
+		for i := range ws {
+			w := &ws[i]
+			if !isExpired(w, now) {
+				continue
+			}
+			if err := release(w); err != nil {
+				// expired workload is removed anyway, left resource group is reported by reconcile
+				log.Errorf("Failed to release expired workload %s: %v", w.ID, err)
+			}
+			message := fmt.Sprintf("Workload lease expired at %s", w.ExpiresAt.Format(time.RFC3339))
+			if err := deleteWorkload(w, evtypes.WorkloadExpired, message); err != nil {
+				log.Errorf("Failed to delete expired workload %s from db: %s", w.ID, err)
+				continue
+			}
+			log.Infof("Workload %s deleted by DBValidator (lease expired)", w.ID)
+			removed++
+		}
+	}()
+	// released resources may be enough for demoted workloads
+	if removed > 0 {
+		restoreDemoted()
+	}
+}
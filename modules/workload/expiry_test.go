@@ -0,0 +1,98 @@
+package workload
+
+import (
+	"testing"
+	"time"
+
+	tw "github.com/intel/rmd/modules/workload/types"
+)
+
+func Test_startLease(t *testing.T) {
+	now := time.Date(2020, 5, 11, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Hour)
+	tests := []struct {
+		name    string
+		w       tw.RDTWorkLoad
+		want    *time.Time
+		wantErr bool
+	}{
+		{"No lease", tw.RDTWorkLoad{}, nil, false},
+		{"TTL", tw.RDTWorkLoad{TTL: 60}, &[]time.Time{now.Add(time.Minute)}[0], false},
+		{"Expiry time", tw.RDTWorkLoad{TTL: 60, ExpiresAt: &future}, &future, false},
+		{"Expiry time in the past", tw.RDTWorkLoad{ExpiresAt: &past}, &past, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := startLease(&tt.w, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("startLease() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (tt.w.ExpiresAt == nil) != (tt.want == nil) ||
+				(tt.want != nil && !tt.w.ExpiresAt.Equal(*tt.want)) {
+				t.Errorf("startLease() expires at %v, want %v", tt.w.ExpiresAt, tt.want)
+			}
+		})
+	}
+}
+
+func Test_renewLease(t *testing.T) {
+	now := time.Date(2020, 5, 11, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Hour)
+	old := now.Add(10 * time.Second)
+	tests := []struct {
+		name    string
+		ttl     uint32
+		patched tw.RDTWorkLoad
+		wantTTL uint32
+		want    *time.Time
+		wantErr bool
+	}{
+		{"No lease", 0, tw.RDTWorkLoad{}, 0, &old, false},
+		{"Heartbeat", 30, tw.RDTWorkLoad{}, 30, &[]time.Time{now.Add(30 * time.Second)}[0], false},
+		{"New TTL", 30, tw.RDTWorkLoad{TTL: 120}, 120, &[]time.Time{now.Add(2 * time.Minute)}[0], false},
+		{"New expiry time", 30, tw.RDTWorkLoad{ExpiresAt: &future}, 30, &future, false},
+		{"Expiry time in the past", 30, tw.RDTWorkLoad{ExpiresAt: &past}, 30, &old, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expiresAt := old
+			w := tw.RDTWorkLoad{TTL: tt.ttl, ExpiresAt: &expiresAt}
+			err := renewLease(&w, &tt.patched, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renewLease() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if w.TTL != tt.wantTTL || !w.ExpiresAt.Equal(*tt.want) {
+				t.Errorf("renewLease() = %v, %v, want %v, %v", w.TTL, w.ExpiresAt, tt.wantTTL, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isHeartbeat(t *testing.T) {
+	expiresAt := time.Date(2020, 5, 11, 12, 0, 0, 0, time.UTC)
+	max := uint32(2)
+	withCache := tw.RDTWorkLoad{}
+	withCache.Rdt.Cache.Max = &max
+	tests := []struct {
+		name    string
+		w       tw.RDTWorkLoad
+		patched tw.RDTWorkLoad
+		want    bool
+	}{
+		{"Empty patch of workload with lease", tw.RDTWorkLoad{TTL: 30, ExpiresAt: &expiresAt}, tw.RDTWorkLoad{}, true},
+		{"Empty patch of workload without lease", tw.RDTWorkLoad{}, tw.RDTWorkLoad{}, false},
+		{"New TTL", tw.RDTWorkLoad{}, tw.RDTWorkLoad{TTL: 30}, true},
+		{"TTL and cores", tw.RDTWorkLoad{}, tw.RDTWorkLoad{TTL: 30, CoreIDs: []string{"1"}}, false},
+		{"Cache params", tw.RDTWorkLoad{ExpiresAt: &expiresAt}, withCache, false},
+		{"Policy", tw.RDTWorkLoad{ExpiresAt: &expiresAt}, tw.RDTWorkLoad{Policy: "gold"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isHeartbeat(&tt.w, &tt.patched); got != tt.want {
+				t.Errorf("isHeartbeat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package workload
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	wltypes "github.com/intel/rmd/modules/workload/types"
+)
+
+var (
+	// label key may contain a prefix separated by slash (ex. example.com/app)
+	labelKeyRegex   = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_./]*[A-Za-z0-9])?$`)
+	labelValueRegex = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?)?$`)
+)
+
+// maxLabelLength is the max length of label key and value
+const maxLabelLength = 63
+
+// requirement is a single condition of label selector
+type requirement struct {
+	key   string
+	value string
+	// operator is one of "=", "!=", "exists" and "!exists"
+	operator string
+}
+
+// selector matches workloads with labels fulfilling all requirements
+type selector []requirement
+
+// validateLabels checks if label keys and values can be used in selector
+func validateLabels(labels map[string]string) error {
+	for k, v := range labels {
+		if len(k) > maxLabelLength || !labelKeyRegex.MatchString(k) {
+			return fmt.Errorf("Invalid label key %q", k)
+		}
+		if len(v) > maxLabelLength || !labelValueRegex.MatchString(v) {
+			return fmt.Errorf("Invalid value %q of label %s", v, k)
+		}
+	}
+	return nil
+}
+
+// validateAnnotations checks annotation keys (values are not restricted)
+func validateAnnotations(annotations map[string]string) error {
+	for k := range annotations {
+		if !labelKeyRegex.MatchString(k) {
+			return fmt.Errorf("Invalid annotation key %q", k)
+		}
+	}
+	return nil
+}
+
+// parseSelector parses comma separated requirements:
+// key=value (or key==value), key!=value, key (label exists) and !key (label does not exist)
+func parseSelector(s string) (selector, error) {
+	sel := selector{}
+	if len(strings.TrimSpace(s)) == 0 {
+		return sel, nil
+	}
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		r := requirement{}
+		switch {
+		case strings.Contains(term, "!="):
+			kv := strings.SplitN(term, "!=", 2)
+			r = requirement{key: kv[0], value: kv[1], operator: "!="}
+		case strings.Contains(term, "="):
+			kv := strings.SplitN(strings.Replace(term, "==", "=", 1), "=", 2)
+			r = requirement{key: kv[0], value: kv[1], operator: "="}
+		case strings.HasPrefix(term, "!"):
+			r = requirement{key: term[1:], operator: "!exists"}
+		default:
+			r = requirement{key: term, operator: "exists"}
+		}
+		r.key = strings.TrimSpace(r.key)
+		r.value = strings.TrimSpace(r.value)
+		if !labelKeyRegex.MatchString(r.key) {
+			return nil, fmt.Errorf("Invalid selector requirement %q", term)
+		}
+		if !labelValueRegex.MatchString(r.value) {
+			return nil, fmt.Errorf("Invalid value in selector requirement %q", term)
+		}
+		sel = append(sel, r)
+	}
+	return sel, nil
+}
+
+// matches checks if labels fulfill all requirements of selector
+func (sel selector) matches(labels map[string]string) bool {
+	for _, r := range sel {
+		v, ok := labels[r.key]
+		switch r.operator {
+		case "=":
+			if !ok || v != r.value {
+				return false
+			}
+		case "!=":
+			// workloads without the label match too
+			if ok && v == r.value {
+				return false
+			}
+		case "exists":
+			if !ok {
+				return false
+			}
+		case "!exists":
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// patchMetadata merges patched labels and annotations into workload,
+// empty value removes the label (annotation)
+func patchMetadata(w, patched *wltypes.RDTWorkLoad) error {
+	if err := validateAnnotations(patched.Annotations); err != nil {
+		return err
+	}
+	if err := validateLabels(patched.Labels); err != nil {
+		return err
+	}
+	w.Labels = mergeMetadata(w.Labels, patched.Labels)
+	w.Annotations = mergeMetadata(w.Annotations, patched.Annotations)
+	return nil
+}
+
+// mergeMetadata returns copy of dst with patched entries set (or removed if empty)
+func mergeMetadata(dst, patched map[string]string) map[string]string {
+	if len(patched) == 0 {
+		return dst
+	}
+	merged := map[string]string{}
+	for k, v := range dst {
+		merged[k] = v
+	}
+	for k, v := range patched {
+		if len(v) == 0 {
+			delete(merged, k)
+			continue
+		}
+		merged[k] = v
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
+
+// isMetadataPatch checks if patch only changes labels or annotations
+func isMetadataPatch(patched *wltypes.RDTWorkLoad) bool {
+	if len(patched.Labels) == 0 && len(patched.Annotations) == 0 {
+		return false
+	}
+	return !hasWorkloadParams(patched)
+}
@@ -0,0 +1,108 @@
+package workload
+
+import (
+	"reflect"
+	"testing"
+
+	tw "github.com/intel/rmd/modules/workload/types"
+)
+
+func Test_parseSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     selector
+		wantErr  bool
+	}{
+		{"", selector{}, false},
+		{"app=db", selector{{"app", "db", "="}}, false},
+		{"app==db, tier!=batch", selector{{"app", "db", "="}, {"tier", "batch", "!="}}, false},
+		{"example.com/app,!canary", selector{{"example.com/app", "", "exists"}, {"canary", "", "!exists"}}, false},
+		{"app=", selector{{"app", "", "="}}, false},
+		{"=db", nil, true},
+		{"app=db,", nil, true},
+		{"app=d b", nil, true},
+		{"app=db=1", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			got, err := parseSelector(tt.selector)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSelector() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSelector() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_selector_matches(t *testing.T) {
+	labels := map[string]string{"app": "db", "tier": "web"}
+	tests := []struct {
+		selector string
+		labels   map[string]string
+		want     bool
+	}{
+		{"", nil, true},
+		{"app=db", labels, true},
+		{"app=db,tier!=batch", labels, true},
+		{"app=db,tier!=web", labels, false},
+		{"app!=db", nil, true},
+		{"app=db", nil, false},
+		{"tier", labels, true},
+		{"!tier", labels, false},
+		{"!canary", labels, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			sel, err := parseSelector(tt.selector)
+			if err != nil {
+				t.Fatalf("parseSelector() error = %v", err)
+			}
+			if got := sel.matches(tt.labels); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_patchMetadata(t *testing.T) {
+	tests := []struct {
+		name            string
+		labels          map[string]string
+		patched         *tw.RDTWorkLoad
+		wantLabels      map[string]string
+		wantAnnotations map[string]string
+		wantErr         bool
+	}{
+		{"add label", map[string]string{"app": "db"},
+			&tw.RDTWorkLoad{Labels: map[string]string{"tier": "web"}},
+			map[string]string{"app": "db", "tier": "web"}, nil, false},
+		{"remove label", map[string]string{"app": "db", "tier": "web"},
+			&tw.RDTWorkLoad{Labels: map[string]string{"tier": ""}, Annotations: map[string]string{"owner": "a b"}},
+			map[string]string{"app": "db"}, map[string]string{"owner": "a b"}, false},
+		{"remove last label", map[string]string{"app": "db"},
+			&tw.RDTWorkLoad{Labels: map[string]string{"app": ""}},
+			nil, nil, false},
+		{"invalid value", map[string]string{"app": "db"},
+			&tw.RDTWorkLoad{Labels: map[string]string{"tier": "a,b"}},
+			map[string]string{"app": "db"}, nil, true},
+		{"invalid annotation", nil,
+			&tw.RDTWorkLoad{Annotations: map[string]string{"-x": "1"}},
+			nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &tw.RDTWorkLoad{Labels: tt.labels}
+			if err := patchMetadata(w, tt.patched); (err != nil) != tt.wantErr {
+				t.Fatalf("patchMetadata() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(w.Labels, tt.wantLabels) {
+				t.Errorf("patchMetadata() labels = %v, want %v", w.Labels, tt.wantLabels)
+			}
+			if !reflect.DeepEqual(w.Annotations, tt.wantAnnotations) {
+				t.Errorf("patchMetadata() annotations = %v, want %v", w.Annotations, tt.wantAnnotations)
+			}
+		})
+	}
+}
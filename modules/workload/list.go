@@ -0,0 +1,114 @@
+package workload
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/intel/rmd/internal/db"
+	wltypes "github.com/intel/rmd/modules/workload/types"
+	util "github.com/intel/rmd/utils"
+)
+
+// ContinueHeader is the response header with token of the next page of workloads
+const ContinueHeader = "X-Continue"
+
+// parseListOptions reads paging, sorting and filters of workload list from query params
+func parseListOptions(query url.Values) (db.ListOptions, error) {
+	opts := db.ListOptions{
+		Filter: db.WorkloadFilter{
+			Status: query.Get("status"),
+			Origin: query.Get("origin"),
+			Policy: query.Get("policy"),
+			CoreID: query.Get("core_id"),
+			TaskID: query.Get("task_id"),
+		},
+	}
+
+	if limit := query.Get("limit"); len(limit) > 0 {
+		l, err := strconv.Atoi(limit)
+		if err != nil || l <= 0 {
+			return opts, fmt.Errorf("Invalid limit %q", limit)
+		}
+		opts.Limit = l
+	}
+
+	if token := query.Get("continue"); len(token) > 0 {
+		position, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil || len(position) == 0 {
+			return opts, fmt.Errorf("Invalid continue token %q", token)
+		}
+		opts.Continue = string(position)
+	}
+
+	if sort := query.Get("sort"); len(sort) > 0 {
+		opts.Descending = strings.HasPrefix(sort, "-")
+		opts.Sort = strings.TrimPrefix(sort, "-")
+		if !util.HasElem(db.SortFields, opts.Sort) {
+			return opts, fmt.Errorf("Invalid sort %q, supported fields are %s (prefixed with - for descending order)",
+				sort, strings.Join(db.SortFields, ", "))
+		}
+	}
+	return opts, nil
+}
+
+// continueToken returns token of the page starting after given list position
+func continueToken(position string) string {
+	if len(position) == 0 {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(position))
+}
+
+// parseFields checks comma separated names of workload fields returned to User
+func parseFields(fields string) ([]string, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	known := map[string]bool{}
+	t := reflect.TypeOf(wltypes.UserRDTWorkLoad{})
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		known[name] = true
+	}
+
+	// ID is always returned to identify workload
+	names := []string{"id"}
+	for _, f := range strings.Split(fields, ",") {
+		f = strings.TrimSpace(f)
+		if !known[f] {
+			return nil, fmt.Errorf("Unknown workload field %q", f)
+		}
+		if f != "id" {
+			names = append(names, f)
+		}
+	}
+	return names, nil
+}
+
+// selectFields returns workloads with only given fields
+func selectFields(userws []wltypes.UserRDTWorkLoad, fields []string) ([]map[string]interface{}, error) {
+	result := []map[string]interface{}{}
+	for _, w := range userws {
+		data, err := json.Marshal(w)
+		if err != nil {
+			return nil, err
+		}
+		all := map[string]interface{}{}
+		if err := json.Unmarshal(data, &all); err != nil {
+			return nil, err
+		}
+		selected := map[string]interface{}{}
+		for _, f := range fields {
+			if v, ok := all[f]; ok {
+				selected[f] = v
+			}
+		}
+		result = append(result, selected)
+	}
+	return result, nil
+}
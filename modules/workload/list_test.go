@@ -0,0 +1,88 @@
+package workload
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/intel/rmd/internal/db"
+	tw "github.com/intel/rmd/modules/workload/types"
+)
+
+func Test_parseListOptions(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    db.ListOptions
+		wantErr bool
+	}{
+		{"", db.ListOptions{}, false},
+		{"limit=10&sort=-id&continue=" + continueToken("12"),
+			db.ListOptions{Limit: 10, Continue: "12", Sort: "id", Descending: true}, false},
+		{"status=Successful&origin=REST&policy=gold&core_id=3&task_id=100&sort=id",
+			db.ListOptions{Filter: db.WorkloadFilter{Status: "Successful", Origin: "REST", Policy: "gold",
+				CoreID: "3", TaskID: "100"}, Sort: "id"}, false},
+		{"sort=status&continue=" + continueToken("Failed\x0012"),
+			db.ListOptions{Continue: "Failed\x0012", Sort: "status"}, false},
+		{"sort=-origin", db.ListOptions{Sort: "origin", Descending: true}, false},
+		{"limit=0", db.ListOptions{}, true},
+		{"limit=a", db.ListOptions{}, true},
+		{"continue=!!", db.ListOptions{}, true},
+		{"sort=cos_name", db.ListOptions{}, true},
+		{"sort=-", db.ListOptions{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.query)
+			got, err := parseListOptions(query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseListOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseListOptions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseFields(t *testing.T) {
+	tests := []struct {
+		fields  string
+		want    []string
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"status, cos_name", []string{"id", "status", "cos_name"}, false},
+		{"id,labels", []string{"id", "labels"}, false},
+		{"backend_plugin_info", nil, true},
+		{"status,", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fields, func(t *testing.T) {
+			got, err := parseFields(tt.fields)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFields() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_selectFields(t *testing.T) {
+	userws := []tw.UserRDTWorkLoad{
+		{ID: "1", Status: tw.Successful, Policy: "gold"},
+		{ID: "2", Status: tw.Failed},
+	}
+	want := []map[string]interface{}{
+		{"id": "1", "status": tw.Successful, "policy": "gold"},
+		{"id": "2", "status": tw.Failed},
+	}
+	got, err := selectFields(userws, []string{"id", "status", "policy"})
+	if err != nil {
+		t.Fatalf("selectFields() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("selectFields() = %v, want %v", got, want)
+	}
+}
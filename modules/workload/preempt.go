@@ -0,0 +1,197 @@
+package workload
+
+// Preemption of cache allocations by workloads with higher priority.
+// When guarantee pool has not enough ways left, guaranteed workloads with
+// lower priority are demoted to besteffort (or shared) pool and restored
+// when cache is available again.
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	rmderror "github.com/intel/rmd/internal/error"
+	"github.com/intel/rmd/modules/cache"
+	"github.com/intel/rmd/modules/events"
+	evtypes "github.com/intel/rmd/modules/events/types"
+	wltypes "github.com/intel/rmd/modules/workload/types"
+)
+
+// pools workload can be demoted to (in order of preference)
+var demotionPools = []string{cache.Besteffort, cache.Shared}
+
+// canPreempt checks if failed calculation of workload resources can be fixed by preemption
+func canPreempt(w *wltypes.RDTWorkLoad, er *wltypes.EnforceRequest, err error) bool {
+	if w.Priority <= 0 || !er.UseCache || er.Type != cache.Guarantee {
+		return false
+	}
+	appErr, ok := err.(*rmderror.AppError)
+	return ok && appErr.Code == http.StatusBadRequest &&
+		strings.HasPrefix(appErr.Message, "Not enough cache left")
+}
+
+// isGuaranteed checks if workload has cache ways allocated in guarantee pool
+func isGuaranteed(w *wltypes.RDTWorkLoad) bool {
+	if w.Demotion != nil || w.Rdt.Cache.Max == nil || w.Rdt.Cache.Min == nil {
+		return false
+	}
+	pool, err := cache.GetCachePoolName(*w.Rdt.Cache.Max, *w.Rdt.Cache.Min)
+	return err == nil && pool == cache.Guarantee
+}
+
+// preemptionCandidates returns guaranteed workloads with priority lower than given one.
+// Workloads with lowest priority are first.
+func preemptionCandidates(ws []wltypes.RDTWorkLoad, priority int) []wltypes.RDTWorkLoad {
+	candidates := []wltypes.RDTWorkLoad{}
+	for _, w := range ws {
+		if len(w.CosName) == 0 || w.Priority >= priority || !isGuaranteed(&w) {
+			continue
+		}
+		candidates = append(candidates, w)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Priority < candidates[j].Priority
+	})
+	return candidates
+}
+
+// demotedWorkloads returns demoted workloads, workloads with highest priority are first
+func demotedWorkloads(ws []wltypes.RDTWorkLoad) []wltypes.RDTWorkLoad {
+	demoted := []wltypes.RDTWorkLoad{}
+	for _, w := range ws {
+		if w.Demotion != nil {
+			demoted = append(demoted, w)
+		}
+	}
+	sort.SliceStable(demoted, func(i, j int) bool {
+		return demoted[i].Priority > demoted[j].Priority
+	})
+	return demoted
+}
+
+// demoteRequest changes enforce request so workload is placed in given pool
+func demoteRequest(req *wltypes.EnforceRequest, pool string) {
+	req.Type = pool
+	// MBA can be used only for guaranteed cache request
+	req.UseMba = false
+	switch pool {
+	case cache.Besteffort:
+		req.MinWays = 1
+	case cache.Shared:
+		req.MaxWays = 0
+		req.MinWays = 0
+	}
+}
+
+// preempt releases guaranteed workloads with lower priority (one by one) until
+// resources for w can be calculated. Released workloads are returned and have to be
+// demoted (or enforced again) by caller. Caller has to hold the workload lock.
+func preempt(w *wltypes.RDTWorkLoad, er *wltypes.EnforceRequest, rdtenforce *wltypes.RDTEnforce,
+	cause error) ([]wltypes.RDTWorkLoad, error) {
+	ws, err := GetAll()
+	if err != nil {
+		return nil, cause
+	}
+	released := []wltypes.RDTWorkLoad{}
+	for _, victim := range preemptionCandidates(ws, w.Priority) {
+		if err := release(&victim); err != nil {
+			log.Errorf("Failed to release workload %s for preemption: %v", victim.ID, err)
+			continue
+		}
+		released = append(released, victim)
+
+		*er = wltypes.EnforceRequest{}
+		*rdtenforce = wltypes.RDTEnforce{}
+		if err := calculateRDT(w, er, rdtenforce); err == nil {
+			log.Infof("Workload with priority %d preempted %d workload(s)", w.Priority, len(released))
+			return released, nil
+		}
+	}
+	// even without lower priority workloads there's not enough cache
+	reenforceVictims(released)
+	return nil, cause
+}
+
+// reenforceVictims enforces again released workloads with their original params
+func reenforceVictims(victims []wltypes.RDTWorkLoad) {
+	for i := range victims {
+		v := &victims[i]
+		if err := enforce(v); err != nil {
+			log.Errorf("Failed to enforce again preempted workload %s: %v", v.ID, err)
+			events.Publish(evtypes.WorkloadFailed, v, err.Error())
+		}
+		if err := updateInDB(v); err != nil {
+			log.Errorf("Failed to update preempted workload %s: %v", v.ID, err)
+		}
+	}
+}
+
+// demote enforces released workloads in besteffort pool (or shared pool if besteffort
+// pool is full) and records demotion in database
+func demote(victims []wltypes.RDTWorkLoad, w *wltypes.RDTWorkLoad) {
+	for i := range victims {
+		v := &victims[i]
+		v.Demotion = &wltypes.Demotion{By: w.CosName, Time: time.Now()}
+		var err error
+		for _, pool := range demotionPools {
+			v.Demotion.Type = pool
+			if err = enforce(v); err == nil {
+				break
+			}
+		}
+		message := fmt.Sprintf("Workload demoted to %s pool by workload in %s", v.Demotion.Type, w.CosName)
+		if err != nil {
+			log.Errorf("Failed to enforce demoted workload %s: %v", v.ID, err)
+			message = fmt.Sprintf("Workload preempted by workload in %s and failed to enforce: %v", w.CosName, err)
+		}
+		if err := updateInDB(v); err != nil {
+			log.Errorf("Failed to update demoted workload %s: %v", v.ID, err)
+		}
+		events.Publish(evtypes.WorkloadDemoted, v, message)
+	}
+}
+
+// restoreDemoted enforces demoted workloads with requested params if there's enough
+// resources for them. Workloads with higher priority are restored first.
+func restoreDemoted() {
+	l.Lock()
+	defer l.Unlock()
+
+	ws, err := GetAll()
+	if err != nil {
+		log.Errorf("Failed to get demoted workloads: %v", err)
+		return
+	}
+	for _, v := range demotedWorkloads(ws) {
+		// check (without any changes) if requested resources are available
+		probe := v
+		probe.Demotion = nil
+		if err := calculateRDT(&probe, &wltypes.EnforceRequest{}, &wltypes.RDTEnforce{}); err != nil {
+			continue
+		}
+
+		demotion := v.Demotion
+		if err := release(&v); err != nil {
+			log.Errorf("Failed to release demoted workload %s: %v", v.ID, err)
+			continue
+		}
+		v.Demotion = nil
+		if err := enforce(&v); err != nil {
+			log.Errorf("Failed to restore demoted workload %s: %v", v.ID, err)
+			v.Demotion = demotion
+			if err := enforce(&v); err != nil {
+				log.Errorf("Failed to enforce again demoted workload %s: %v", v.ID, err)
+				events.Publish(evtypes.WorkloadFailed, &v, err.Error())
+			}
+		} else {
+			events.Publish(evtypes.WorkloadRestored, &v, "")
+		}
+		if err := updateInDB(&v); err != nil {
+			log.Errorf("Failed to update demoted workload %s: %v", v.ID, err)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package workload
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	rmderror "github.com/intel/rmd/internal/error"
+	"github.com/intel/rmd/modules/cache"
+	tw "github.com/intel/rmd/modules/workload/types"
+)
+
+func newPriorityWorkload(id string, priority int, max, min uint32, demoted bool) tw.RDTWorkLoad {
+	w := tw.RDTWorkLoad{ID: id, CosName: id + "-cos", Priority: priority}
+	w.Rdt.Cache.Max = &max
+	w.Rdt.Cache.Min = &min
+	if demoted {
+		w.Demotion = &tw.Demotion{Type: cache.Besteffort}
+	}
+	return w
+}
+
+func Test_preemptionCandidates(t *testing.T) {
+	ws := []tw.RDTWorkLoad{
+		newPriorityWorkload("g5", 5, 2, 2, false),
+		newPriorityWorkload("g1", 1, 2, 2, false),
+		newPriorityWorkload("g0", 0, 4, 4, false),
+		newPriorityWorkload("be", 0, 4, 1, false),
+		newPriorityWorkload("shared", 0, 0, 0, false),
+		newPriorityWorkload("demoted", 0, 2, 2, true),
+		newPriorityWorkload("g10", 10, 2, 2, false),
+	}
+	noCos := newPriorityWorkload("nocos", 0, 2, 2, false)
+	noCos.CosName = ""
+	ws = append(ws, noCos)
+
+	tests := []struct {
+		name     string
+		priority int
+		want     []string
+	}{
+		{"Lowest priority preempts nothing", 0, []string{}},
+		{"Lower priority guaranteed workloads first", 6, []string{"g0", "g1", "g5"}},
+		{"Same priority is not preempted", 5, []string{"g0", "g1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, w := range preemptionCandidates(ws, tt.priority) {
+				got = append(got, w.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("preemptionCandidates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_demotedWorkloads(t *testing.T) {
+	ws := []tw.RDTWorkLoad{
+		newPriorityWorkload("d1", 1, 2, 2, true),
+		newPriorityWorkload("g", 9, 2, 2, false),
+		newPriorityWorkload("d3", 3, 2, 2, true),
+	}
+	got := []string{}
+	for _, w := range demotedWorkloads(ws) {
+		got = append(got, w.ID)
+	}
+	if want := []string{"d3", "d1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("demotedWorkloads() = %v, want %v", got, want)
+	}
+}
+
+func Test_demoteRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		pool string
+		want tw.EnforceRequest
+	}{
+		{"Besteffort", cache.Besteffort, tw.EnforceRequest{Type: cache.Besteffort, MaxWays: 4, MinWays: 1}},
+		{"Shared", cache.Shared, tw.EnforceRequest{Type: cache.Shared}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tw.EnforceRequest{Type: cache.Guarantee, MaxWays: 4, MinWays: 4, UseMba: true}
+			demoteRequest(&req, tt.pool)
+			if !reflect.DeepEqual(req, tt.want) {
+				t.Errorf("demoteRequest() = %+v, want %+v", req, tt.want)
+			}
+		})
+	}
+}
+
+func Test_canPreempt(t *testing.T) {
+	noCache := rmderror.AppErrorf(http.StatusBadRequest, "Not enough cache left on cache_id %s", "0")
+	tests := []struct {
+		name     string
+		priority int
+		poolType string
+		err      error
+		want     bool
+	}{
+		{"Guaranteed with priority", 1, cache.Guarantee, noCache, true},
+		{"No priority", 0, cache.Guarantee, noCache, false},
+		{"Besteffort request", 1, cache.Besteffort, noCache, false},
+		{"Other error", 1, cache.Guarantee, rmderror.AppErrorf(http.StatusBadRequest, "Bad cache ways request"), false},
+		{"Not application error", 1, cache.Guarantee, errors.New("Not enough cache left"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &tw.RDTWorkLoad{Priority: tt.priority}
+			er := &tw.EnforceRequest{UseCache: true, Type: tt.poolType}
+			if got := canPreempt(w, er, tt.err); got != tt.want {
+				t.Errorf("canPreempt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
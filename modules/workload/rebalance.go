@@ -0,0 +1,274 @@
+package workload
+
+// Rebalancing of besteffort workloads.
+// Every interval cache ways of each besteffort workload are grown or shrunk
+// by one way (between its min and max) according to last level cache occupancy
+// read from its monitoring group.
+
+import (
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	proxyclient "github.com/intel/rmd/internal/proxy/client"
+	"github.com/intel/rmd/modules/cache"
+	cacheconf "github.com/intel/rmd/modules/cache/config"
+	wltypes "github.com/intel/rmd/modules/workload/types"
+	libutil "github.com/intel/rmd/utils/bitmap"
+	"github.com/intel/rmd/utils/pqos"
+	"github.com/intel/rmd/utils/resctrl"
+)
+
+// number of latest cache adjustments kept in workload
+const maxAdjustments = 10
+
+// rebalanceSample keeps state of single workload on single cache id between samples
+type rebalanceSample struct {
+	// number of consecutive samples above grow / below shrink threshold
+	high, low uint
+	mbm       uint64
+	time      time.Time
+}
+
+// rebalance samples indexed by workload id and cache id
+var rebalanceSamples = map[string]*rebalanceSample{}
+
+// startRebalancer runs rebalancing of besteffort workloads if enabled in configuration
+func startRebalancer() {
+	conf := cacheconf.NewCachePoolConfig()
+	if !conf.Rebalance {
+		return
+	}
+	if !isCqmSupported {
+		log.Error("Besteffort rebalancing not started - cache monitoring not supported on this platform")
+		return
+	}
+	if conf.RebalanceInterval == 0 || conf.ShrinkThreshold >= conf.GrowThreshold {
+		log.Errorf("Besteffort rebalancing not started due to wrong configuration: %+v", *conf)
+		return
+	}
+	for {
+		time.Sleep(time.Duration(conf.RebalanceInterval) * time.Second)
+		rebalance(conf)
+	}
+}
+
+// besteffortRange returns range of ways rebalancer can use for workload
+// ok is false if workload is not in besteffort pool
+func besteffortRange(w *wltypes.RDTWorkLoad) (max, min uint32, ok bool) {
+	if len(w.CosName) == 0 || w.Rdt.Cache.Max == nil || w.Rdt.Cache.Min == nil {
+		return 0, 0, false
+	}
+	max, min = *w.Rdt.Cache.Max, *w.Rdt.Cache.Min
+	if w.Demotion != nil {
+		// demoted workloads are kept in besteffort pool with 1 way
+		return max, 1, w.Demotion.Type == cache.Besteffort
+	}
+	pool, err := cache.GetCachePoolName(max, min)
+	return max, min, err == nil && pool == cache.Besteffort
+}
+
+// next records occupancy sample and returns direction of change: 1 to grow, -1 to shrink
+// or 0 if ways should not be changed
+func (s *rebalanceSample) next(occupancy uint32, conf *cacheconf.CachePool) int {
+	switch {
+	case occupancy >= uint32(conf.GrowThreshold):
+		s.high++
+		s.low = 0
+	case occupancy <= uint32(conf.ShrinkThreshold):
+		s.low++
+		s.high = 0
+	default:
+		s.high, s.low = 0, 0
+	}
+	if s.high >= conf.Hysteresis {
+		s.high = 0
+		return 1
+	}
+	if s.low >= conf.Hysteresis {
+		s.low = 0
+		return -1
+	}
+	return 0
+}
+
+// bandwidth records MBM counter and returns memory bandwidth in bytes per second since previous sample
+func (s *rebalanceSample) bandwidth(mbm uint64, now time.Time) uint64 {
+	var result uint64
+	if elapsed := now.Sub(s.time).Seconds(); !s.time.IsZero() && elapsed > 0 && mbm >= s.mbm {
+		result = uint64(float64(mbm-s.mbm) / elapsed)
+	}
+	s.mbm, s.time = mbm, now
+	return result
+}
+
+// occupancyPercent returns occupancy in percent of ways allocated
+func occupancyPercent(occupancy uint64, ways, waySize uint32) uint32 {
+	allocated := uint64(ways) * uint64(waySize)
+	if allocated == 0 {
+		return 0
+	}
+	return uint32(occupancy * 100 / allocated)
+}
+
+// growMask adds one free way next to mask (above it if possible)
+func growMask(mask, free *libutil.Bitmap) (*libutil.Bitmap, bool) {
+	if mask.IsEmpty() || free == nil {
+		return mask, false
+	}
+	for _, way := range []int{int(mask.Maximum()), mask.Next(0) - 1} {
+		if way < mask.Len && free.IsSet(way) {
+			grown := libutil.NewBitmapFromWords(mask.Len, mask.Words())
+			grown.Set(way)
+			return grown, true
+		}
+	}
+	return mask, false
+}
+
+// shrinkMask removes highest way of mask
+func shrinkMask(mask *libutil.Bitmap) *libutil.Bitmap {
+	if mask.Count() <= 1 {
+		return mask
+	}
+	shrunk := libutil.NewBitmapFromWords(mask.Len, mask.Words())
+	shrunk.Clear(int(mask.Maximum()) - 1)
+	return shrunk
+}
+
+// rebalance changes ways of besteffort workloads according to their cache occupancy
+func rebalance(conf *cacheconf.CachePool) {
+	// CDP workloads have separate code and data masks
+	if isCdpEnabled {
+		return
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	ws, err := GetAll()
+	if err != nil {
+		log.Errorf("Rebalancer failed to get workloads: %v", err)
+		return
+	}
+	allWays, waySize, err := getLLCGeometry()
+	if err != nil {
+		log.Errorf("Rebalancer failed to get last level cache geometry: %v", err)
+		return
+	}
+	resaall := proxyclient.GetResAssociation(pqos.GetAvailableCLOSes())
+	level := "L" + strconv.FormatUint(uint64(cache.GetLLC()), 10)
+	av, err := cache.GetAvailableCacheSchemata(resaall, []string{pqos.InfraGoupCOS, pqos.OSGroupCOS},
+		cache.Besteffort, level)
+	if err != nil {
+		log.Errorf("Rebalancer failed to read besteffort pool: %v", err)
+		return
+	}
+	now := time.Now()
+	sampled := map[string]bool{}
+	for i := range ws {
+		w := &ws[i]
+		max, min, ok := besteffortRange(w)
+		res, found := resaall[w.CosName]
+		if !ok || !found || w.MonGroup == "" {
+			continue
+		}
+		data, err := proxyclient.GetMonData(w.CosName, w.MonGroup)
+		if err != nil {
+			log.Warningf("Rebalancer failed to read monitoring data of workload %s: %v", w.ID, err)
+			continue
+		}
+
+		adjustments := rebalanceWorkload(w.ID, res, level, data, av, max, min, allWays, waySize, now,
+			sampled, conf)
+		if len(adjustments) == 0 {
+			continue
+		}
+		if err := proxyclient.Commit(res, w.CosName); err != nil {
+			log.Errorf("Rebalancer failed to commit resource group %s: %v", w.CosName, err)
+			continue
+		}
+		w.Adjustments = append(w.Adjustments, adjustments...)
+		if len(w.Adjustments) > maxAdjustments {
+			w.Adjustments = w.Adjustments[len(w.Adjustments)-maxAdjustments:]
+		}
+		if err := updateInDB(w); err != nil {
+			log.Errorf("Rebalancer failed to update workload %s: %v", w.ID, err)
+		}
+	}
+	// forget samples of removed workloads
+	for key := range rebalanceSamples {
+		if !sampled[key] {
+			delete(rebalanceSamples, key)
+		}
+	}
+}
+
+// rebalanceWorkload changes cache masks of workload resource group (not committed)
+// Free ways of besteffort pool are updated accordingly.
+func rebalanceWorkload(id string, res *resctrl.ResAssociation, level string, data map[string]resctrl.MonData,
+	free map[string]*libutil.Bitmap, max, min, allWays, waySize uint32, now time.Time, sampled map[string]bool,
+	conf *cacheconf.CachePool) []wltypes.CacheAdjustment {
+
+	adjustments := []wltypes.CacheAdjustment{}
+	coses := res.CacheSchemata[level]
+	for i := range coses {
+		cacheID := strconv.Itoa(int(coses[i].ID))
+		mask, err := libutil.NewBitmap(int(allWays), coses[i].Mask)
+		if err != nil {
+			log.Warningf("Rebalancer failed to parse mask %s of cache id %s: %v", coses[i].Mask, cacheID, err)
+			continue
+		}
+		ways := uint32(mask.Count())
+		mon, ok := data[cacheID]
+		// cache ids not used by workload have all ways set
+		if !ok || ways == allWays {
+			continue
+		}
+
+		key := id + "/" + cacheID
+		sampled[key] = true
+		s, ok := rebalanceSamples[key]
+		if !ok {
+			s = &rebalanceSample{}
+			rebalanceSamples[key] = s
+		}
+		occupancy := occupancyPercent(mon.LLCOccupancy, ways, waySize)
+		bandwidth := s.bandwidth(mon.MBMTotalBytes, now)
+
+		newMask := mask
+		switch s.next(occupancy, conf) {
+		case 1:
+			if ways < max {
+				newMask, _ = growMask(mask, free[cacheID])
+			}
+		case -1:
+			if ways > min {
+				newMask = shrinkMask(mask)
+			}
+		}
+		if newMask == mask {
+			continue
+		}
+
+		// ways taken by workload are not free anymore, released ones are free
+		released := mask.Axor(newMask)
+		if f, ok := free[cacheID]; ok {
+			released = f.Axor(newMask).Or(released)
+		}
+		free[cacheID] = released
+		coses[i].Mask = newMask.ToString()
+		adjustments = append(adjustments, wltypes.CacheAdjustment{
+			Time:      now,
+			CacheID:   cacheID,
+			OldWays:   ways,
+			NewWays:   uint32(newMask.Count()),
+			Occupancy: occupancy,
+			Bandwidth: bandwidth,
+		})
+		log.Infof("Rebalancer changed ways of workload %s on cache id %s from %d to %d (occupancy %d%%)",
+			id, cacheID, ways, newMask.Count(), occupancy)
+	}
+	return adjustments
+}
@@ -0,0 +1,154 @@
+package workload
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	cacheconf "github.com/intel/rmd/modules/cache/config"
+	tw "github.com/intel/rmd/modules/workload/types"
+	libutil "github.com/intel/rmd/utils/bitmap"
+	"github.com/intel/rmd/utils/resctrl"
+)
+
+// newWaysMask returns 11 ways cache mask from hex string
+func newWaysMask(mask string) *libutil.Bitmap {
+	bm, _ := libutil.NewBitmap(11, mask)
+	return bm
+}
+
+func Test_rebalanceSample_next(t *testing.T) {
+	conf := &cacheconf.CachePool{GrowThreshold: 90, ShrinkThreshold: 50, Hysteresis: 2}
+	tests := []struct {
+		name    string
+		samples []uint32
+		want    []int
+	}{
+		{"Grow after hysteresis", []uint32{95, 90, 99}, []int{0, 1, 0}},
+		{"Shrink after hysteresis", []uint32{10, 50}, []int{0, -1}},
+		{"Interrupted by sample in range", []uint32{95, 70, 95, 20, 95}, []int{0, 0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &rebalanceSample{}
+			got := []int{}
+			for _, occ := range tt.samples {
+				got = append(got, s.next(occ, conf))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_rebalanceSample_bandwidth(t *testing.T) {
+	s := &rebalanceSample{}
+	now := time.Now()
+	if got := s.bandwidth(1000, now); got != 0 {
+		t.Errorf("bandwidth() of first sample = %v, want 0", got)
+	}
+	if got := s.bandwidth(3000, now.Add(2*time.Second)); got != 1000 {
+		t.Errorf("bandwidth() = %v, want 1000", got)
+	}
+}
+
+func Test_growMask(t *testing.T) {
+	tests := []struct {
+		name   string
+		mask   string
+		free   string
+		want   string
+		wantOk bool
+	}{
+		{"Grow above", "30", "c0", "70", true},
+		{"Grow below", "30", "f", "38", true},
+		{"Not adjacent free ways", "30", "300", "30", false},
+		{"Highest way", "600", "1ff", "700", true},
+		{"No free way below lowest", "3", "0", "3", false},
+		{"Empty mask", "0", "ff", "0", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := growMask(newWaysMask(tt.mask), newWaysMask(tt.free))
+			if got.ToString() != tt.want || ok != tt.wantOk {
+				t.Errorf("growMask() = %s, %v, want %s, %v", got.ToString(), ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_shrinkMask(t *testing.T) {
+	tests := []struct {
+		mask string
+		want string
+	}{
+		{"70", "30"},
+		{"1", "1"},
+		{"0", "0"},
+	}
+	for _, tt := range tests {
+		if got := shrinkMask(newWaysMask(tt.mask)).ToString(); got != tt.want {
+			t.Errorf("shrinkMask(%s) = %s, want %s", tt.mask, got, tt.want)
+		}
+	}
+}
+
+func Test_besteffortRange(t *testing.T) {
+	be := newPriorityWorkload("be", 0, 4, 2, false)
+	demoted := newPriorityWorkload("demoted", 0, 4, 4, true)
+	guaranteed := newPriorityWorkload("g", 0, 4, 4, false)
+	tests := []struct {
+		name    string
+		w       tw.RDTWorkLoad
+		wantMax uint32
+		wantMin uint32
+		wantOk  bool
+	}{
+		{"Besteffort", be, 4, 2, true},
+		{"Demoted to besteffort", demoted, 4, 1, true},
+		{"Guaranteed", guaranteed, 4, 4, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			max, min, ok := besteffortRange(&tt.w)
+			if ok != tt.wantOk || (ok && (max != tt.wantMax || min != tt.wantMin)) {
+				t.Errorf("besteffortRange() = %v, %v, %v, want %v, %v, %v",
+					max, min, ok, tt.wantMax, tt.wantMin, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_rebalanceWorkload(t *testing.T) {
+	defer func() { rebalanceSamples = map[string]*rebalanceSample{} }()
+	conf := &cacheconf.CachePool{GrowThreshold: 90, ShrinkThreshold: 50, Hysteresis: 1}
+	// 2 ways allocated on cache id 0 (full), 3 ways on cache id 1 (almost empty)
+	res := &resctrl.ResAssociation{CacheSchemata: map[string][]resctrl.CacheCos{
+		"L3": {{ID: 0, Mask: "30"}, {ID: 1, Mask: "70"}, {ID: 2, Mask: "7ff"}},
+	}}
+	data := map[string]resctrl.MonData{
+		"0": {LLCOccupancy: 2000},
+		"1": {LLCOccupancy: 100},
+		"2": {LLCOccupancy: 2000},
+	}
+	free := map[string]*libutil.Bitmap{"0": newWaysMask("c0"), "1": newWaysMask("180"), "2": newWaysMask("7ff")}
+	sampled := map[string]bool{}
+
+	got := rebalanceWorkload("1", res, "L3", data, free, 4, 2, 11, 1000, time.Now(), sampled, conf)
+	if len(got) != 2 || got[0].NewWays != 3 || got[1].NewWays != 2 {
+		t.Fatalf("rebalanceWorkload() = %+v, want grow on cache id 0 and shrink on cache id 1", got)
+	}
+	wantMasks := []string{"70", "30", "7ff"}
+	for i, c := range res.CacheSchemata["L3"] {
+		if c.Mask != wantMasks[i] {
+			t.Errorf("mask of cache id %d = %s, want %s", c.ID, c.Mask, wantMasks[i])
+		}
+	}
+	if free["0"].ToString() != "80" || free["1"].ToString() != "1c0" {
+		t.Errorf("free ways = %s/%s, want 80/1c0", free["0"].ToString(), free["1"].ToString())
+	}
+	if !sampled["1/0"] || !sampled["1/1"] || sampled["1/2"] {
+		t.Errorf("sampled = %v, want only targeted cache ids", sampled)
+	}
+}
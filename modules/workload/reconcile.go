@@ -0,0 +1,262 @@
+package workload
+
+// Reconciliation of workloads stored in database with resctrl state.
+// After RMD restart (or resctrl re-mount) resource groups, CLOS pools and
+// cache masks may not match workloads found in database.
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	proxyclient "github.com/intel/rmd/internal/proxy/client"
+	"github.com/intel/rmd/modules/cache"
+	wltypes "github.com/intel/rmd/modules/workload/types"
+	libutil "github.com/intel/rmd/utils/bitmap"
+	"github.com/intel/rmd/utils/pqos"
+	"github.com/intel/rmd/utils/resctrl"
+)
+
+// report of reconciliation done during module initialization
+var startupReport *wltypes.ReconcileReport
+var reconcileLock sync.Mutex
+
+// Reconcile compares workloads in database with resource groups in resctrl.
+// If fix is true orphaned resource groups are reset and missing workloads are enforced again.
+func Reconcile(fix bool) (wltypes.ReconcileReport, error) {
+	l.Lock()
+	ws, err := GetAll()
+	if err != nil {
+		l.Unlock()
+		return wltypes.ReconcileReport{}, err
+	}
+	resall := proxyclient.GetResAssociation(nil)
+	level := "L" + strconv.FormatUint(uint64(cache.GetLLC()), 10)
+	report := diffWorkloads(ws, resall, level, cache.GetCosInfo().CbmMaskLen)
+	if fix {
+		// orphaned COSes are in the pool of available ones, reset them (under lock,
+		// before they are given to any workload) and before re-enforced workloads use them
+		resetOrphaned(&report)
+	}
+	l.Unlock()
+
+	if !fix {
+		return report, nil
+	}
+
+	for _, id := range report.Missing {
+		for i := range ws {
+			if ws[i].ID != id {
+				continue
+			}
+			if err := reenforce(&ws[i], ws); err != nil {
+				log.Errorf("Failed to re-enforce workload %s: %v", id, err)
+				if report.Failed == nil {
+					report.Failed = make(map[string]string)
+				}
+				report.Failed[id] = err.Error()
+				continue
+			}
+			log.Infof("Workload %s re-enforced with COS %s", id, ws[i].CosName)
+			report.Reenforced = append(report.Reenforced, id)
+		}
+	}
+	return report, nil
+}
+
+// GetReconcileStatus returns report of startup reconciliation and current difference
+// between database and resctrl. Nothing is changed here.
+func GetReconcileStatus() (wltypes.ReconcileStatus, error) {
+	status := wltypes.ReconcileStatus{}
+	current, err := Reconcile(false)
+	if err != nil {
+		return status, err
+	}
+	status.Current = current
+
+	reconcileLock.Lock()
+	status.Startup = startupReport
+	reconcileLock.Unlock()
+	return status, nil
+}
+
+// startupReconcile reconciles workloads during module initialization.
+// Errors are only logged as RMD should start even if some workloads cannot be restored.
+func startupReconcile() {
+	report, err := Reconcile(true)
+	if err != nil {
+		log.Errorf("Failed to reconcile workloads: %v", err)
+		return
+	}
+	if len(report.Missing) > 0 || len(report.Mismatched) > 0 || len(report.Orphaned) > 0 {
+		log.Warningf("Workloads reconciled: missing %v, mismatched %v, orphaned %v",
+			report.Missing, report.Mismatched, report.Orphaned)
+	}
+
+	reconcileLock.Lock()
+	startupReport = &report
+	reconcileLock.Unlock()
+}
+
+// resetOrphaned removes orphaned resource groups, so their tasks and CPUs are moved back
+// to default resource group and COS gets default params when it is used again
+func resetOrphaned(report *wltypes.ReconcileReport) {
+	for _, name := range report.Orphaned {
+		if err := proxyclient.DestroyResAssociation(name); err != nil {
+			log.Warningf("Orphaned resource group %s not reset: %v", name, err)
+			continue
+		}
+		log.Warningf("Orphaned resource group %s reset to defaults", name)
+		report.Reset = append(report.Reset, name)
+	}
+}
+
+// reenforce enforces workload again using new COS and updates it in database
+func reenforce(w *wltypes.RDTWorkLoad, ws []wltypes.RDTWorkLoad) error {
+	if len(w.TaskIDs) > 0 && shouldRemoveWorkload(w) {
+		return errors.New("No task of workload is running")
+	}
+	if err := validate(w); err != nil {
+		return err
+	}
+
+	// COS of workload was marked as used during initialization,
+	// return it to the pool unless it is shared with other workloads
+	users := 0
+	for _, v := range ws {
+		if v.CosName == w.CosName {
+			users++
+		}
+	}
+	if users == 1 {
+		pqos.ReturnClos(w.CosName)
+	}
+	w.CosName = ""
+
+	enforceErr := Enforce(w)
+	if err := updateInDB(w); err != nil {
+		return err
+	}
+	return enforceErr
+}
+
+// diffWorkloads compares workloads with resource groups found in resctrl.
+// allWays is the number of ways of level cache (used by not targeted cache ids).
+func diffWorkloads(ws []wltypes.RDTWorkLoad, resall map[string]*resctrl.ResAssociation,
+	level string, allWays int) wltypes.ReconcileReport {
+
+	report := wltypes.ReconcileReport{Time: time.Now()}
+	used := map[string]bool{pqos.OSGroupCOS: true, pqos.InfraGoupCOS: true}
+
+	for i := range ws {
+		w := &ws[i]
+		// CosName is used only for RDT based workloads
+		if len(w.CosName) == 0 {
+			continue
+		}
+		used[w.CosName] = true
+		res, ok := resall[w.CosName]
+		if !ok || !isAssociated(w, res) {
+			report.Missing = append(report.Missing, w.ID)
+			continue
+		}
+		max, min := w.Rdt.Cache.Max, w.Rdt.Cache.Min
+		// shared workloads use whole shared pool
+		if max == nil || min == nil || *max == 0 {
+			continue
+		}
+		// demoted workloads do not get requested number of ways
+		if w.Demotion != nil {
+			if w.Demotion.Type != cache.Besteffort {
+				continue
+			}
+			one := uint32(1)
+			min = &one
+		}
+		if !cacheWaysMatch(res.CacheSchemata[level], *max, *min, allWays) {
+			report.Mismatched = append(report.Mismatched, w.ID)
+		}
+	}
+
+	for name, res := range resall {
+		if used[name] || !strings.HasPrefix(name, "COS") {
+			continue
+		}
+		if len(res.Tasks) > 0 || !isEmptyCPUMask(res.CPUs) {
+			report.Orphaned = append(report.Orphaned, name)
+		}
+	}
+	sort.Strings(report.Orphaned)
+	return report
+}
+
+// isAssociated checks if workload cores and running tasks are assigned to resource group
+func isAssociated(w *wltypes.RDTWorkLoad, res *resctrl.ResAssociation) bool {
+	if len(w.CoreIDs) > 0 {
+		bm, err := cache.BitmapsCPUWrapper(w.CoreIDs)
+		if err != nil {
+			return false
+		}
+		resbm, err := cache.BitmapsCPUWrapper(res.CPUs)
+		if err != nil {
+			return false
+		}
+		if bm.And(resbm).ToString() != bm.ToString() {
+			return false
+		}
+	}
+
+	tasks := make(map[string]bool, len(res.Tasks))
+	for _, t := range res.Tasks {
+		tasks[t] = true
+	}
+	for _, t := range w.TaskIDs {
+		// tasks that are not running anymore are removed by DB validator
+		if _, err := os.Stat("/proc/" + t); err != nil {
+			continue
+		}
+		if !tasks[t] {
+			return false
+		}
+	}
+	return true
+}
+
+// cacheWaysMatch checks if number of ways in resource group is in range requested by workload.
+// Cache ids not used by workload have all ways set.
+func cacheWaysMatch(coses []resctrl.CacheCos, max, min uint32, allWays int) bool {
+	// no cache schemata of given level (ex. CDP enabled) - nothing to compare
+	if len(coses) == 0 {
+		return true
+	}
+	matched := false
+	for _, c := range coses {
+		ways := maskWays(c.Mask)
+		if ways >= min && ways <= max {
+			matched = true
+		} else if int(ways) != allWays {
+			return false
+		}
+	}
+	return matched
+}
+
+// maskWays returns number of ways (set bits) in hex cache mask
+func maskWays(mask string) uint32 {
+	bm, err := libutil.NewBitmap(strings.TrimSpace(mask))
+	if err != nil {
+		return 0
+	}
+	return uint32(bm.Count())
+}
+
+// isEmptyCPUMask returns true if no CPU is set in resctrl cpus mask
+func isEmptyCPUMask(mask string) bool {
+	return strings.Trim(mask, "0,\n ") == ""
+}
@@ -0,0 +1,75 @@
+package workload
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+
+	tw "github.com/intel/rmd/modules/workload/types"
+	"github.com/intel/rmd/utils/resctrl"
+)
+
+func Test_cacheWaysMatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		masks []string
+		max   uint32
+		min   uint32
+		want  bool
+	}{
+		{"Guarantee ways on one cache id", []string{"f0", "7ff"}, 4, 4, true},
+		{"Besteffort ways in range", []string{"70", "3c"}, 4, 2, true},
+		{"Ways out of range", []string{"ff", "7ff"}, 4, 4, false},
+		{"Only default ways", []string{"7ff", "7ff"}, 4, 4, false},
+		{"No schemata", []string{}, 4, 4, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coses := []resctrl.CacheCos{}
+			for i, m := range tt.masks {
+				coses = append(coses, resctrl.CacheCos{ID: uint8(i), Mask: m})
+			}
+			if got := cacheWaysMatch(coses, tt.max, tt.min, 11); got != tt.want {
+				t.Errorf("cacheWaysMatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_diffWorkloads(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+	four := uint32(4)
+
+	ws := []tw.RDTWorkLoad{
+		{ID: "1", CosName: "COS2", TaskIDs: []string{pid}},
+		{ID: "2", CosName: "COS3", TaskIDs: []string{pid}},
+		{ID: "3", CosName: "COS4", TaskIDs: []string{pid}},
+		{ID: "4", TaskIDs: []string{pid}},
+	}
+	ws[0].Rdt.Cache.Max, ws[0].Rdt.Cache.Min = &four, &four
+	ws[2].Rdt.Cache.Max, ws[2].Rdt.Cache.Min = &four, &four
+
+	resall := map[string]*resctrl.ResAssociation{
+		".":    {CPUs: "ff"},
+		"COS1": {CPUs: "1"},
+		"COS2": {Tasks: []string{pid}, CPUs: "0",
+			CacheSchemata: map[string][]resctrl.CacheCos{"L3": {{ID: 0, Mask: "f0"}}}},
+		"COS3": {CPUs: "0"},
+		"COS4": {Tasks: []string{pid}, CPUs: "0",
+			CacheSchemata: map[string][]resctrl.CacheCos{"L3": {{ID: 0, Mask: "ff0"}}}},
+		"COS5": {Tasks: []string{"1"}, CPUs: "0"},
+		"COS6": {CPUs: "00000000,00000000"},
+	}
+
+	report := diffWorkloads(ws, resall, "L3", 11)
+	if !reflect.DeepEqual(report.Missing, []string{"2"}) {
+		t.Errorf("diffWorkloads() missing = %v, want [2]", report.Missing)
+	}
+	if !reflect.DeepEqual(report.Mismatched, []string{"3"}) {
+		t.Errorf("diffWorkloads() mismatched = %v, want [3]", report.Mismatched)
+	}
+	if !reflect.DeepEqual(report.Orphaned, []string{"COS5"}) {
+		t.Errorf("diffWorkloads() orphaned = %v, want [COS5]", report.Orphaned)
+	}
+}
@@ -0,0 +1,315 @@
+package workload
+
+// Scheduled workload params.
+// Workload can define time windows (cron-like start and end) in which it uses
+// alternate policy or RDT params. Scheduler checks every minute which window
+// should be active and switches workload params with update(). Active window and
+// params used outside of windows are stored in database, so the right params are
+// applied after restart as well.
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/intel/rmd/modules/events"
+	evtypes "github.com/intel/rmd/modules/events/types"
+	"github.com/intel/rmd/modules/policy"
+	wltypes "github.com/intel/rmd/modules/workload/types"
+)
+
+// no active schedule window
+const noWindow = -1
+
+// how far back cron expression is checked for last match (covers leap days)
+const cronLookBackYears = 5
+
+// cronSchedule is parsed cron expression, each field is bit set of allowed values
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// day matches if day of month or day of week matches (unless one of them is *)
+	domStar, dowStar bool
+}
+
+// ranges of cron fields: minute, hour, day of month, month, day of week (0 and 7 are Sunday)
+var cronRanges = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+// parseCron parses cron expression "minute hour day-of-month month day-of-week".
+// Fields can be *, numbers, ranges (a-b), lists (a,b) and steps (*/n, a-b/n).
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronRanges) {
+		return nil, fmt.Errorf("cron expression %q has to have %d fields", expr, len(cronRanges))
+	}
+	sets := [len(cronRanges)]uint64{}
+	for i, field := range fields {
+		set, err := parseCronField(field, cronRanges[i][0], cronRanges[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %v", expr, err)
+		}
+		sets[i] = set
+	}
+	// Sunday can be given as 7
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+	return &cronSchedule{
+		minute:  sets[0],
+		hour:    sets[1],
+		dom:     sets[2],
+		month:   sets[3],
+		dow:     sets[4],
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField returns bit set of values allowed by cron field
+func parseCronField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		stepped := false
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			part = part[:i]
+			stepped = true
+		}
+		lo, hi := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			switch {
+			case len(bounds) == 2:
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid value %q", part)
+				}
+			case !stepped:
+				hi = lo
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("value %q out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// dayMatches checks day of month and day of week of t
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// prev returns latest time (full minute) not after t matching cron schedule
+// ok is false if there's no such time in last cronLookBackYears years
+func (c *cronSchedule) prev(t time.Time) (time.Time, bool) {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+	limit := t.AddDate(-cronLookBackYears, 0, 0)
+	for !t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			// last minute of previous month
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc).Add(-time.Minute)
+		case !c.dayMatches(t):
+			// last minute of previous day
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc).Add(-time.Minute)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			// last minute of previous hour
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc).Add(-time.Minute)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(-time.Minute)
+		default:
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// activeWindow returns index of first schedule window active at time t (or noWindow).
+// Window is active if its start matched after its end.
+func activeWindow(windows []wltypes.ScheduleWindow, t time.Time) int {
+	for i, win := range windows {
+		start, err := parseCron(win.Start)
+		if err != nil {
+			continue
+		}
+		end, err := parseCron(win.End)
+		if err != nil {
+			continue
+		}
+		started, ok := start.prev(t)
+		if !ok {
+			continue
+		}
+		if ended, ok := end.prev(t); !ok || started.After(ended) {
+			return i
+		}
+	}
+	return noWindow
+}
+
+// hasRdtParams checks if any RDT param is defined
+func hasRdtParams(p *wltypes.RDTParams) bool {
+	return p.Cache.Max != nil || p.Cache.Min != nil || p.Cache.Code != nil || p.Cache.Data != nil ||
+		p.Cache.L2.Max != nil || p.Cache.L2.Min != nil || p.Mba.Percentage != nil || p.Mba.Mbps != nil
+}
+
+// validateSchedule checks schedule windows of workload
+func validateSchedule(windows []wltypes.ScheduleWindow) error {
+	for i, win := range windows {
+		if _, err := parseCron(win.Start); err != nil {
+			return fmt.Errorf("Invalid start of schedule window %d: %v", i, err)
+		}
+		if _, err := parseCron(win.End); err != nil {
+			return fmt.Errorf("Invalid end of schedule window %d: %v", i, err)
+		}
+		if strings.Join(strings.Fields(win.Start), " ") == strings.Join(strings.Fields(win.End), " ") {
+			return fmt.Errorf("Schedule window %d starts and ends at the same time", i)
+		}
+		if (len(win.Policy) > 0) == hasRdtParams(&win.Rdt) {
+			return fmt.Errorf("Schedule window %d has to define either policy or rdt params", i)
+		}
+		if len(win.Policy) > 0 {
+			if _, err := policy.GetDefaultPolicy(win.Policy); err != nil {
+				return fmt.Errorf("Invalid policy of schedule window %d: %v", i, err)
+			}
+			continue
+		}
+		c := win.Rdt.Cache
+		if (c.Max == nil) != (c.Min == nil) {
+			return fmt.Errorf("Schedule window %d has to define both cache.* or none of them", i)
+		}
+		if c.Max != nil && *c.Min > *c.Max {
+			return fmt.Errorf("Schedule window %d cache min greater than max", i)
+		}
+	}
+	return nil
+}
+
+// copyRdtParams returns deep copy of RDT params (update() changes values under pointers)
+func copyRdtParams(p wltypes.RDTParams) wltypes.RDTParams {
+	result := wltypes.RDTParams{}
+	data, err := json.Marshal(p)
+	if err == nil {
+		json.Unmarshal(data, &result)
+	}
+	return result
+}
+
+// startScheduler switches params of scheduled workloads at the beginning of every minute
+func startScheduler() {
+	for {
+		now := time.Now()
+		time.Sleep(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+		runSchedules(time.Now())
+	}
+}
+
+// runSchedules switches params of workloads which active schedule window changed
+func runSchedules(now time.Time) {
+	// workloads are read and updated under the workload lock so concurrent
+	// changes (patch, delete) are not overwritten with stale data
+	l.Lock()
+	defer l.Unlock()
+
+	ws, err := GetAll()
+	if err != nil {
+		log.Errorf("Scheduler failed to get workloads: %v", err)
+		return
+	}
+	for i := range ws {
+		w := &ws[i]
+		if len(w.Schedule) == 0 && w.ScheduleState == nil {
+			continue
+		}
+		window := activeWindow(w.Schedule, now)
+		if !needsSwitch(w.ScheduleState, window) {
+			continue
+		}
+		if err := switchWindow(w, window, now); err != nil {
+			log.Errorf("Scheduler failed to switch workload %s to window %d: %v", w.ID, window, err)
+		}
+	}
+}
+
+// needsSwitch checks if workload with schedule state has to be switched to window,
+// switch is retried if params of active window could not be enforced
+func needsSwitch(state *wltypes.ScheduleState, window int) bool {
+	if state == nil {
+		return window != noWindow
+	}
+	return state.Window != window || state.Failed
+}
+
+// changesScheduledParams checks if patch changes policy or RDT params while they are
+// set by active schedule window (they would be replaced when window ends)
+func changesScheduledParams(w, patched *wltypes.RDTWorkLoad) bool {
+	if w.ScheduleState == nil {
+		return false
+	}
+	return (len(patched.Policy) > 0 && patched.Policy != w.Policy) || hasRdtParams(&patched.Rdt)
+}
+
+// switchWindow updates workload with params of schedule window
+// (or with params used outside of windows if window is noWindow)
+// Caller has to hold the workload lock
+func switchWindow(w *wltypes.RDTWorkLoad, window int, now time.Time) error {
+	state := w.ScheduleState
+	if state == nil {
+		// params used outside of windows
+		state = &wltypes.ScheduleState{Policy: w.Policy, Rdt: copyRdtParams(w.Rdt)}
+	}
+
+	patched := &wltypes.RDTWorkLoad{}
+	if window == noWindow {
+		patched.Policy = state.Policy
+		patched.Rdt = copyRdtParams(state.Rdt)
+	} else {
+		patched.Policy = w.Schedule[window].Policy
+		patched.Rdt = copyRdtParams(w.Schedule[window].Rdt)
+	}
+	// keep current tasks, cores and plugins params
+	patched.TaskIDs = w.TaskIDs
+	patched.CoreIDs = w.CoreIDs
+	patched.Plugins = w.Plugins
+
+	err := update(w, patched)
+	if err == nil && w.Status == wltypes.Failed {
+		// params did not change since failed switch, so they were not enforced again by update()
+		if err = enforce(w); err != nil {
+			events.Publish(evtypes.WorkloadFailed, w, err.Error())
+		}
+	}
+	if window == noWindow {
+		w.ScheduleState = nil
+	} else {
+		w.ScheduleState = &wltypes.ScheduleState{Window: window, Since: now, Failed: err != nil,
+			Policy: state.Policy, Rdt: state.Rdt}
+	}
+	if dbErr := updateInDB(w); dbErr != nil {
+		return dbErr
+	}
+	if err == nil {
+		log.Infof("Workload %s switched to schedule window %d", w.ID, window)
+	}
+	return err
+}
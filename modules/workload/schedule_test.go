@@ -0,0 +1,177 @@
+package workload
+
+import (
+	"testing"
+	"time"
+
+	tw "github.com/intel/rmd/modules/workload/types"
+)
+
+func Test_parseCron(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{"0 22 * * *", false},
+		{"*/15 8-18 * * 1-5", false},
+		{"0 0 1,15 * 7", false},
+		{"30 2 * 2/3 *", false},
+		{"0 22 * *", true},
+		{"60 22 * * *", true},
+		{"0 22 0 * *", true},
+		{"0 5-1 * * *", true},
+		{"0 */0 * * *", true},
+		{"a 22 * * *", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			if _, err := parseCron(tt.expr); (err != nil) != tt.wantErr {
+				t.Errorf("parseCron() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_cronSchedule_prev(t *testing.T) {
+	at := func(s string) time.Time {
+		tm, _ := time.ParseInLocation("2006-01-02 15:04", s, time.UTC)
+		return tm
+	}
+	tests := []struct {
+		expr string
+		now  string
+		want string
+	}{
+		{"0 22 * * *", "2020-05-11 23:10", "2020-05-11 22:00"},
+		{"0 22 * * *", "2020-05-11 21:59", "2020-05-10 22:00"},
+		{"0 22 * * *", "2020-05-11 22:00", "2020-05-11 22:00"},
+		{"*/15 8-18 * * 1-5", "2020-05-10 12:00", "2020-05-08 18:45"},
+		{"0 0 1 1 *", "2020-05-11 12:00", "2020-01-01 00:00"},
+		{"0 0 29 2 *", "2023-05-11 12:00", "2020-02-29 00:00"},
+		{"0 12 13 * 5", "2020-05-11 12:00", "2020-05-08 12:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr+" "+tt.now, func(t *testing.T) {
+			c, err := parseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("parseCron() error = %v", err)
+			}
+			got, ok := c.prev(at(tt.now))
+			if !ok || !got.Equal(at(tt.want)) {
+				t.Errorf("prev() = %v, %v, want %v", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func Test_activeWindow(t *testing.T) {
+	windows := []tw.ScheduleWindow{
+		{Start: "0 22 * * *", End: "0 6 * * *", Policy: "gold"},
+		{Start: "0 12 * * 6", End: "0 14 * * 6", Policy: "silver"},
+	}
+	tests := []struct {
+		now  string
+		want int
+	}{
+		{"2020-05-11 23:00", 0},
+		{"2020-05-12 05:59", 0},
+		{"2020-05-12 06:00", noWindow},
+		{"2020-05-12 12:30", noWindow},
+		{"2020-05-16 13:00", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.now, func(t *testing.T) {
+			now, _ := time.ParseInLocation("2006-01-02 15:04", tt.now, time.UTC)
+			if got := activeWindow(windows, now); got != tt.want {
+				t.Errorf("activeWindow() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateSchedule(t *testing.T) {
+	max, min := uint32(6), uint32(6)
+	bigMin := uint32(8)
+	cacheWindow := tw.ScheduleWindow{Start: "0 22 * * *", End: "0 6 * * *"}
+	cacheWindow.Rdt.Cache.Max = &max
+	cacheWindow.Rdt.Cache.Min = &min
+	maxOnly := cacheWindow
+	maxOnly.Rdt.Cache.Min = nil
+	minGreater := cacheWindow
+	minGreater.Rdt.Cache.Min = &bigMin
+	sameTime := cacheWindow
+	sameTime.End = " 0  22 * * *"
+	bothParams := cacheWindow
+	bothParams.Policy = "gold"
+
+	tests := []struct {
+		name    string
+		windows []tw.ScheduleWindow
+		wantErr bool
+	}{
+		{"No schedule", nil, false},
+		{"Cache window", []tw.ScheduleWindow{cacheWindow}, false},
+		{"Invalid start", []tw.ScheduleWindow{{Start: "0 25 * * *", End: "0 6 * * *"}}, true},
+		{"No params", []tw.ScheduleWindow{{Start: "0 22 * * *", End: "0 6 * * *"}}, true},
+		{"Policy and params", []tw.ScheduleWindow{bothParams}, true},
+		{"Only max cache", []tw.ScheduleWindow{maxOnly}, true},
+		{"Min greater than max", []tw.ScheduleWindow{minGreater}, true},
+		{"Same start and end", []tw.ScheduleWindow{sameTime}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateSchedule(tt.windows); (err != nil) != tt.wantErr {
+				t.Errorf("validateSchedule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_needsSwitch(t *testing.T) {
+	tests := []struct {
+		name   string
+		state  *tw.ScheduleState
+		window int
+		want   bool
+	}{
+		{"No state outside of windows", nil, noWindow, false},
+		{"Window started", nil, 0, true},
+		{"Window active", &tw.ScheduleState{Window: 0}, 0, false},
+		{"Window changed", &tw.ScheduleState{Window: 0}, 1, true},
+		{"Window ended", &tw.ScheduleState{Window: 0}, noWindow, true},
+		{"Failed switch retried", &tw.ScheduleState{Window: 0, Failed: true}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := needsSwitch(tt.state, tt.window); got != tt.want {
+				t.Errorf("needsSwitch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_changesScheduledParams(t *testing.T) {
+	max := uint32(4)
+	rdtPatch := &tw.RDTWorkLoad{}
+	rdtPatch.Rdt.Cache.Max = &max
+	tests := []struct {
+		name    string
+		state   *tw.ScheduleState
+		patched *tw.RDTWorkLoad
+		want    bool
+	}{
+		{"No active window", nil, &tw.RDTWorkLoad{Policy: "silver"}, false},
+		{"Policy changed", &tw.ScheduleState{Window: 0}, &tw.RDTWorkLoad{Policy: "silver"}, true},
+		{"Same policy", &tw.ScheduleState{Window: 0}, &tw.RDTWorkLoad{Policy: "gold"}, false},
+		{"RDT params", &tw.ScheduleState{Window: 0}, rdtPatch, true},
+		{"Tasks only", &tw.ScheduleState{Window: 0}, &tw.RDTWorkLoad{TaskIDs: []string{"1"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &tw.RDTWorkLoad{Policy: "gold", ScheduleState: tt.state}
+			if got := changesScheduledParams(w, tt.patched); got != tt.want {
+				t.Errorf("changesScheduledParams() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
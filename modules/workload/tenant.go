@@ -0,0 +1,117 @@
+package workload
+
+// Tenant quotas.
+// Workloads created by authenticated users belong to tenant of the user.
+// Resources used by all workloads of tenant (guaranteed cache ways, MBA and
+// classes of service) are limited by quota from [tenants] section of configuration.
+
+import (
+	"fmt"
+
+	"github.com/intel/rmd/modules/cache"
+	wlconf "github.com/intel/rmd/modules/workload/config"
+	wltypes "github.com/intel/rmd/modules/workload/types"
+)
+
+// quotaUsage is amount of resources limited by tenant quota
+type quotaUsage struct {
+	ways, mba, cos uint32
+}
+
+// add sums resources
+func (u *quotaUsage) add(o quotaUsage) {
+	u.ways += o.ways
+	u.mba += o.mba
+	u.cos += o.cos
+}
+
+// usesOwnCos checks if workload has (or would get) class of service not shared with other workloads
+func usesOwnCos(w *wltypes.RDTWorkLoad) bool {
+	if w.Demotion != nil {
+		return w.Demotion.Type != cache.Shared
+	}
+	if w.Rdt.Cache.Max != nil && w.Rdt.Cache.Min != nil {
+		pool, err := cache.GetCachePoolName(*w.Rdt.Cache.Max, *w.Rdt.Cache.Min)
+		return err != nil || pool != cache.Shared
+	}
+	return hasRdtParams(&w.Rdt)
+}
+
+// workloadUsage returns resources used by workload with its requested params
+func workloadUsage(w *wltypes.RDTWorkLoad) quotaUsage {
+	u := quotaUsage{}
+	if isGuaranteed(w) {
+		u.ways = *w.Rdt.Cache.Max
+	}
+	// demoted workloads do not use MBA
+	if w.Demotion == nil {
+		if w.Rdt.Mba.Percentage != nil {
+			u.mba = *w.Rdt.Mba.Percentage
+		} else if w.Rdt.Mba.Mbps != nil {
+			u.mba = *w.Rdt.Mba.Mbps
+		}
+	}
+	if usesOwnCos(w) {
+		u.cos = 1
+	}
+	return u
+}
+
+// tenantUsage returns resources used by enforced workloads of tenant (except workload with skipID)
+func tenantUsage(ws []wltypes.RDTWorkLoad, tenant, skipID string) quotaUsage {
+	u := quotaUsage{}
+	for i := range ws {
+		w := &ws[i]
+		if w.Tenant != tenant || len(w.CosName) == 0 || (len(skipID) > 0 && w.ID == skipID) {
+			continue
+		}
+		u.add(workloadUsage(w))
+	}
+	return u
+}
+
+// validateQuota checks if workload fits in quota of its tenant together with
+// workloads ws and pending workloads (not enforced yet) of the tenant
+func validateQuota(w *wltypes.RDTWorkLoad, ws []wltypes.RDTWorkLoad, pending []*wltypes.RDTWorkLoad) error {
+	if len(w.Tenant) == 0 {
+		return nil
+	}
+	quota := wlconf.NewTenantsConfig().GetQuota(w.Tenant)
+	if quota == (wlconf.Quota{}) {
+		return nil
+	}
+
+	used := tenantUsage(ws, w.Tenant, w.ID)
+	for _, p := range pending {
+		if p.Tenant == w.Tenant {
+			used.add(workloadUsage(p))
+		}
+	}
+	requested := workloadUsage(w)
+
+	if quota.GuaranteedWays > 0 && used.ways+requested.ways > quota.GuaranteedWays {
+		return fmt.Errorf("Quota of tenant %s exceeded: %d guaranteed cache ways requested, %d of %d used",
+			w.Tenant, requested.ways, used.ways, quota.GuaranteedWays)
+	}
+	if quota.Mba > 0 && used.mba+requested.mba > quota.Mba {
+		return fmt.Errorf("Quota of tenant %s exceeded: MBA %d requested, %d of %d used",
+			w.Tenant, requested.mba, used.mba, quota.Mba)
+	}
+	if quota.Cos > 0 && used.cos+requested.cos > quota.Cos {
+		return fmt.Errorf("Quota of tenant %s exceeded: %d classes of service used of %d",
+			w.Tenant, used.cos, quota.Cos)
+	}
+	return nil
+}
+
+// checkQuota validates workload against quota of its tenant and workloads in database
+func checkQuota(w *wltypes.RDTWorkLoad) error {
+	if len(w.Tenant) == 0 {
+		return nil
+	}
+	ws, err := GetAll()
+	if err != nil {
+		return err
+	}
+	return validateQuota(w, ws, nil)
+}
@@ -0,0 +1,86 @@
+package workload
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/intel/rmd/modules/cache"
+	tw "github.com/intel/rmd/modules/workload/types"
+)
+
+func newTenantWorkload(id, tenant string, max, min uint32, mba *uint32) tw.RDTWorkLoad {
+	w := tw.RDTWorkLoad{ID: id, CosName: id + "-cos", Tenant: tenant}
+	w.Rdt.Cache.Max = &max
+	w.Rdt.Cache.Min = &min
+	w.Rdt.Mba.Percentage = mba
+	return w
+}
+
+func Test_workloadUsage(t *testing.T) {
+	mba := uint32(50)
+	demoted := newTenantWorkload("d", "a", 4, 4, &mba)
+	demoted.Demotion = &tw.Demotion{Type: cache.Shared}
+	tests := []struct {
+		name string
+		w    tw.RDTWorkLoad
+		want quotaUsage
+	}{
+		{"Guaranteed with MBA", newTenantWorkload("g", "a", 4, 4, &mba), quotaUsage{4, 50, 1}},
+		{"Besteffort", newTenantWorkload("b", "a", 4, 1, nil), quotaUsage{0, 0, 1}},
+		{"Shared", newTenantWorkload("s", "a", 0, 0, nil), quotaUsage{0, 0, 0}},
+		{"Demoted to shared pool", demoted, quotaUsage{0, 0, 0}},
+		{"Plugins only", tw.RDTWorkLoad{ID: "p"}, quotaUsage{0, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := workloadUsage(&tt.w); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("workloadUsage() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateQuota(t *testing.T) {
+	viper.Set("tenants.default.guaranteedways", 6)
+	viper.Set("tenants.default.cos", 3)
+	viper.Set("tenants.quota.big.mba", 100)
+
+	mba := uint32(50)
+	ws := []tw.RDTWorkLoad{
+		newTenantWorkload("1", "a", 4, 4, nil),
+		newTenantWorkload("2", "a", 4, 1, nil),
+		newTenantWorkload("3", "b", 6, 6, nil),
+		newTenantWorkload("4", "big", 8, 8, &mba),
+	}
+	notEnforced := newTenantWorkload("5", "a", 2, 2, nil)
+	notEnforced.CosName = ""
+	ws = append(ws, notEnforced)
+	pending := newTenantWorkload("", "a", 2, 2, nil)
+
+	tests := []struct {
+		name    string
+		w       tw.RDTWorkLoad
+		pending []*tw.RDTWorkLoad
+		wantErr bool
+	}{
+		{"Without tenant", newTenantWorkload("", "", 10, 10, nil), nil, false},
+		{"Fits in default quota", newTenantWorkload("", "a", 2, 2, nil), nil, false},
+		{"Guaranteed ways exceeded", newTenantWorkload("", "a", 3, 3, nil), nil, true},
+		{"Patched workload is not counted twice", newTenantWorkload("1", "a", 6, 6, nil), nil, false},
+		{"Pending workloads are counted", newTenantWorkload("", "a", 1, 1, nil), []*tw.RDTWorkLoad{&pending}, true},
+		{"Classes of service exceeded", newTenantWorkload("", "a", 2, 1, nil), []*tw.RDTWorkLoad{&pending}, true},
+		{"Shared pool does not use class of service", newTenantWorkload("", "a", 0, 0, nil),
+			[]*tw.RDTWorkLoad{&pending}, false},
+		{"Tenant quota", newTenantWorkload("", "big", 8, 8, &mba), nil, false},
+		{"Tenant MBA quota exceeded", newTenantWorkload("", "big", 8, 8, &[]uint32{60}[0]), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateQuota(&tt.w, ws, tt.pending); (err != nil) != tt.wantErr {
+				t.Errorf("validateQuota() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package workload
+
+// Noisy neighbor detection.
+// Memory bandwidth of besteffort workloads is sampled (MBM) every interval. When workload exceeds configured threshold on some sockets, MBA of
+// its COS is temporarily capped on these sockets and restored after cooldown.
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	proxyclient "github.com/intel/rmd/internal/proxy/client"
+	cacheconf "github.com/intel/rmd/modules/cache/config"
+	"github.com/intel/rmd/modules/events"
+	evtypes "github.com/intel/rmd/modules/events/types"
+	wltypes "github.com/intel/rmd/modules/workload/types"
+	"github.com/intel/rmd/utils/pqos"
+	"github.com/intel/rmd/utils/resctrl"
+)
+
+// default throttling settings used if not set in [rdt] section
+const (
+	defaultThrottleInterval = 5
+	defaultThrottleCooldown = 60
+	// MBA percentage applied in percentage mode (in Mbps mode threshold is used)
+	defaultThrottleMbaPercentage = 20
+)
+
+// throttleState keeps MBA values of throttled COS from before throttling
+type throttleState struct {
+	original map[int]int
+	until    time.Time
+}
+
+// throttled COSes indexed by COS name
+var throttledCOS = map[string]*throttleState{}
+
+// bandwidth samples indexed by workload id and socket id
+var throttleSamples = map[string]*rebalanceSample{}
+
+// startThrottler runs noisy neighbor detection if enabled in configuration
+func startThrottler(conf cacheconf.RDTConfig) {
+	if conf.ThrottleMbps == 0 {
+		return
+	}
+	if !isCqmSupported {
+		log.Error("Noisy neighbor throttling not started - memory bandwidth monitoring not supported on this platform")
+		return
+	}
+	if conf.ThrottleInterval == 0 {
+		conf.ThrottleInterval = defaultThrottleInterval
+	}
+	if conf.ThrottleCooldown == 0 {
+		conf.ThrottleCooldown = defaultThrottleCooldown
+	}
+	if conf.ThrottleMba == 0 {
+		conf.ThrottleMba = defaultThrottleMbaPercentage
+		if isMbaMbpsAvailable {
+			conf.ThrottleMba = conf.ThrottleMbps
+		}
+	}
+	if conf.ThrottleMba > mbaMaxValue {
+		log.Errorf("Noisy neighbor throttling not started - throttleMba bigger than %d", mbaMaxValue)
+		return
+	}
+	for {
+		time.Sleep(time.Duration(conf.ThrottleInterval) * time.Second)
+		throttle(&conf)
+	}
+}
+
+// isThrottleCandidate checks if workload is in besteffort pool. Workloads of shared
+// pool are skipped as capping MBA of shared COS would throttle all of them.
+func isThrottleCandidate(w *wltypes.RDTWorkLoad) bool {
+	if len(w.CosName) == 0 || w.MonGroup == "" || pqos.IsSharedCLOS(w.CosName) {
+		return false
+	}
+	_, _, ok := besteffortRange(w)
+	return ok
+}
+
+// noisySockets returns sorted ids of sockets where bandwidth (bytes per second) exceeds threshold (Mbps)
+func noisySockets(bandwidth map[int]uint64, thresholdMbps uint32) []int {
+	sockets := []int{}
+	for id, bw := range bandwidth {
+		if bytesToMb(bw) > uint64(thresholdMbps) {
+			sockets = append(sockets, id)
+		}
+	}
+	sort.Ints(sockets)
+	return sockets
+}
+
+// bytesToMb converts bytes into megabytes
+func bytesToMb(bytes uint64) uint64 {
+	return bytes / (1024 * 1024)
+}
+
+// throttleValues returns MBA values to set for throttled sockets (current value is kept if lower than limit)
+func throttleValues(current map[int]int, sockets []int, limit int) map[int]int {
+	values := make(map[int]int, len(sockets))
+	for _, id := range sockets {
+		values[id] = limit
+		if val, ok := current[id]; ok && val < limit {
+			values[id] = val
+		}
+	}
+	return values
+}
+
+// currentMba returns MBA values of resource group per socket id
+func currentMba(res *resctrl.ResAssociation) map[int]int {
+	values := map[int]int{}
+	for _, m := range res.MbaSchemata["MB"] {
+		values[int(m.ID)] = int(m.Mba)
+	}
+	return values
+}
+
+// throttle caps MBA of noisy workloads and lifts expired caps
+func throttle(conf *cacheconf.RDTConfig) {
+	l.Lock()
+	defer l.Unlock()
+
+	ws, err := GetAll()
+	if err != nil {
+		log.Errorf("Throttler failed to get workloads: %v", err)
+		return
+	}
+	resaall := proxyclient.GetResAssociation(pqos.GetAvailableCLOSes())
+	now := time.Now()
+
+	liftThrottles(ws, resaall, now)
+
+	sampled := map[string]bool{}
+	for i := range ws {
+		w := &ws[i]
+		if !isThrottleCandidate(w) {
+			continue
+		}
+		data, err := proxyclient.GetMonData(w.CosName, w.MonGroup)
+		if err != nil {
+			log.Warningf("Throttler failed to read monitoring data of workload %s: %v", w.ID, err)
+			continue
+		}
+		bandwidth := map[int]uint64{}
+		for domain, mon := range data {
+			id, err := strconv.Atoi(domain)
+			if err != nil {
+				continue
+			}
+			key := w.ID + "/" + domain
+			sampled[key] = true
+			s, ok := throttleSamples[key]
+			if !ok {
+				s = &rebalanceSample{}
+				throttleSamples[key] = s
+			}
+			bandwidth[id] = s.bandwidth(mon.MBMTotalBytes, now)
+		}
+
+		res, ok := resaall[w.CosName]
+		if _, throttled := throttledCOS[w.CosName]; throttled || !ok {
+			continue
+		}
+		sockets := noisySockets(bandwidth, conf.ThrottleMbps)
+		if len(sockets) == 0 {
+			continue
+		}
+		original := currentMba(res)
+		if err := proxyclient.SetMba(w.CosName, throttleValues(original, sockets, int(conf.ThrottleMba))); err != nil {
+			log.Errorf("Throttler failed to set MBA of %s: %v", w.CosName, err)
+			continue
+		}
+		until := now.Add(time.Duration(conf.ThrottleCooldown) * time.Second)
+		throttledCOS[w.CosName] = &throttleState{original: original, until: until}
+
+		var peak uint64
+		for _, id := range sockets {
+			if bw := bytesToMb(bandwidth[id]); bw > peak {
+				peak = bw
+			}
+		}
+		w.Throttle = &wltypes.Throttle{Mba: conf.ThrottleMba, Bandwidth: peak, Sockets: sockets, Since: now, Until: until}
+		w.Status = wltypes.Throttled
+		if err := updateInDB(w); err != nil {
+			log.Errorf("Throttler failed to update workload %s: %v", w.ID, err)
+		}
+		message := fmt.Sprintf("Memory bandwidth %d Mbps exceeds %d Mbps, MBA of %s set to %d until %s",
+			peak, conf.ThrottleMbps, w.CosName, conf.ThrottleMba, until.Format(time.RFC3339))
+		log.Warningf("Workload %s throttled: %s", w.ID, message)
+		events.Publish(evtypes.WorkloadThrottled, w, message)
+	}
+	// forget samples of removed workloads
+	for key := range throttleSamples {
+		if !sampled[key] {
+			delete(throttleSamples, key)
+		}
+	}
+}
+
+// liftThrottles restores MBA of COSes throttled longer than cooldown
+func liftThrottles(ws []wltypes.RDTWorkLoad, resaall map[string]*resctrl.ResAssociation, now time.Time) {
+	for i := range ws {
+		w := &ws[i]
+		if w.Throttle == nil {
+			continue
+		}
+		// throttle state is lost on restart, shared and besteffort workloads
+		// can not use MBA lower than max so max is restored
+		if _, ok := throttledCOS[w.CosName]; !ok {
+			original := map[int]int{}
+			for _, id := range w.Throttle.Sockets {
+				original[id] = int(mbaMaxValue)
+			}
+			throttledCOS[w.CosName] = &throttleState{original: original, until: w.Throttle.Until}
+		}
+	}
+
+	for name, state := range throttledCOS {
+		_, used := resaall[name]
+		if used && now.Before(state.until) {
+			continue
+		}
+		// MBA of COS not used anymore is reset on release
+		if used && len(state.original) > 0 {
+			if err := proxyclient.SetMba(name, state.original); err != nil {
+				log.Errorf("Throttler failed to restore MBA of %s: %v", name, err)
+				continue
+			}
+		}
+		delete(throttledCOS, name)
+	}
+
+	for i := range ws {
+		w := &ws[i]
+		if w.Throttle == nil {
+			continue
+		}
+		if _, ok := throttledCOS[w.CosName]; ok {
+			continue
+		}
+		w.Throttle = nil
+		if w.Status == wltypes.Throttled {
+			w.Status = wltypes.Successful
+		}
+		if err := updateInDB(w); err != nil {
+			log.Errorf("Throttler failed to update workload %s: %v", w.ID, err)
+		}
+		events.Publish(evtypes.WorkloadUnthrottled, w, "")
+	}
+}
@@ -0,0 +1,56 @@
+package workload
+
+import (
+	"reflect"
+	"testing"
+
+	tw "github.com/intel/rmd/modules/workload/types"
+)
+
+func Test_noisySockets(t *testing.T) {
+	mb := uint64(1024 * 1024)
+	bandwidth := map[int]uint64{0: 500 * mb, 1: 1500 * mb, 2: 1000 * mb, 3: 2000 * mb}
+	if got, want := noisySockets(bandwidth, 1000), []int{1, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("noisySockets() = %v, want %v", got, want)
+	}
+	if got := noisySockets(bandwidth, 5000); len(got) != 0 {
+		t.Errorf("noisySockets() = %v, want none", got)
+	}
+}
+
+func Test_throttleValues(t *testing.T) {
+	current := map[int]int{0: 100, 1: 10, 2: 100}
+	got := throttleValues(current, []int{0, 1, 3}, 20)
+	want := map[int]int{0: 20, 1: 10, 3: 20}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("throttleValues() = %v, want %v", got, want)
+	}
+}
+
+func Test_isThrottleCandidate(t *testing.T) {
+	shared := newPriorityWorkload("shared", 0, 0, 0, false)
+	demotedShared := newPriorityWorkload("demoted", 0, 4, 4, true)
+	demotedShared.Demotion.Type = "shared"
+	tests := []struct {
+		name      string
+		w         tw.RDTWorkLoad
+		monitored bool
+		want      bool
+	}{
+		{"Besteffort", newPriorityWorkload("be", 0, 4, 2, false), true, true},
+		{"Shared", shared, true, false},
+		{"Demoted to shared", demotedShared, true, false},
+		{"Guaranteed", newPriorityWorkload("g", 0, 4, 4, false), true, false},
+		{"Not monitored", newPriorityWorkload("be", 0, 4, 2, false), false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.monitored {
+				tt.w.MonGroup = "rmd_1"
+			}
+			if got := isThrottleCandidate(&tt.w); got != tt.want {
+				t.Errorf("isThrottleCandidate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
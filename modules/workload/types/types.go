@@ -1,6 +1,8 @@
 package types
 
 import (
+	"time"
+
 	"github.com/intel/rmd/modules/cache"
 	libutil "github.com/intel/rmd/utils/bitmap"
 	"github.com/intel/rmd/utils/resctrl"
@@ -15,8 +17,75 @@ const (
 	Invalid = "Invalid"
 	// None status
 	None = "None"
+	// Throttled workload MBA temporarily capped as noisy neighbor
+	Throttled = "Throttled"
 )
 
+// TaskSelector selects workload tasks by process properties.
+// All given criteria have to match.
+type TaskSelector struct {
+	// Comm is a glob matched against process name
+	Comm string `json:"comm,omitempty"`
+	// Cmdline is a regular expression matched against process command line
+	Cmdline string `json:"cmdline,omitempty"`
+	// Unit is a systemd unit name (ex. nginx.service)
+	Unit string `json:"unit,omitempty"`
+}
+
+// RDTParams are RDT module (cache, MBA) settings of workload
+type RDTParams struct {
+	// Cache Settings
+	Cache struct {
+		// Max Cache ways, use pointer to distinguish 0 value and empty value
+		Max *uint32 `json:"max,omitempty"`
+		// Min Cache ways, use pointer to distinguish 0 value and empty value
+		Min *uint32 `json:"min,omitempty"`
+		// Code ways (out of allocated cache ways), used only when CDP is enabled
+		Code *uint32 `json:"code,omitempty"`
+		// Data ways (out of allocated cache ways), used only when CDP is enabled
+		Data *uint32 `json:"data,omitempty"`
+		// L2 Cache Settings, used only on platforms with L2 CAT
+		L2 struct {
+			// Max L2 Cache ways
+			Max *uint32 `json:"max,omitempty"`
+			// Min L2 Cache ways
+			Min *uint32 `json:"min,omitempty"`
+		} `json:"l2,omitempty"`
+	} `json:"cache,omitempty"`
+	// MBA settings
+	Mba struct {
+		// MBA values to be specified in Percentage
+		Percentage *uint32 `json:"percentage,omitempty"`
+		// MBA values to be specified in MB per sec
+		Mbps *uint32 `json:"mbps,omitempty"`
+	} `json:"mba,omitempty"`
+}
+
+// ScheduleWindow is time window in which workload uses alternate params.
+// Window starts and ends at times matching cron expressions
+// (minute hour day-of-month month day-of-week, ex. "0 22 * * *").
+type ScheduleWindow struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// Policy used in window
+	Policy string `json:"policy,omitempty"`
+	// RDT params used in window (if policy not given)
+	Rdt RDTParams `json:"rdt,omitempty"`
+}
+
+// ScheduleState is state of workload schedule
+type ScheduleState struct {
+	// Window is index of active schedule window
+	Window int `json:"window"`
+	// Since is time of switch to window
+	Since time.Time `json:"since"`
+	// Failed is set if workload could not be enforced with window params
+	Failed bool `json:"failed,omitempty"`
+	// Policy and RDT params used outside of windows
+	Policy string    `json:"policy,omitempty"`
+	Rdt    RDTParams `json:"rdt,omitempty"`
+}
+
 //UserRDTWorkLoad is the workload struct of RMD used by User
 type UserRDTWorkLoad struct {
 	// ID
@@ -25,29 +94,35 @@ type UserRDTWorkLoad struct {
 	CoreIDs []string `json:"core_ids,omitempty"`
 	// task ids, the work load's task ids
 	TaskIDs []string `json:"task_ids,omitempty"`
+	// cgroup path, all tasks of the cgroup (including new ones) belong to the work load
+	CgroupPath string `json:"cgroup_path,omitempty"`
+	// task selector, all matching processes (including new ones) belong to the work load
+	TaskSelector *TaskSelector `json:"task_selector,omitempty"`
 	// policy the workload want to apply
 	Policy string `json:"policy,omitempty"`
+	// priority of the workload, workloads with higher priority can preempt
+	// cache allocations of workloads with lower priority (0 by default)
+	Priority int `json:"priority,omitempty"`
+	// demotion of the workload to lower cache pool by workload with higher priority
+	Demotion *Demotion `json:"demotion,omitempty"`
+	// latest changes of besteffort workload cache ways done by rebalancer
+	Adjustments []CacheAdjustment `json:"cache_adjustments,omitempty"`
+	// temporary MBA cap applied to noisy workload
+	Throttle *Throttle `json:"throttle,omitempty"`
+	// time windows with alternate cache/MBA settings
+	Schedule []ScheduleWindow `json:"schedule,omitempty"`
+	// active schedule window and params used outside of windows
+	ScheduleState *ScheduleState `json:"schedule_state,omitempty"`
+	// lease in seconds, workload expires when not patched within ttl
+	TTL uint32 `json:"ttl,omitempty"`
+	// time after which workload is released and removed
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 	// Status
 	Status string `json:"status"`
 	// CosNamej
 	CosName string `json:"cos_name"`
 	// RDT module (RDT) related settings (Cache, MBA)
-	Rdt struct {
-		// Cache Settings
-		Cache struct {
-			// Max Cache ways, use pointer to distinguish 0 value and empty value
-			Max *uint32 `json:"max,omitempty"`
-			// Min Cache ways, use pointer to distinguish 0 value and empty value
-			Min *uint32 `json:"min,omitempty"`
-		} `json:"cache,omitempty"`
-		// MBA settings
-		Mba struct {
-			// MBA values to be specified in Percentage
-			Percentage *uint32 `json:"percentage,omitempty"`
-			// MBA values to be specified in MB per sec
-			Mbps *uint32 `json:"mbps,omitempty"`
-		} `json:"mba,omitempty"`
-	} `json:"rdt,omitempty"`
+	Rdt RDTParams `json:"rdt,omitempty"`
 	// Plugins contains information about RMD plugins and theirs settings
 	Plugins map[string]map[string]interface{} `json:"plugins,omitempty"`
 	// UUID field is for storing OpenStack instance UUID
@@ -55,6 +130,12 @@ type UserRDTWorkLoad struct {
 	// Origin, mandatory field, is for distinction who is responsible for current workload (REST API / Notification)
 	// possible values: REST and OPENSTACK
 	Origin string `json:"origin"`
+	// Tenant owning the workload (empty if workload was created without authentication)
+	Tenant string `json:"tenant,omitempty"`
+	// Labels identify group of workloads, can be used in selector queries
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations are arbitrary metadata of workload not used by RMD
+	Annotations map[string]string `json:"annotations,omitempty"`
 }
 
 //RDTWorkLoad is the workload struct of RMD
@@ -65,27 +146,35 @@ type RDTWorkLoad struct {
 	CoreIDs []string `json:"core_ids,omitempty"`
 	// task ids, the work load's task ids
 	TaskIDs []string `json:"task_ids,omitempty"`
+	// cgroup path, all tasks of the cgroup (including new ones) belong to the work load
+	CgroupPath string `json:"cgroup_path,omitempty"`
+	// task selector, all matching processes (including new ones) belong to the work load
+	TaskSelector *TaskSelector `json:"task_selector,omitempty"`
 	// policy the workload want to apply
 	Policy string `json:"policy,omitempty"`
+	// priority of the workload, workloads with higher priority can preempt
+	// cache allocations of workloads with lower priority (0 by default)
+	Priority int `json:"priority,omitempty"`
+	// demotion of the workload to lower cache pool by workload with higher priority
+	Demotion *Demotion `json:"demotion,omitempty"`
+	// latest changes of besteffort workload cache ways done by rebalancer
+	Adjustments []CacheAdjustment `json:"cache_adjustments,omitempty"`
+	// temporary MBA cap applied to noisy workload
+	Throttle *Throttle `json:"throttle,omitempty"`
+	// time windows with alternate cache/MBA settings
+	Schedule []ScheduleWindow `json:"schedule,omitempty"`
+	// active schedule window and params used outside of windows
+	ScheduleState *ScheduleState `json:"schedule_state,omitempty"`
+	// lease in seconds, workload expires when not patched within ttl
+	TTL uint32 `json:"ttl,omitempty"`
+	// time after which workload is released and removed
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 	// Status
 	Status string `json:"status"`
 	// CosNamej
 	CosName string `json:"cos_name"`
 	// RDT module (RDT) related settings (Cache, MBA)
-	Rdt struct {
-		// Cache Settings
-		Cache struct {
-			// Max Cache ways, use pointer to distinguish 0 value and empty value
-			Max *uint32 `json:"max,omitempty"`
-			// Min Cache ways, use pointer to distinguish 0 value and empty value
-			Min *uint32 `json:"min,omitempty"`
-		} `json:"cache,omitempty"`
-		// MBA settings
-		Mba struct {
-			Percentage *uint32 `json:"percentage,omitempty"`
-			Mbps       *uint32 `json:"mbps,omitempty"`
-		} `json:"mba,omitempty"`
-	} `json:"rdt,omitempty"`
+	Rdt RDTParams `json:"rdt,omitempty"`
 	// Plugins contains information about RMD plugins and theirs settings
 	Plugins map[string]map[string]interface{} `json:"plugins,omitempty"`
 	// UUID field is for storing OpenStack instance UUID
@@ -93,9 +182,128 @@ type RDTWorkLoad struct {
 	// Origin, mandatory field, is for distinction who is responsible for current workload (REST API / Notification)
 	// possible values: REST and OPENSTACK
 	Origin string `json:"origin"`
+	// Tenant owning the workload (empty if workload was created without authentication)
+	Tenant string `json:"tenant,omitempty"`
+	// Labels identify group of workloads, can be used in selector queries
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations are arbitrary metadata of workload not used by RMD
+	Annotations map[string]string `json:"annotations,omitempty"`
 	// BackendPluginInfo contains backend related information to handle RMD plugins in code
 	// There is no reason to return those info to User
 	BackendPluginInfo map[string]string `json:"backend_plugin_info,omitempty"`
+	// MonGroup is the name of resctrl monitoring group (inside CosName) used for CMT/MBM
+	MonGroup string `json:"mon_group,omitempty"`
+}
+
+// WorkLoadPatch is the body of workload PATCH request
+type WorkLoadPatch struct {
+	RDTWorkLoad
+	// priority is a pointer so it can be patched back to 0
+	Priority *int `json:"priority,omitempty"`
+}
+
+// Demotion describes workload moved from guarantee pool to make place for workload with higher priority
+type Demotion struct {
+	// Type of cache pool (besteffort or shared) workload is enforced in now
+	Type string `json:"type"`
+	// By is the COS name of workload which preempted this one
+	By string `json:"by"`
+	// Time of demotion
+	Time time.Time `json:"time"`
+}
+
+// CacheAdjustment describes change of besteffort workload cache ways done by rebalancer
+type CacheAdjustment struct {
+	Time    time.Time `json:"time"`
+	CacheID string    `json:"cache_id"`
+	OldWays uint32    `json:"old_ways"`
+	NewWays uint32    `json:"new_ways"`
+	// LLC occupancy in percent of ways allocated before change
+	Occupancy uint32 `json:"occupancy"`
+	// memory bandwidth (MBM total) in bytes per second since previous sample
+	Bandwidth uint64 `json:"bandwidth"`
+}
+
+// Throttle describes temporary MBA cap applied to workload exceeding memory bandwidth threshold
+type Throttle struct {
+	// MBA value applied (in units of MBA mode)
+	Mba uint32 `json:"mba"`
+	// Bandwidth in Mbps measured when throttling started
+	Bandwidth uint64 `json:"bandwidth"`
+	// Sockets (MBA ids) throttled
+	Sockets []int     `json:"sockets"`
+	Since   time.Time `json:"since"`
+	Until   time.Time `json:"until"`
+}
+
+// WorkLoadMetrics is the monitoring (CMT/MBM) data of workload returned to User
+type WorkLoadMetrics struct {
+	// ID of workload
+	ID string `json:"id"`
+	// CosName of workload
+	CosName string `json:"cos_name"`
+	// Monitoring data per L3 cache domain
+	Metrics map[string]resctrl.MonData `json:"metrics"`
+}
+
+// DryRunResult describes what would be allocated for workload, returned to User by dry run request
+type DryRunResult struct {
+	// CosName that would be used by workload
+	CosName string `json:"cos_name"`
+	// Type of cache pool used (guarantee, besteffort or shared)
+	Type string `json:"type,omitempty"`
+	// Schemata would be set for COS: cache level (ex. L3, L3CODE, L2) -> cache id -> ways mask
+	Schemata map[string]map[string]string `json:"schemata,omitempty"`
+	// Mba values would be set for COS: socket id -> value
+	Mba map[string]uint32 `json:"mba,omitempty"`
+	// CPUs of COS
+	CPUs string `json:"cpus,omitempty"`
+	// Shrunk besteffort groups: COS name -> cache id -> new ways mask
+	Shrunk map[string]map[string]string `json:"shrunk,omitempty"`
+	// FreePools is free ways left after allocation: cache level -> pool -> cache id -> ways mask
+	FreePools map[string]map[string]map[string]string `json:"free_pools,omitempty"`
+}
+
+// WorkLoadBatch is the list of workloads created and deleted together, received from User
+type WorkLoadBatch struct {
+	// Create workloads
+	Create []UserRDTWorkLoad `json:"create,omitempty"`
+	// Delete workloads (ids)
+	Delete []string `json:"delete,omitempty"`
+}
+
+// WorkLoadBatchResult is the result of committed batch returned to User
+type WorkLoadBatchResult struct {
+	// Created workloads
+	Created []UserRDTWorkLoad `json:"created"`
+	// Deleted workloads (ids)
+	Deleted []string `json:"deleted"`
+}
+
+// ReconcileReport is the difference between workloads stored in database and resctrl state
+type ReconcileReport struct {
+	// Time of reconciliation
+	Time time.Time `json:"time"`
+	// Missing workloads (ids) without resource group or CPUs/tasks association in resctrl
+	Missing []string `json:"missing,omitempty"`
+	// Mismatched workloads (ids) which cache ways in resctrl differ from requested ones
+	Mismatched []string `json:"mismatched,omitempty"`
+	// Orphaned COS names with CPUs or tasks assigned but not used by any workload
+	Orphaned []string `json:"orphaned,omitempty"`
+	// Reenforced workloads (ids) enforced again during reconciliation
+	Reenforced []string `json:"reenforced,omitempty"`
+	// Reset orphaned COS names which resource groups were removed (tasks and CPUs moved to default group)
+	Reset []string `json:"reset,omitempty"`
+	// Failed re-enforcements: workload id -> reason
+	Failed map[string]string `json:"failed,omitempty"`
+}
+
+// ReconcileStatus is the reconciliation status returned to User
+type ReconcileStatus struct {
+	// Startup is the report of reconciliation done on RMD start
+	Startup *ReconcileReport `json:"startup,omitempty"`
+	// Current difference between database and resctrl
+	Current ReconcileReport `json:"current"`
 }
 
 // EnforceRequest build this struct when create ResAssociation
@@ -108,10 +316,28 @@ type EnforceRequest struct {
 	MinWays uint32
 	// cache specification is not mandatory, this flag marks if cache values are used
 	UseCache bool
+	// code ways (CDP only)
+	CodeWays uint32
+	// data ways (CDP only)
+	DataWays uint32
+	// CDP enabled on host, cache is enforced as separate code and data masks
+	UseCDP bool
+	// max L2 cache ways
+	L2MaxWays uint32
+	// min L2 cache ways
+	L2MinWays uint32
+	// L2 cache specification is not mandatory, this flag marks if L2 cache values are used
+	UseL2Cache bool
+	// L2 cache pool type
+	L2Type string
+	// enforce L2 request on these L2 cache ID's
+	L2IDs []uint32
 	// enforce RDT request on these socket ID's
 	SocketIDs []uint32
 	// Mba
 	UseMba bool
+	// priority of workload (besteffort workloads with lower priority can be shrunk)
+	Priority int
 	// consume from base group or not
 	Consume bool
 	// request type
@@ -134,6 +360,8 @@ type RDTEnforce struct {
 	TargetMba string
 	// cache calculations in all sockets
 	CandidateCache map[string]*libutil.Bitmap
+	// L2 cache calculations in all L2 cache ids
+	CandidateL2Cache map[string]*libutil.Bitmap
 	// mba calculations in all sockets
 	CandidateMba map[string]*uint32
 
@@ -3,16 +3,21 @@ package workload
 // workload api objects to represent resources in RMD
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"os"
 	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/gobwas/glob"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
@@ -30,6 +35,8 @@ import (
 
 	"github.com/intel/rmd/internal/db"
 	rmderror "github.com/intel/rmd/internal/error"
+	"github.com/intel/rmd/modules/events"
+	evtypes "github.com/intel/rmd/modules/events/types"
 	"github.com/intel/rmd/modules/policy"
 	wltypes "github.com/intel/rmd/modules/workload/types"
 	util "github.com/intel/rmd/utils"
@@ -44,7 +51,137 @@ var workloadDatabase db.DB
 // Flag to check if MBA and L3 CAT is supported
 var isMbaSupported, isL3CATSupported, isMbaMbpsAvailable bool
 
-var mbaMaxValue, mbaValue uint32
+// Flag to check if cache monitoring (CMT/MBM) is supported
+var isCqmSupported bool
+
+// L2 CAT can be used in addition to last level cache allocation
+var isL2CATSupported bool
+
+// resctrl is mounted with Code/Data Prioritization enabled
+var isCdpEnabled bool
+
+var mbaMaxValue uint32
+
+// workloadMba returns MBA value requested by workload (0 if not requested)
+func workloadMba(w *wltypes.RDTWorkLoad) uint32 {
+	if isMbaMbpsAvailable && w.Rdt.Mba.Mbps != nil {
+		return *w.Rdt.Mba.Mbps
+	}
+	if !isMbaMbpsAvailable && w.Rdt.Mba.Percentage != nil {
+		return *w.Rdt.Mba.Percentage
+	}
+	return 0
+}
+
+// Measured peak memory bandwidth per socket in Mbps (0 if not configured)
+var mbaPeakMbps uint32
+
+// getLLCGeometry returns number of last level cache ways and size of single
+// way in bytes
+var getLLCGeometry = func() (uint32, uint32, error) {
+	ways := uint32(cache.GetCosInfo().CbmMaskLen)
+	waySize, err := cache.GetLLCWaySize()
+	return ways, waySize, err
+}
+
+// resolveCacheWays returns number of cache ways for given bound ("max" or
+// "min") of policy cache params defined as number of ways, percentage of
+// last level cache or cache size in MB
+func resolveCacheWays(params policy.Param, bound string) (uint32, error) {
+	if v, ok := params[bound]; ok {
+		val, ok := policy.ParamToUint32(v)
+		if !ok {
+			return 0, fmt.Errorf("Failed to convert type for %s cache", bound)
+		}
+		return val, nil
+	}
+
+	percent, isPercent := params[bound+policy.PercentSuffix]
+	size, isSize := params[bound+policy.MBSuffix]
+	if !isPercent && !isSize {
+		return 0, fmt.Errorf("Invalid policy - %s cache param not defined", bound)
+	}
+	ways, waySize, err := getLLCGeometry()
+	if err != nil || ways == 0 || waySize == 0 {
+		return 0, fmt.Errorf("Failed to get last level cache geometry: %v", err)
+	}
+
+	if isPercent {
+		val, ok := policy.ParamToFloat64(percent)
+		if !ok || val < 0 || val > 100 {
+			return 0, fmt.Errorf("Invalid policy - %s cache percentage out of range", bound)
+		}
+		result := uint32(math.Round(float64(ways) * val / 100))
+		// any non zero percentage means at least one way
+		if result == 0 && val > 0 {
+			result = 1
+		}
+		return result, nil
+	}
+
+	val, ok := policy.ParamToFloat64(size)
+	if !ok || val < 0 {
+		return 0, fmt.Errorf("Invalid policy - %s cache size out of range", bound)
+	}
+	result := uint32(math.Ceil(val * 1024 * 1024 / float64(waySize)))
+	if result > ways {
+		return 0, fmt.Errorf("Invalid policy - %s cache size %v MB exceeds last level cache size", bound, val)
+	}
+	return result, nil
+}
+
+// resolveMBA returns MBA value of policy MBA params for MBA mode of platform.
+// If params define MBA only in other mode, value is converted with measured
+// peak memory bandwidth (mbaPeakMbps).
+func resolveMBA(params policy.Param) (uint32, error) {
+	percentage, isPercentage := params["percentage"]
+	mbps, isMbps := params["mbps"]
+
+	if isMbaMbpsAvailable {
+		if isMbps {
+			val, ok := policy.ParamToUint32(mbps)
+			if !ok || val == 0 {
+				return 0, fmt.Errorf("MBA mbps has to be positive integer")
+			}
+			return val, nil
+		}
+		val, ok := policy.ParamToUint32(percentage)
+		if !isPercentage || !ok || val == 0 || val > cache.MaxMBAPercentage {
+			return 0, fmt.Errorf("MBA percentage has to be integer from 1 to %d", cache.MaxMBAPercentage)
+		}
+		// no limit in percentage means no limit in Mbps
+		if val == cache.MaxMBAPercentage {
+			return cache.MaxMBAMbps, nil
+		}
+		if mbaPeakMbps == 0 {
+			return 0, fmt.Errorf("MBA defined only in percentage and peak memory bandwidth is not configured")
+		}
+		return uint32(math.Round(float64(mbaPeakMbps) * float64(val) / 100)), nil
+	}
+
+	if isPercentage {
+		val, ok := policy.ParamToUint32(percentage)
+		if !ok || val == 0 || val > cache.MaxMBAPercentage {
+			return 0, fmt.Errorf("MBA percentage has to be integer from 1 to %d", cache.MaxMBAPercentage)
+		}
+		return val, nil
+	}
+	val, ok := policy.ParamToUint32(mbps)
+	if !isMbps || !ok || val == 0 {
+		return 0, fmt.Errorf("MBA mbps has to be positive integer")
+	}
+	if val == cache.MaxMBAMbps {
+		return cache.MaxMBAPercentage, nil
+	}
+	if mbaPeakMbps == 0 {
+		return 0, fmt.Errorf("MBA defined only in Mbps and peak memory bandwidth is not configured")
+	}
+	result := math.Ceil(float64(val) * 100 / float64(mbaPeakMbps))
+	if result > cache.MaxMBAPercentage {
+		result = cache.MaxMBAPercentage
+	}
+	return uint32(result), nil
+}
 
 // reusable function for filling workload with policy-based params
 func fillWorkloadByPolicy(wrkld *wltypes.RDTWorkLoad) error {
@@ -56,46 +193,47 @@ func fillWorkloadByPolicy(wrkld *wltypes.RDTWorkLoad) error {
 	}
 
 	// workload contains policy description - try to set all params
-	policy, err := policy.GetDefaultPolicy(wrkld.Policy)
+	params, err := policy.GetDefaultPolicy(wrkld.Policy)
 	if err != nil {
 		return fmt.Errorf("Could not find the Policy. %v", err)
 	}
 
 	// cache allocation is not mandatory so use param if they exists
-	var errMax error
-	var errMin error
-
-	// check and copy cache data
-	maxCache, ok := policy["cache"]["max"].(int64)
-	if !ok {
-		errMax = fmt.Errorf("Failed to convert type for max cache")
-	}
-
-	minCache, ok := policy["cache"]["min"].(int64)
-	if !ok {
-		errMin = fmt.Errorf("Failed to convert type for min cache")
-	}
-
-	if errMax == nil && errMin == nil {
-		wrkld.Rdt.Cache.Max = new(uint32)
-		*wrkld.Rdt.Cache.Max = uint32(maxCache)
-		wrkld.Rdt.Cache.Min = new(uint32)
-		*wrkld.Rdt.Cache.Min = uint32(minCache)
+	if cacheParams, ok := params["cache"]; ok {
+		maxCache, err := resolveCacheWays(cacheParams, "max")
+		if err != nil {
+			return err
+		}
+		minCache, err := resolveCacheWays(cacheParams, "min")
+		if err != nil {
+			return err
+		}
+		if minCache > maxCache {
+			return fmt.Errorf("Invalid policy - cache min greater than max on this platform")
+		}
+		wrkld.Rdt.Cache.Max = &maxCache
+		wrkld.Rdt.Cache.Min = &minCache
 	}
 
 	if (wrkld.Rdt.Cache.Max != nil && wrkld.Rdt.Cache.Min == nil) || (wrkld.Rdt.Cache.Max == nil && wrkld.Rdt.Cache.Min != nil) {
 		return fmt.Errorf("Invalid policy - exactly one *Cache param defined")
 	}
 
-	// check and copy MBA data
-	valMba, ok := policy["mba"]["percentage"].(int)
-	if ok {
-		wrkld.Rdt.Mba.Percentage = new(uint32)
-		*wrkld.Rdt.Mba.Percentage = uint32(valMba)
+	// check and copy MBA data (value matching MBA mode of platform is used)
+	if mbaParams, ok := params["mba"]; ok {
+		valMba, err := resolveMBA(mbaParams)
+		if err != nil {
+			return fmt.Errorf("Invalid policy %s - %v", wrkld.Policy, err)
+		}
+		if isMbaMbpsAvailable {
+			wrkld.Rdt.Mba.Mbps = &valMba
+		} else {
+			wrkld.Rdt.Mba.Percentage = &valMba
+		}
 	}
 
 	// get data from policy and fill plugins' params
-	for mod, data := range policy {
+	for mod, data := range params {
 		log.Debugf("Params for module %v found in policy", mod)
 		// "cache" and "mba" are currently internal part of workload - not plugins
 		if mod == "cache" || mod == "mba" {
@@ -114,6 +252,33 @@ func fillWorkloadByPolicy(wrkld *wltypes.RDTWorkLoad) error {
 
 // validate the request workload object is validated.
 func validate(w *wltypes.RDTWorkLoad) error {
+	// tasks of cgroup based workload are always taken from the cgroup
+	if len(w.CgroupPath) > 0 {
+		tasks, err := proc.GetCgroupTasks(w.CgroupPath)
+		if err != nil {
+			return fmt.Errorf("Failed to get tasks of cgroup: %v", err)
+		}
+		if len(tasks) == 0 {
+			return fmt.Errorf("No task in cgroup %s", w.CgroupPath)
+		}
+		w.TaskIDs = tasks
+	}
+
+	// tasks of selector based workload are all processes matching the selector
+	if w.TaskSelector != nil {
+		if len(w.CgroupPath) > 0 {
+			return fmt.Errorf("Task selector cannot be used together with cgroup path")
+		}
+		tasks, err := selectTasks(w.TaskSelector)
+		if err != nil {
+			return err
+		}
+		if len(tasks) == 0 {
+			return fmt.Errorf("No process matches task selector")
+		}
+		w.TaskIDs = tasks
+	}
+
 	if len(w.TaskIDs) <= 0 && len(w.CoreIDs) <= 0 {
 		return fmt.Errorf("No task or core id specified")
 	}
@@ -126,12 +291,33 @@ func validate(w *wltypes.RDTWorkLoad) error {
 		}
 	}
 
+	if err := validateSchedule(w.Schedule); err != nil {
+		return err
+	}
+
+	if err := validateLabels(w.Labels); err != nil {
+		return err
+	}
+	if err := validateAnnotations(w.Annotations); err != nil {
+		return err
+	}
+
+	if err := startLease(w, time.Now()); err != nil {
+		return err
+	}
+
 	if w.Policy == "" {
 		// MBA part
 		// there have to be both cache values or none of them
 		if (w.Rdt.Cache.Max == nil && w.Rdt.Cache.Min != nil) || (w.Rdt.Cache.Max != nil && w.Rdt.Cache.Min == nil) {
 			return fmt.Errorf("Need to provide both cache.* or none of them")
 		}
+		if err := validateCDP(w); err != nil {
+			return err
+		}
+		if err := validateL2Cache(w); err != nil {
+			return err
+		}
 		// If MBA values are provided :
 		// 1. Check if its a Cache guaranteed request
 		// 2. Check if MBA value is range of 1 to max
@@ -141,20 +327,15 @@ func validate(w *wltypes.RDTWorkLoad) error {
 				if w.Rdt.Mba.Percentage != nil {
 					return fmt.Errorf("Please provide MBA in Mbps")
 				}
-				if w.Rdt.Mba.Mbps != nil {
-					mbaValue = *w.Rdt.Mba.Mbps
-				}
 			} else {
 				if w.Rdt.Mba.Mbps != nil {
 					return fmt.Errorf("Please provide MBA in Percentage")
 				}
-				if w.Rdt.Mba.Percentage != nil {
-					mbaValue = *w.Rdt.Mba.Percentage
-				}
 			}
 		}
 
 		if w.Rdt.Mba.Mbps != nil || w.Rdt.Mba.Percentage != nil {
+			mbaValue := workloadMba(w)
 			if w.Rdt.Cache.Max != nil && w.Rdt.Cache.Min != nil &&
 				((*w.Rdt.Cache.Max != *w.Rdt.Cache.Min && mbaValue != mbaMaxValue) ||
 					(*w.Rdt.Cache.Min == 0 && *w.Rdt.Cache.Max == 0 && mbaValue != mbaMaxValue)) {
@@ -237,6 +418,11 @@ func validate(w *wltypes.RDTWorkLoad) error {
 		return nil
 	}
 
+	if w.Rdt.Cache.L2.Max != nil && w.Rdt.Cache.L2.Min != nil {
+		// L2 Cache params defined
+		return nil
+	}
+
 	if w.Rdt.Mba.Percentage != nil {
 		// MBA params defined
 		return nil
@@ -251,6 +437,57 @@ func validate(w *wltypes.RDTWorkLoad) error {
 	return fmt.Errorf("No RDT/Plugins params in workload")
 }
 
+// validateCDP checks code and data cache params of workload (if defined)
+func validateCDP(w *wltypes.RDTWorkLoad) error {
+	c := w.Rdt.Cache
+	if c.Code == nil && c.Data == nil {
+		return nil
+	}
+	if c.Code == nil || c.Data == nil {
+		return fmt.Errorf("Need to provide both cache.code and cache.data or none of them")
+	}
+	if !isCdpEnabled {
+		return fmt.Errorf("Code/Data Prioritization is not enabled on this machine")
+	}
+	if c.Max == nil || c.Min == nil {
+		return fmt.Errorf("cache.code and cache.data can be used only with cache.max and cache.min")
+	}
+	if *c.Max == 0 {
+		return fmt.Errorf("cache.code and cache.data are not supported for shared cache")
+	}
+	if *c.Code == 0 || *c.Data == 0 || *c.Code > *c.Max || *c.Data > *c.Max {
+		return fmt.Errorf("cache.code and cache.data should range from 1 to cache.max (%d)", *c.Max)
+	}
+	return nil
+}
+
+// validateL2Cache checks L2 cache params of workload (if defined)
+func validateL2Cache(w *wltypes.RDTWorkLoad) error {
+	l2 := w.Rdt.Cache.L2
+	if l2.Max == nil && l2.Min == nil {
+		return nil
+	}
+	if l2.Max == nil || l2.Min == nil {
+		return fmt.Errorf("Need to provide both cache.l2.* or none of them")
+	}
+	if !isL2CATSupported {
+		return fmt.Errorf("This machine does not support L2 cache allocation")
+	}
+	l2Type, err := cache.GetCachePoolName(*l2.Max, *l2.Min)
+	if err != nil {
+		return err
+	}
+	// L2 and last level cache are allocated in the same COS
+	// so shared group cannot be mixed with guarantee or besteffort one
+	if w.Rdt.Cache.Max != nil && w.Rdt.Cache.Min != nil {
+		llcType, err := cache.GetCachePoolName(*w.Rdt.Cache.Max, *w.Rdt.Cache.Min)
+		if err == nil && (llcType == cache.Shared) != (l2Type == cache.Shared) {
+			return fmt.Errorf("L2 cache and last level cache have to be both shared or both not shared")
+		}
+	}
+	return nil
+}
+
 func enforceCache(w *wltypes.RDTWorkLoad, er *wltypes.EnforceRequest, rdtenforce *wltypes.RDTEnforce) error {
 	resaall := proxyclient.GetResAssociation(pqos.GetAvailableCLOSes())
 
@@ -299,12 +536,17 @@ func enforceCache(w *wltypes.RDTWorkLoad, er *wltypes.EnforceRequest, rdtenforce
 				}
 			}
 			if maxWays <= 0 {
-				if !reserved[cache.Besteffort].Shrink {
+				// without pool shrinking enabled only workloads with lower priority can be shrunk
+				maxPriority := er.Priority - 1
+				if reserved[cache.Besteffort].Shrink {
+					maxPriority = er.Priority
+				} else if er.Priority <= 0 {
 					return rmderror.AppErrorf(http.StatusBadRequest,
 						"Not enough cache left on cache_id %s", k)
 				}
 				// Try to Shrink workload in besteffort pool
-				cand, changed, err := shrinkBEPool(resaall, reserved[cache.Besteffort].Schemata[k], socketID, er.MinWays)
+				cand, changed, err := shrinkBEPool(resaall, reserved[cache.Besteffort].Schemata[k], socketID, er.MinWays,
+					maxPriority)
 				if err != nil {
 					return rmderror.AppErrorf(http.StatusInternalServerError,
 						"Errors while try to shrink cache ways on cache_id %s", k)
@@ -345,6 +587,80 @@ func enforceCache(w *wltypes.RDTWorkLoad, er *wltypes.EnforceRequest, rdtenforce
 	return nil
 }
 
+// This function populates the rdtenforce structure with L2 cache candidates
+// L2 cache ids not used by workload get all L2 cache ways (default value)
+func enforceL2Cache(w *wltypes.RDTWorkLoad, er *wltypes.EnforceRequest, rdtenforce *wltypes.RDTEnforce) error {
+	resaall := rdtenforce.Resall
+	if resaall == nil {
+		resaall = proxyclient.GetResAssociation(pqos.GetAvailableCLOSes())
+		rdtenforce.Resall = resaall
+	}
+
+	av, err := cache.GetAvailableCacheSchemata(resaall, []string{pqos.InfraGoupCOS, pqos.OSGroupCOS}, er.L2Type, "L2")
+	if err != nil {
+		return rmderror.AppErrorf(http.StatusInternalServerError,
+			"Unable to read L2 cache schemata; %s", err.Error())
+	}
+
+	reserved := cache.GetL2ReservedInfo()
+	l2CosInfo := cache.GetL2CosInfo()
+	candidate := make(map[string]*libutil.Bitmap, 0)
+
+	for k, v := range av {
+		l2ID, _ := strconv.Atoi(k)
+		if !inCacheList(uint32(l2ID), er.L2IDs) && er.L2Type != cache.Shared {
+			candidate[k], _ = libutil.NewBitmap(l2CosInfo.CbmMaskLen, l2CosInfo.CbmMask)
+			continue
+		}
+		switch er.L2Type {
+		case cache.Guarantee:
+			candidate[k] = v.GetConnectiveBits(er.L2MaxWays, 0, false)
+		case cache.Besteffort:
+			// try to allocate max cache ways, if fail get the most available ones
+			// (shrinking of besteffort pool is not supported for L2)
+			maxWays := maxConnectiveWays(v, er.L2MinWays)
+			if maxWays == 0 {
+				return rmderror.AppErrorf(http.StatusBadRequest,
+					"Not enough L2 cache left on L2 cache_id %s", k)
+			}
+			if maxWays > er.L2MaxWays {
+				maxWays = er.L2MaxWays
+			}
+			candidate[k] = v.GetConnectiveBits(maxWays, 0, false)
+		case cache.Shared:
+			r, ok := reserved[cache.Shared]
+			if !ok {
+				return rmderror.AppErrorf(http.StatusBadRequest,
+					"No shared L2 cache pool configured")
+			}
+			candidate[k] = r.Schemata[k]
+		}
+
+		if candidate[k].IsEmpty() {
+			return rmderror.AppErrorf(http.StatusBadRequest,
+				"Not enough L2 cache left on L2 cache_id %s", k)
+		}
+	}
+	rdtenforce.CandidateL2Cache = candidate
+
+	return nil
+}
+
+// maxConnectiveWays returns length of the longest free ways range in bitmap
+// that is not shorter than minWays (0 if there's no such range)
+func maxConnectiveWays(bm *libutil.Bitmap, minWays uint32) uint32 {
+	var maxWays uint32
+	for _, val := range bm.ToBinStrings() {
+		if val[0] == '1' {
+			valLen := uint32(len(val))
+			if valLen >= minWays && maxWays < valLen {
+				maxWays = valLen
+			}
+		}
+	}
+	return maxWays
+}
+
 // This function populates the rdtenforce structure with necessary MBA params
 func enforceMba(w *wltypes.RDTWorkLoad, er *wltypes.EnforceRequest, rdtenforce *wltypes.RDTEnforce) error {
 	var availableSchemata map[string]*libutil.Bitmap
@@ -365,6 +681,7 @@ func enforceMba(w *wltypes.RDTWorkLoad, er *wltypes.EnforceRequest, rdtenforce *
 	rdtenforce.CandidateMba = make(map[string]*uint32, len(availableSchemata))
 	rdtenforce.TargetMba = "MB"
 	defaultMBAValue := mbaMaxValue
+	mbaValue := workloadMba(w)
 	for k := range availableSchemata {
 		socketID, ok := strconv.Atoi(k)
 		if ok != nil {
@@ -384,12 +701,6 @@ func enforceRDT(w *wltypes.RDTWorkLoad, er *wltypes.EnforceRequest, rdtenforce *
 	var resAss *resctrl.ResAssociation
 	var grpName string
 	var err error
-	// Read all the rdtenforce cache and MBA params
-	targetLev := rdtenforce.TargetLev
-	targetMba := rdtenforce.TargetMba
-	resaall := rdtenforce.Resall
-	candidateCache := rdtenforce.CandidateCache
-	candidateMba := rdtenforce.CandidateMba
 	changedRes := rdtenforce.ChangedRes
 
 	shouldReturnCLOS := false
@@ -418,6 +729,82 @@ func enforceRDT(w *wltypes.RDTWorkLoad, er *wltypes.EnforceRequest, rdtenforce *
 		}
 	}()
 
+	if resAss, err = newWorkloadResAss(w, er, rdtenforce, grpName); err != nil {
+		return err
+	}
+	if err = proxyclient.Commit(resAss, grpName); err != nil {
+		log.Errorf("Error while try to commit resource group for workload %s, group name %s", w.ID, grpName)
+		return rmderror.NewAppError(http.StatusInternalServerError,
+			"Error to commit resource group for workload.", err)
+	}
+
+	// loop to change shrunk resource
+	// TODO: there's corners if there are multiple changed resource groups,
+	// but we failed to commit one of them (worst case is the last group),
+	// there's no rollback.
+	// possible fix is to adding this into a task flow
+	for name, res := range changedRes {
+		log.Debugf("Shink %s group", name)
+		if err = proxyclient.Commit(res, name); err != nil {
+			log.Errorf("Error while try to commit shrunk resource group, name: %s", name)
+			proxyclient.DestroyResAssociation(grpName)
+			return rmderror.NewAppError(http.StatusInternalServerError,
+				"Error to shrink resource group", err)
+		}
+	}
+
+	// reset os group
+	if err = cache.SetOSGroup(); err != nil {
+		log.Errorf("Error while try to commit resource group for default group")
+		proxyclient.DestroyResAssociation(grpName)
+		return rmderror.NewAppError(http.StatusInternalServerError,
+			"Error while try to commit resource group for default group.", err)
+	}
+
+	log.Debug("Setting cos_name to: ", grpName)
+	// no errors till now - remove CLOS returning (releasing) flag
+	shouldReturnCLOS = false
+	w.CosName = grpName
+	startMonitoring(w)
+	publishShrunk(changedRes, w)
+	return nil
+}
+
+// publishShrunk publishes event for every besteffort workload shrunk to make place for w
+func publishShrunk(changedRes map[string]*resctrl.ResAssociation, w *wltypes.RDTWorkLoad) {
+	if len(changedRes) == 0 {
+		return
+	}
+	ws, err := GetAll()
+	if err != nil {
+		log.Errorf("Failed to get workloads shrunk in besteffort pool: %v", err)
+		return
+	}
+	for name := range changedRes {
+		message := fmt.Sprintf("Resource group %s shrunk for workload in %s", name, w.CosName)
+		var shrunk *wltypes.RDTWorkLoad
+		for i := range ws {
+			if ws[i].CosName == name {
+				shrunk = &ws[i]
+				break
+			}
+		}
+		events.Publish(evtypes.PoolShrunk, shrunk, message)
+	}
+}
+
+// newWorkloadResAss builds resource association of grpName group from cache and MBA candidates
+// calculated for workload. Nothing is committed here.
+func newWorkloadResAss(w *wltypes.RDTWorkLoad, er *wltypes.EnforceRequest, rdtenforce *wltypes.RDTEnforce,
+	grpName string) (*resctrl.ResAssociation, error) {
+	var resAss *resctrl.ResAssociation
+	// Read all the rdtenforce cache and MBA params
+	targetLev := rdtenforce.TargetLev
+	targetMba := rdtenforce.TargetMba
+	resaall := rdtenforce.Resall
+	candidateCache := rdtenforce.CandidateCache
+	candidateMba := rdtenforce.CandidateMba
+
 	// If cache is used
 	if er.UseCache {
 		if er.Type == cache.Shared {
@@ -429,12 +816,27 @@ func enforceRDT(w *wltypes.RDTWorkLoad, er *wltypes.EnforceRequest, rdtenforce *
 		} else {
 			resAss = newResAss(candidateCache, targetLev)
 		}
+		if er.UseCDP && resAss.CacheSchemata["L"+targetLev] != nil {
+			resAss = newResAssForCDP(resAss, candidateCache, er, targetLev)
+		}
+	}
+	// If L2 cache is used
+	if er.UseL2Cache {
+		l2ResAss := newResAss(rdtenforce.CandidateL2Cache, "2")
+		if resAss == nil {
+			if res, ok := resaall[grpName]; ok && er.Type == cache.Shared {
+				resAss = res
+			} else {
+				resAss = l2ResAss
+			}
+		}
+		resAss.CacheSchemata["L2"] = l2ResAss.CacheSchemata["L2"]
 	}
 	// If Mba is used
 	if er.UseMba {
 		// shared cache group is not allowed when MBA in use
 		if er.Type == cache.Shared {
-			return errors.New("MBA forbidden for shared group")
+			return nil, errors.New("MBA forbidden for shared group")
 		}
 		resAss = newResAssForMba(resAss, candidateMba, targetMba)
 	}
@@ -451,51 +853,64 @@ func enforceRDT(w *wltypes.RDTWorkLoad, er *wltypes.EnforceRequest, rdtenforce *
 		}
 	}
 	resAss.Tasks = append(resAss.Tasks, w.TaskIDs...)
-	if err = proxyclient.Commit(resAss, grpName); err != nil {
-		log.Errorf("Error while try to commit resource group for workload %s, group name %s", w.ID, grpName)
-		return rmderror.NewAppError(http.StatusInternalServerError,
-			"Error to commit resource group for workload.", err)
-	}
+	return resAss, nil
+}
 
-	// loop to change shrunk resource
-	// TODO: there's corners if there are multiple changed resource groups,
-	// but we failed to commit one of them (worst case is the last group),
-	// there's no rollback.
-	// possible fix is to adding this into a task flow
-	for name, res := range changedRes {
-		log.Debugf("Shink %s group", name)
-		if err = proxyclient.Commit(res, name); err != nil {
-			log.Errorf("Error while try to commit shrunk resource group, name: %s", name)
-			proxyclient.DestroyResAssociation(grpName)
-			return rmderror.NewAppError(http.StatusInternalServerError,
-				"Error to shrink resource group", err)
+// startMonitoring creates (or updates) CMT/MBM monitoring group for workload inside its COS
+// Monitoring is an addition to allocation so failure here is only logged
+func startMonitoring(w *wltypes.RDTWorkLoad) {
+	if !isCqmSupported || w.CosName == "" {
+		return
+	}
+	if w.MonGroup == "" {
+		w.MonGroup = "rmd_" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+	cpus := ""
+	if len(w.CoreIDs) > 0 {
+		bm, err := cache.BitmapsCPUWrapper(w.CoreIDs)
+		if err == nil {
+			cpus = bm.ToString()
 		}
 	}
-
-	// reset os group
-	if err = cache.SetOSGroup(); err != nil {
-		log.Errorf("Error while try to commit resource group for default group")
-		proxyclient.DestroyResAssociation(grpName)
-		return rmderror.NewAppError(http.StatusInternalServerError,
-			"Error while try to commit resource group for default group.", err)
+	if err := proxyclient.CreateMonGroup(w.CosName, w.MonGroup, w.TaskIDs, cpus); err != nil {
+		log.Warningf("Failed to create monitoring group for workload %s: %v", w.ID, err)
+		w.MonGroup = ""
 	}
-
-	log.Debug("Setting cos_name to: ", grpName)
-	// no errors till now - remove CLOS returning (releasing) flag
-	shouldReturnCLOS = false
-	w.CosName = grpName
-	return nil
 }
 
-// Enforce a user request workload based on defined policy
-func Enforce(w *wltypes.RDTWorkLoad) error {
-	w.Status = wltypes.Failed
+// stopMonitoring removes CMT/MBM monitoring group of workload
+func stopMonitoring(w *wltypes.RDTWorkLoad) {
+	if w.MonGroup == "" {
+		return
+	}
+	if err := proxyclient.DestroyMonGroup(w.CosName, w.MonGroup); err != nil {
+		log.Warningf("Failed to remove monitoring group %s: %v", w.MonGroup, err)
+	}
+	w.MonGroup = ""
+}
 
-	l.Lock()
-	defer l.Unlock()
+// GetMetrics returns CMT/MBM monitoring data of workload
+func GetMetrics(w *wltypes.RDTWorkLoad) (wltypes.WorkLoadMetrics, error) {
+	result := wltypes.WorkLoadMetrics{ID: w.ID, CosName: w.CosName}
+	if !isCqmSupported {
+		return result, rmderror.NewAppError(http.StatusNotImplemented,
+			"Cache monitoring not supported on this platform")
+	}
+	if w.MonGroup == "" {
+		return result, rmderror.NewAppError(http.StatusNotFound,
+			"Workload is not monitored")
+	}
+	data, err := proxyclient.GetMonData(w.CosName, w.MonGroup)
+	if err != nil {
+		return result, rmderror.NewAppError(http.StatusInternalServerError,
+			"Failed to read monitoring data", err)
+	}
+	result.Metrics = data
+	return result, nil
+}
 
-	er := &wltypes.EnforceRequest{}
-	rdtenforce := &wltypes.RDTEnforce{}
+// calculateRDT populates enforce request and calculates cache and MBA candidates for workload
+func calculateRDT(w *wltypes.RDTWorkLoad, er *wltypes.EnforceRequest, rdtenforce *wltypes.RDTEnforce) error {
 	if err := populateEnforceRequest(er, w); err != nil {
 		return err
 	}
@@ -505,19 +920,104 @@ func Enforce(w *wltypes.RDTWorkLoad) error {
 			return err
 		}
 	}
+	// Use L2 cache when params received as part of request
+	if er.UseL2Cache {
+		if err := enforceL2Cache(w, er, rdtenforce); err != nil {
+			return err
+		}
+	}
 	// Use Mba when params received as part of request
 	if er.UseMba {
 		if err := enforceMba(w, er, rdtenforce); err != nil {
 			return err
 		}
 	}
+	return nil
+}
+
+// Enforce a user request workload based on defined policy
+func Enforce(w *wltypes.RDTWorkLoad) (err error) {
+	w.Status = wltypes.Failed
+
+	l.Lock()
+	defer l.Unlock()
+
+	defer func() {
+		if err != nil {
+			events.Publish(evtypes.WorkloadFailed, w, err.Error())
+		}
+	}()
+
+	return enforce(w)
+}
+
+// Add checks quota of tenant, enforces new workload and stores it in database.
+// All is done under the workload lock so concurrent requests of a tenant
+// cannot exceed its quota together.
+func Add(w *wltypes.RDTWorkLoad) error {
+	w.Status = wltypes.Failed
+
+	l.Lock()
+	defer l.Unlock()
+
+	if err := checkQuota(w); err != nil {
+		log.Errorf("Failed to validate workload quota due to reason: %s", err.Error())
+		return rmderror.AppErrorf(http.StatusBadRequest, "Failed to validate workload. Reason: %v", err)
+	}
+	if err := enforce(w); err != nil {
+		events.Publish(evtypes.WorkloadFailed, w, err.Error())
+		return err
+	}
+	if err := Create(w); err != nil {
+		if relErr := release(w); relErr != nil {
+			log.Errorf("Failed to release workload not stored in database: %v", relErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// enforce does the work of Enforce, caller has to hold the workload lock
+func enforce(w *wltypes.RDTWorkLoad) error {
+	w.Status = wltypes.Failed
+
+	er := &wltypes.EnforceRequest{}
+	rdtenforce := &wltypes.RDTEnforce{}
+	var victims []wltypes.RDTWorkLoad
+	if err := calculateRDT(w, er, rdtenforce); err != nil {
+		if !canPreempt(w, er, err) {
+			return err
+		}
+		if victims, err = preempt(w, er, rdtenforce, err); err != nil {
+			return err
+		}
+	}
 	// Enforce the Cache and MBA params into the resctrl
-	if er.UseMba || er.UseCache {
+	if er.UseMba || er.UseCache || er.UseL2Cache {
 		if err := enforceRDT(w, er, rdtenforce); err != nil {
+			reenforceVictims(victims)
 			return err
 		}
 	}
+	if err := enforcePlugins(w); err != nil {
+		// resources of workload are given back to preempted workloads
+		if relErr := releaseRDT(w); relErr != nil {
+			log.Errorf("Failed to release workload with failed plugin: %v", relErr)
+		}
+		w.CosName = ""
+		reenforceVictims(victims)
+		return err
+	}
+	demote(victims, w)
 
+	// new COS is not throttled
+	w.Throttle = nil
+	w.Status = wltypes.Successful
+	return nil
+}
+
+// enforcePlugins sends enforce requests of workload to plugin modules
+func enforcePlugins(w *wltypes.RDTWorkLoad) error {
 	for module, params := range w.Plugins {
 		log.Debugf("Sending enforce request to %v module with %v params", module, params)
 		paramsMap, err := util.UnifyMapParamsTypes(params)
@@ -543,9 +1043,124 @@ func Enforce(w *wltypes.RDTWorkLoad) error {
 
 		w.BackendPluginInfo[module] = result
 	}
-
-	w.Status = wltypes.Successful
-	return nil
+	return nil
+}
+
+// DryRun calculates resources that Enforce would allocate for workload.
+// Nothing is committed to resctrl, no CLOS is reserved and workload is not modified.
+// Preemption is not simulated (it would release workloads with lower priority), so
+// when guarantee pool is full DryRun fails while Enforce may still succeed by demoting them.
+func DryRun(w *wltypes.RDTWorkLoad) (wltypes.DryRunResult, error) {
+	result := wltypes.DryRunResult{}
+
+	l.Lock()
+	defer l.Unlock()
+
+	if err := checkQuota(w); err != nil {
+		return result, rmderror.AppErrorf(http.StatusBadRequest, "Failed to validate workload. Reason: %v", err)
+	}
+
+	er := &wltypes.EnforceRequest{}
+	rdtenforce := &wltypes.RDTEnforce{}
+	if err := calculateRDT(w, er, rdtenforce); err != nil {
+		return result, err
+	}
+	if !er.UseMba && !er.UseCache && !er.UseL2Cache {
+		// nothing to allocate in resctrl
+		return result, nil
+	}
+
+	var grpName string
+	var err error
+	if er.Type == cache.Shared {
+		grpName, err = pqos.PeekSharedCLOS()
+	} else {
+		grpName, err = pqos.PeekAvailableCLOS()
+	}
+	if err != nil {
+		return result, rmderror.NewAppError(http.StatusBadRequest, "Failed to find free CLOS", err)
+	}
+
+	// candidates are calculated on a fresh copy of resource associations
+	// so they can be changed here without touching the host
+	resAss, err := newWorkloadResAss(w, er, rdtenforce, grpName)
+	if err != nil {
+		return result, rmderror.NewAppError(http.StatusBadRequest, err.Error())
+	}
+
+	result.CosName = grpName
+	result.Type = er.Type
+	result.CPUs = resAss.CPUs
+	result.Schemata = make(map[string]map[string]string, len(resAss.CacheSchemata))
+	for level, coses := range resAss.CacheSchemata {
+		result.Schemata[level] = cacheCosToMap(coses)
+	}
+	if len(resAss.MbaSchemata) > 0 {
+		result.Mba = make(map[string]uint32)
+		for _, v := range resAss.MbaSchemata[rdtenforce.TargetMba] {
+			result.Mba[strconv.Itoa(int(v.ID))] = v.Mba
+		}
+	}
+	if len(rdtenforce.ChangedRes) > 0 {
+		result.Shrunk = make(map[string]map[string]string, len(rdtenforce.ChangedRes))
+		for name, res := range rdtenforce.ChangedRes {
+			result.Shrunk[name] = cacheCosToMap(res.CacheSchemata["L"+rdtenforce.TargetLev])
+		}
+	}
+
+	resaall := make(map[string]*resctrl.ResAssociation, len(rdtenforce.Resall)+1)
+	for name, res := range rdtenforce.Resall {
+		resaall[name] = res
+	}
+	resaall[grpName] = resAss
+	levels := []string{}
+	if er.UseCache {
+		levels = append(levels, "L"+rdtenforce.TargetLev)
+	}
+	if er.UseL2Cache {
+		levels = append(levels, "L2")
+	}
+	result.FreePools = getFreePools(resaall, levels)
+
+	return result, nil
+}
+
+// cacheCosToMap converts list of cache COS into cache id -> ways mask map
+func cacheCosToMap(coses []resctrl.CacheCos) map[string]string {
+	result := make(map[string]string, len(coses))
+	for _, v := range coses {
+		result[strconv.Itoa(int(v.ID))] = v.Mask
+	}
+	return result
+}
+
+// getFreePools returns free ways of all cache pools on given cache levels
+// Pools that cannot be calculated are skipped
+func getFreePools(resaall map[string]*resctrl.ResAssociation, levels []string) map[string]map[string]map[string]string {
+	result := make(map[string]map[string]map[string]string, len(levels))
+	for _, level := range levels {
+		reserved := cache.GetReservedInfo()
+		if level == "L2" && cache.IsL2CatEnabled() {
+			reserved = cache.GetL2ReservedInfo()
+		}
+		result[level] = make(map[string]map[string]string)
+		for _, pool := range []string{cache.Guarantee, cache.Besteffort, cache.Shared} {
+			if _, ok := reserved[pool]; !ok {
+				continue
+			}
+			av, err := cache.GetAvailableCacheSchemata(resaall,
+				[]string{pqos.InfraGoupCOS, pqos.OSGroupCOS}, pool, level)
+			if err != nil {
+				log.Debugf("Failed to get available schemata of %s pool: %v", pool, err)
+				continue
+			}
+			result[level][pool] = make(map[string]string, len(av))
+			for id, bm := range av {
+				result[level][pool][id] = bm.ToString()
+			}
+		}
+	}
+	return result
 }
 
 // Release Cos of the workload
@@ -553,6 +1168,11 @@ func Release(w *wltypes.RDTWorkLoad) error {
 	l.Lock()
 	defer l.Unlock()
 
+	return release(w)
+}
+
+// release does the work of Release, caller has to hold the workload lock
+func release(w *wltypes.RDTWorkLoad) error {
 	for module, params := range w.Plugins {
 		log.Debugf("Sending release request to %v module with %v params", module, params) // temporary log
 
@@ -582,12 +1202,32 @@ func Release(w *wltypes.RDTWorkLoad) error {
 			return err
 		}
 	}
+	return releaseRDT(w)
+}
 
+// releaseRDT releases resource group of workload, caller has to hold the workload lock
+func releaseRDT(w *wltypes.RDTWorkLoad) error {
 	// CosName is used only for RDT based workloads so now check it en exit if not found
 	if w.CosName == "" {
 		return nil
 	}
 
+	// remove monitoring group before tasks and cores are moved out of resource group
+	stopMonitoring(w)
+
+	// cgroup could get new tasks after last DB validation
+	if len(w.CgroupPath) > 0 {
+		if tasks, err := proc.GetCgroupTasks(w.CgroupPath); err == nil {
+			w.TaskIDs = tasks
+		}
+	}
+	// so could the task selector
+	if w.TaskSelector != nil {
+		if tasks, err := selectTasks(w.TaskSelector); err == nil {
+			w.TaskIDs = tasks
+		}
+	}
+
 	// remove workload tasks from resource group
 	if len(w.TaskIDs) > 0 {
 		if err := proxyclient.RemoveTasks(w.TaskIDs); err != nil {
@@ -644,7 +1284,7 @@ func Release(w *wltypes.RDTWorkLoad) error {
 	return cache.SetOSGroup()
 }
 
-// Update a workload
+// Update a workload, caller has to hold the workload lock
 func update(w, patched *wltypes.RDTWorkLoad) error {
 	// if we change policy/max_cache/min_cache, release current resource group
 	// and re-enforce it.
@@ -687,6 +1327,28 @@ func update(w, patched *wltypes.RDTWorkLoad) error {
 			}
 		}
 
+		if patched.Rdt.Cache.Code != nil || patched.Rdt.Cache.Data != nil {
+			// param manually defined - drop policy information
+			w.Policy = ""
+			if patchUint32(&w.Rdt.Cache.Code, patched.Rdt.Cache.Code) {
+				reEnforce = true
+			}
+			if patchUint32(&w.Rdt.Cache.Data, patched.Rdt.Cache.Data) {
+				reEnforce = true
+			}
+		}
+
+		if patched.Rdt.Cache.L2.Max != nil || patched.Rdt.Cache.L2.Min != nil {
+			// param manually defined - drop policy information
+			w.Policy = ""
+			if patchUint32(&w.Rdt.Cache.L2.Max, patched.Rdt.Cache.L2.Max) {
+				reEnforce = true
+			}
+			if patchUint32(&w.Rdt.Cache.L2.Min, patched.Rdt.Cache.L2.Min) {
+				reEnforce = true
+			}
+		}
+
 		if isMbaSupported {
 			if isMbaMbpsAvailable {
 				if patched.Rdt.Mba.Percentage != nil {
@@ -704,12 +1366,10 @@ func update(w, patched *wltypes.RDTWorkLoad) error {
 				w.Policy = ""
 				if w.Rdt.Mba.Percentage == nil {
 					w.Rdt.Mba.Percentage = patched.Rdt.Mba.Percentage
-					mbaValue = *patched.Rdt.Mba.Percentage
 					reEnforce = true
 				}
 				if w.Rdt.Mba.Percentage != nil && *w.Rdt.Mba.Percentage != *patched.Rdt.Mba.Percentage {
 					*w.Rdt.Mba.Percentage = *patched.Rdt.Mba.Percentage
-					mbaValue = *patched.Rdt.Mba.Percentage
 					reEnforce = true
 				}
 			} else {
@@ -722,12 +1382,10 @@ func update(w, patched *wltypes.RDTWorkLoad) error {
 				w.Policy = ""
 				if w.Rdt.Mba.Mbps == nil {
 					w.Rdt.Mba.Mbps = patched.Rdt.Mba.Mbps
-					mbaValue = *patched.Rdt.Mba.Mbps
 					reEnforce = true
 				}
 				if w.Rdt.Mba.Mbps != nil && *w.Rdt.Mba.Mbps != *patched.Rdt.Mba.Mbps {
 					*w.Rdt.Mba.Mbps = *patched.Rdt.Mba.Mbps
-					mbaValue = *patched.Rdt.Mba.Mbps
 					reEnforce = true
 				}
 			} else {
@@ -795,8 +1453,25 @@ func update(w, patched *wltypes.RDTWorkLoad) error {
 		}
 	}
 
+	// schedule is replaced (empty schedule removes it), window params are applied by scheduler
+	if patched.Schedule != nil {
+		if err := validateSchedule(patched.Schedule); err != nil {
+			return rmderror.NewAppError(http.StatusBadRequest, "Invalid schedule", err)
+		}
+		w.Schedule = patched.Schedule
+	}
+
 	if reEnforce == true {
-		if err := Release(w); err != nil {
+		// new params have to fit in quota of tenant (demotion is cleared below)
+		probe := *w
+		probe.Demotion = nil
+		if err := checkQuota(&probe); err != nil {
+			return rmderror.NewAppError(http.StatusBadRequest, "Failed to validate workload quota", err)
+		}
+
+		// new params are enforced as requested
+		w.Demotion = nil
+		if err := release(w); err != nil {
 			return rmderror.NewAppError(http.StatusInternalServerError, "Failed to release workload",
 				fmt.Errorf(""))
 		}
@@ -810,11 +1485,13 @@ func update(w, patched *wltypes.RDTWorkLoad) error {
 
 		w.Plugins = patched.Plugins
 
-		return Enforce(w)
+		if err := enforce(w); err != nil {
+			events.Publish(evtypes.WorkloadFailed, w, err.Error())
+			return err
+		}
+		return nil
 	}
 
-	l.Lock()
-	defer l.Unlock()
 	resaall := proxyclient.GetResAssociation(pqos.GetAvailableCLOSes())
 
 	if !reflect.DeepEqual(patched.CoreIDs, w.CoreIDs) ||
@@ -851,10 +1528,43 @@ func update(w, patched *wltypes.RDTWorkLoad) error {
 			return rmderror.NewAppError(http.StatusInternalServerError,
 				"Error to commit resource group for workload.", err)
 		}
+		// assign new tasks and cores to monitoring group
+		startMonitoring(w)
 	}
 	return nil
 }
 
+// patchUint32 copies patched param (if defined) into workload param
+// returns true if value changed
+func patchUint32(dst **uint32, patched *uint32) bool {
+	if patched == nil {
+		return false
+	}
+	if *dst != nil && **dst == *patched {
+		return false
+	}
+	v := *patched
+	*dst = &v
+	return true
+}
+
+// getL2IDs returns L2 cache ids used by tasks and cpus of workload
+func getL2IDs(taskids []string, cpubitmap string, cpunum int) ([]uint32, error) {
+	syscaches, err := cache.GetSysCaches(2)
+	if err != nil {
+		return nil, err
+	}
+	l2infos := &cache.Infos{Caches: make(map[uint32]cache.Info)}
+	for _, sc := range syscaches {
+		id, err := strconv.Atoi(sc.ID)
+		if err != nil {
+			continue
+		}
+		l2infos.Caches[uint32(id)] = cache.Info{ID: uint32(id), ShareCPUList: sc.SharedCPUList}
+	}
+	return getSocketIDs(taskids, cpubitmap, l2infos, cpunum), nil
+}
+
 func getSocketIDs(taskids []string, cpubitmap string, cacheinfos *cache.Infos, cpunum int) []uint32 {
 	var SocketIDs []uint32
 	cpubm, _ := libutil.NewBitmap(cpunum, cpubitmap)
@@ -920,70 +1630,64 @@ func populateEnforceRequest(req *wltypes.EnforceRequest, w *wltypes.RDTWorkLoad)
 	}
 
 	req.SocketIDs = getSocketIDs(w.TaskIDs, cpubitstr, cacheinfo, cpunum)
+	req.Priority = w.Priority
 
-	// if policy not defined in workload then use values from manually defined params
-	// (assuming RDTWorkLoad object has been validated before and only some safe-checks needed)
-	if len(w.Policy) == 0 {
-		if w.Rdt.Cache.Min != nil {
-			req.MinWays = *w.Rdt.Cache.Min
-		}
-		if w.Rdt.Cache.Max != nil {
-			req.MaxWays = *w.Rdt.Cache.Max
-		}
-		if w.Rdt.Cache.Min != nil && w.Rdt.Cache.Max != nil {
-			req.UseCache = true
-		}
-		// Check if MBA is available and enabled in the host
-		// MBA to be used only for Guaranteed Cache Request
-		if w.Rdt.Mba.Percentage != nil || w.Rdt.Mba.Mbps != nil {
-			if !isMbaSupported {
-				req.UseMba = false
-				log.Error("Mba is not supported in this machine")
-				return rmderror.NewAppError(http.StatusInternalServerError,
-					"MBA is not supported in this machine")
-			}
-			if flag, _ := proc.IsEnableMba(); !flag {
-				req.UseMba = false
-				log.Error("Mba is not enabled. Enable Mba")
-				return rmderror.NewAppError(http.StatusInternalServerError,
-					"Please enable MBA in resctrl fs")
-			}
-			if (w.Rdt.Cache.Min == nil && w.Rdt.Cache.Max == nil) ||
-				(req.UseCache && (*w.Rdt.Cache.Max == *w.Rdt.Cache.Min && *w.Rdt.Cache.Max > 0 ||
-					*w.Rdt.Cache.Max != *w.Rdt.Cache.Min && mbaValue == mbaMaxValue ||
-					*w.Rdt.Cache.Max == 0 && *w.Rdt.Cache.Min == 0 && mbaValue == mbaMaxValue)) {
-				req.UseMba = true
-			} else {
-				req.UseMba = false
-				log.Error("Mba can be used only guaranteed Cache Request")
-				return rmderror.NewAppError(http.StatusInternalServerError,
-					"MBA is only supported for Guarantee Cache Request")
-			}
-		}
-	} else {
-		policy, err := policy.GetDefaultPolicy(w.Policy)
-		if err != nil {
+	// policy params are resolved for platform and copied into workload during validation
+	// (workloads that were not validated yet are filled here)
+	if len(w.Policy) > 0 && w.Rdt.Cache.Max == nil && w.Rdt.Cache.Min == nil &&
+		w.Rdt.Mba.Percentage == nil && w.Rdt.Mba.Mbps == nil {
+		if err := fillWorkloadByPolicy(w); err != nil {
 			return rmderror.NewAppError(http.StatusInternalServerError,
 				"Could not find the Policy.", err)
 		}
+	}
 
-		maxWays, okMax := policy["cache"]["max"].(int64)
-		if !okMax {
-			log.Error("Max cache reading error - cache way assignment will be skipped")
-		} else {
-			req.MaxWays = uint32(maxWays)
+	// use values from workload params
+	// (assuming RDTWorkLoad object has been validated before and only some safe-checks needed)
+	if w.Rdt.Cache.Min != nil {
+		req.MinWays = *w.Rdt.Cache.Min
+	}
+	if w.Rdt.Cache.Max != nil {
+		req.MaxWays = *w.Rdt.Cache.Max
+	}
+	if w.Rdt.Cache.Min != nil && w.Rdt.Cache.Max != nil {
+		req.UseCache = true
+	}
+	if w.Rdt.Cache.Code != nil && w.Rdt.Cache.Data != nil {
+		req.CodeWays = *w.Rdt.Cache.Code
+		req.DataWays = *w.Rdt.Cache.Data
+	}
+	if w.Rdt.Cache.L2.Min != nil && w.Rdt.Cache.L2.Max != nil {
+		req.L2MinWays = *w.Rdt.Cache.L2.Min
+		req.L2MaxWays = *w.Rdt.Cache.L2.Max
+		req.UseL2Cache = true
+	}
+	// Check if MBA is available and enabled in the host
+	// MBA to be used only for Guaranteed Cache Request
+	if w.Rdt.Mba.Percentage != nil || w.Rdt.Mba.Mbps != nil {
+		if !isMbaSupported {
+			req.UseMba = false
+			log.Error("Mba is not supported in this machine")
+			return rmderror.NewAppError(http.StatusInternalServerError,
+				"MBA is not supported in this machine")
 		}
-
-		minWays, okMin := policy["cache"]["min"].(int64)
-		if !okMin {
-			log.Error("Min cache reading error - cache way assignment will be skipped")
+		if flag, _ := proc.IsEnableMba(); !flag {
+			req.UseMba = false
+			log.Error("Mba is not enabled. Enable Mba")
+			return rmderror.NewAppError(http.StatusInternalServerError,
+				"Please enable MBA in resctrl fs")
+		}
+		mbaValue := workloadMba(w)
+		if (w.Rdt.Cache.Min == nil && w.Rdt.Cache.Max == nil) ||
+			(req.UseCache && (*w.Rdt.Cache.Max == *w.Rdt.Cache.Min && *w.Rdt.Cache.Max > 0 ||
+				*w.Rdt.Cache.Max != *w.Rdt.Cache.Min && mbaValue == mbaMaxValue ||
+				*w.Rdt.Cache.Max == 0 && *w.Rdt.Cache.Min == 0 && mbaValue == mbaMaxValue)) {
+			req.UseMba = true
 		} else {
-			req.MinWays = uint32(minWays)
-		}
-
-		// use cache params only if both defined
-		if okMax && okMin {
-			req.UseCache = true
+			req.UseMba = false
+			log.Error("Mba can be used only guaranteed Cache Request")
+			return rmderror.NewAppError(http.StatusInternalServerError,
+				"MBA is only supported for Guarantee Cache Request")
 		}
 	}
 
@@ -1000,6 +1704,34 @@ func populateEnforceRequest(req *wltypes.EnforceRequest, w *wltypes.RDTWorkLoad)
 				"Bad cache ways request",
 				err)
 		}
+		if w.Demotion != nil {
+			demoteRequest(req, w.Demotion.Type)
+		}
+		// with CDP enabled kernel accepts only separate code and data masks
+		req.UseCDP = isCdpEnabled
+	}
+
+	if req.UseL2Cache {
+		if req.L2MinWays > req.L2MaxWays {
+			return rmderror.NewAppError(http.StatusBadRequest,
+				"Min L2 cache value cannot be greater than max L2 cache value")
+		}
+		var err error
+		req.L2Type, err = cache.GetCachePoolName(req.L2MaxWays, req.L2MinWays)
+		if err != nil {
+			return rmderror.NewAppError(http.StatusBadRequest,
+				"Bad L2 cache ways request",
+				err)
+		}
+		// COS type (shared or not) is defined by L2 request if there's no LLC request
+		if !req.UseCache {
+			req.Type = req.L2Type
+		}
+		req.L2IDs, err = getL2IDs(w.TaskIDs, cpubitstr, cpunum)
+		if err != nil {
+			return rmderror.NewAppError(http.StatusInternalServerError,
+				"Failed to get L2 cache ids", err)
+		}
 	}
 
 	return nil
@@ -1023,6 +1755,41 @@ func newResAss(r map[string]*libutil.Bitmap, level string) *resctrl.ResAssociati
 	return &newResAss
 }
 
+// newResAssForCDP replaces cache schemata of given level with separate code and data schemata
+// Code ways are taken from the high end and data ways from the low end of candidate,
+// so they overlap only if code + data ways is more than allocated ways.
+// If code/data ways are not defined (or on not used cache ids) both masks equal candidate.
+func newResAssForCDP(resAss *resctrl.ResAssociation, candidate map[string]*libutil.Bitmap,
+	er *wltypes.EnforceRequest, level string) *resctrl.ResAssociation {
+
+	targetLev := "L" + level
+	delete(resAss.CacheSchemata, targetLev)
+
+	for k, v := range candidate {
+		cacheID, _ := strconv.Atoi(k)
+		code, data := v, v
+		if er.CodeWays > 0 && er.DataWays > 0 && inCacheList(uint32(cacheID), er.SocketIDs) {
+			ways := uint32(strings.Count(v.ToBinString(), "1"))
+			code = v.GetConnectiveBits(minUint32(er.CodeWays, ways), 0, false)
+			data = v.GetConnectiveBits(minUint32(er.DataWays, ways), 0, true)
+		}
+		resAss.CacheSchemata[targetLev+"CODE"] = append(resAss.CacheSchemata[targetLev+"CODE"],
+			resctrl.CacheCos{ID: uint8(cacheID), Mask: code.ToString()})
+		resAss.CacheSchemata[targetLev+"DATA"] = append(resAss.CacheSchemata[targetLev+"DATA"],
+			resctrl.CacheCos{ID: uint8(cacheID), Mask: data.ToString()})
+
+		log.Debugf("Newly created code/data Masks for Cache %s are %s/%s", k, code.ToString(), data.ToString())
+	}
+	return resAss
+}
+
+func minUint32(a, b uint32) uint32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
 func newResAssForMba(resAss *resctrl.ResAssociation, candidate map[string]*uint32, targetMba string) *resctrl.ResAssociation {
 	if resAss == nil {
 		resAss = &resctrl.ResAssociation{}
@@ -1039,6 +1806,7 @@ func newResAssForMba(resAss *resctrl.ResAssociation, candidate map[string]*uint3
 // shrinkBEPool requres to provide cacheid of the request, MinCache ways (
 // because we lack cache now if we need to shrink), of cause resassociations
 // besteffort pool reserved cache way bitmap.
+// Only workloads with priority not higher than maxPriority are shrunk.
 // returns: bitmap we allocated for the new request
 // returns: a map[string]*resctrl.ResAssociation as we changed other workloads'
 // cache ways, need to reflect them into resctrl fs.
@@ -1046,7 +1814,8 @@ func newResAssForMba(resAss *resctrl.ResAssociation, candidate map[string]*uint3
 func shrinkBEPool(resaall map[string]*resctrl.ResAssociation,
 	reservedSchemata *libutil.Bitmap,
 	cacheID int,
-	reqways uint32) (*libutil.Bitmap, map[string]*resctrl.ResAssociation, error) {
+	reqways uint32,
+	maxPriority int) (*libutil.Bitmap, map[string]*resctrl.ResAssociation, error) {
 
 	besteffortRes := make(map[string]*resctrl.ResAssociation)
 	dbc, _ := db.NewDB()
@@ -1055,7 +1824,6 @@ func shrinkBEPool(resaall map[string]*resctrl.ResAssociation,
 	targetLev := strconv.FormatUint(uint64(cache.GetLLC()), 10)
 	for name, v := range resaall {
 		if strings.HasSuffix(name, "-"+cache.Besteffort) {
-			besteffortRes[name] = v
 			ws, _ := dbc.QueryWorkload(map[string]interface{}{
 				"CosName": name})
 			if len(ws) == 0 {
@@ -1063,12 +1831,23 @@ func shrinkBEPool(resaall map[string]*resctrl.ResAssociation,
 					"Internal error, can not find exsting workload for resource group name %s", name)
 			}
 			cosSchemata, _ := cache.BitmapsCacheWrapper(v.CacheSchemata["L"+targetLev][cacheID].Mask)
+			// ways of workloads with higher priority are left untouched
+			if ws[0].Priority > maxPriority {
+				availableSchemata = availableSchemata.Axor(cosSchemata)
+				continue
+			}
+			besteffortRes[name] = v
 			// TODO: need find a better way to reduce the cache way fragments
 			// as currently we are using map to keep resctrl group, it's non-order
 			// so it's little hard to get which resctrl group next to which.
 			// just using max - min slot to shrink the cache. Hence, the result
 			// would only shrink one of the resource group to min one
-			minSchemata := cosSchemata.GetConnectiveBits(*ws[0].Rdt.Cache.Min, 0, false)
+			minWays := *ws[0].Rdt.Cache.Min
+			// demoted workloads are kept in besteffort pool with 1 way
+			if ws[0].Demotion != nil {
+				minWays = 1
+			}
+			minSchemata := cosSchemata.GetConnectiveBits(minWays, 0, false)
 			availableSchemata = availableSchemata.Axor(minSchemata)
 		}
 	}
@@ -1106,6 +1885,16 @@ func GetByUUID(uuid string) (result wltypes.RDTWorkLoad, err error) {
 
 //Delete function deletes workload from data base
 func Delete(wl *wltypes.RDTWorkLoad) error {
+	if err := deleteWorkload(wl, evtypes.WorkloadDeleted, ""); err != nil {
+		return err
+	}
+	// released resources may be enough for demoted workloads
+	restoreDemoted()
+	return nil
+}
+
+// deleteWorkload deletes workload from data base and publishes event of given type
+func deleteWorkload(wl *wltypes.RDTWorkLoad, eventType, message string) error {
 	if workloadDatabase == nil {
 		return rmderror.NewAppError(http.StatusInternalServerError, "Service database not initialized")
 	}
@@ -1113,6 +1902,7 @@ func Delete(wl *wltypes.RDTWorkLoad) error {
 	if err != nil {
 		return rmderror.NewAppError(rmderror.InternalServer, "Failed to remove workload from database", err)
 	}
+	events.Publish(eventType, wl, message)
 	return nil
 }
 
@@ -1125,6 +1915,7 @@ func Create(wl *wltypes.RDTWorkLoad) error {
 	if err != nil {
 		return rmderror.NewAppError(rmderror.InternalServer, "Failed to create workload in database", err)
 	}
+	events.Publish(evtypes.WorkloadCreated, wl, "")
 	return nil
 }
 
@@ -1141,6 +1932,18 @@ func GetAll() ([]wltypes.RDTWorkLoad, error) {
 	return ws, nil
 }
 
+// List gets page of workloads matching options and ID to continue from
+func List(opts db.ListOptions) ([]wltypes.RDTWorkLoad, string, error) {
+	if workloadDatabase == nil {
+		return nil, "", rmderror.NewAppError(http.StatusInternalServerError, "Service database not initialized")
+	}
+	ws, next, err := workloadDatabase.ListWorkloads(opts)
+	if err != nil {
+		return nil, "", rmderror.NewAppError(http.StatusInternalServerError, err.Error())
+	}
+	return ws, next, nil
+}
+
 //GetWorkloadByID function gets workload from data base by ID
 func GetWorkloadByID(id string) (result wltypes.RDTWorkLoad, err error) {
 	if workloadDatabase == nil {
@@ -1174,6 +1977,7 @@ func updateInDB(w *wltypes.RDTWorkLoad) error {
 	if err := workloadDatabase.UpdateWorkload(w); err != nil {
 		return rmderror.NewAppError(rmderror.InternalServer, "Failed to update workload in database", err)
 	}
+	events.Publish(evtypes.WorkloadUpdated, w, "")
 
 	return nil
 }
@@ -1195,17 +1999,45 @@ func Validate(w *wltypes.RDTWorkLoad) error {
 }
 
 // Update a workload
-func Update(w, patched *wltypes.RDTWorkLoad) error {
+func Update(w *wltypes.RDTWorkLoad, patch *wltypes.WorkLoadPatch) error {
+	patched := &patch.RDTWorkLoad
 
 	dbContentValidation()
 
-	err := update(w, patched)
-	if err != nil {
-		log.Error("Failed to update/patch workload")
-		return err
+	l.Lock()
+	defer l.Unlock()
+
+	// params used outside of active window are restored when window ends
+	if changesScheduledParams(w, patched) {
+		return rmderror.AppErrorf(http.StatusConflict,
+			"Params of workload are set by schedule window %d until it ends, change schedule instead",
+			w.ScheduleState.Window)
+	}
+
+	// every patch extends lease of workload
+	if err := renewLease(w, patched, time.Now()); err != nil {
+		return rmderror.NewAppError(http.StatusBadRequest, "Invalid lease", err)
+	}
+
+	// labels and annotations are merged without re-enforcing workload
+	if err := patchMetadata(w, patched); err != nil {
+		return rmderror.NewAppError(http.StatusBadRequest, "Invalid labels", err)
+	}
+
+	// priority is used by next preemption, current allocation is kept
+	if patch.Priority != nil {
+		w.Priority = *patch.Priority
+	}
+	priorityOnly := patch.Priority != nil && !hasWorkloadParams(patched)
+
+	if !priorityOnly && !isHeartbeat(w, patched) && !isMetadataPatch(patched) {
+		if err := update(w, patched); err != nil {
+			log.Error("Failed to update/patch workload")
+			return err
+		}
 	}
 
-	err = updateInDB(w)
+	err := updateInDB(w)
 	if err != nil {
 		log.Error("Failed to update/patch workload in database")
 		return err
@@ -1214,6 +2046,156 @@ func Update(w, patched *wltypes.RDTWorkLoad) error {
 	return nil
 }
 
+// workloadsUsingPolicy returns ids of workloads using given policy
+func workloadsUsingPolicy(name string) []string {
+	ids := []string{}
+	if workloadDatabase == nil {
+		return ids
+	}
+	ws, err := workloadDatabase.GetAllWorkload()
+	if err != nil {
+		log.Errorf("Failed to get workloads from database: %v", err)
+		return ids
+	}
+	for _, w := range ws {
+		if w.Policy == name {
+			ids = append(ids, w.ID)
+		}
+	}
+	return ids
+}
+
+// ReapplyPolicy re-enforces all workloads using given policy with current
+// policy params. It returns ids of re-enforced workloads and failure reason
+// for each workload that could not be re-enforced.
+// Workloads are read and re-enforced under the workload lock, so concurrent
+// updates and removals do not interleave with re-enforcement.
+func ReapplyPolicy(name string) ([]string, map[string]string) {
+	reapplied := []string{}
+	failed := map[string]string{}
+	if workloadDatabase == nil {
+		return reapplied, failed
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	ws, err := workloadDatabase.GetAllWorkload()
+	if err != nil {
+		log.Errorf("Failed to get workloads from database: %v", err)
+		return reapplied, failed
+	}
+	for i := range ws {
+		w := &ws[i]
+		if w.Policy != name {
+			continue
+		}
+		if err := reapplyPolicy(w); err != nil {
+			log.Errorf("Failed to re-apply policy %s to workload %s: %v", name, w.ID, err)
+			failed[w.ID] = err.Error()
+			continue
+		}
+		reapplied = append(reapplied, w.ID)
+	}
+	return reapplied, failed
+}
+
+// reapplyPolicy re-enforces workload with current params of its policy,
+// caller has to hold the workload lock
+func reapplyPolicy(w *wltypes.RDTWorkLoad) error {
+	// params of current policy version are checked before workload is released
+	probe := *w
+	probe.Rdt = wltypes.RDTWorkLoad{}.Rdt
+	probe.Plugins = nil
+	if err := fillWorkloadByPolicy(&probe); err != nil {
+		return rmderror.NewAppError(http.StatusBadRequest, "Failed to fill workload by policy", err)
+	}
+	if err := checkQuota(&probe); err != nil {
+		return rmderror.NewAppError(http.StatusBadRequest, "Failed to validate workload quota", err)
+	}
+
+	if err := release(w); err != nil {
+		return rmderror.NewAppError(http.StatusInternalServerError, "Failed to release workload", err)
+	}
+
+	// previous params are enforced again if policy cannot be re-applied
+	prev := *w
+
+	w.Rdt = probe.Rdt
+	w.Plugins = probe.Plugins
+	w.BackendPluginInfo = nil
+	w.CosName = ""
+	err := enforce(w)
+	if err == nil {
+		return updateInDB(w)
+	}
+	events.Publish(evtypes.WorkloadFailed, w, err.Error())
+	restorePolicyParams(w, &prev)
+	return err
+}
+
+// restorePolicyParams enforces params workload had before re-applying policy failed,
+// caller has to hold the workload lock.
+// Database is updated in any case so it shows if workload still has resources (Failed status if not)
+func restorePolicyParams(w, prev *wltypes.RDTWorkLoad) {
+	w.Rdt = prev.Rdt
+	w.Plugins = prev.Plugins
+	w.BackendPluginInfo = nil
+	w.CosName = ""
+	if err := enforce(w); err != nil {
+		events.Publish(evtypes.WorkloadFailed, w, err.Error())
+		log.Errorf("Failed to enforce previous params of workload %s: %v", w.ID, err)
+	}
+	if err := updateInDB(w); err != nil {
+		log.Errorf("Failed to update workload %s in database: %v", w.ID, err)
+	}
+}
+
+// policyParams returns params of workload that are set by policy
+// (JSON form so numbers of different types can be compared)
+func policyParams(w *wltypes.RDTWorkLoad) string {
+	params := struct {
+		Rdt     interface{}
+		Plugins map[string]map[string]interface{}
+	}{w.Rdt, w.Plugins}
+	data, _ := json.Marshal(params)
+	return string(data)
+}
+
+// reportPolicyDrift reports workloads which params differ from current
+// definition of their policy (after policy was changed)
+func reportPolicyDrift(names []string) {
+	if workloadDatabase == nil {
+		return
+	}
+	ws, err := workloadDatabase.GetAllWorkload()
+	if err != nil {
+		log.Errorf("Failed to get workloads from database: %v", err)
+		return
+	}
+	changed := map[string]bool{}
+	for _, name := range names {
+		changed[name] = true
+	}
+	for i := range ws {
+		w := &ws[i]
+		if !changed[w.Policy] {
+			continue
+		}
+		expected := wltypes.RDTWorkLoad{Policy: w.Policy}
+		message := ""
+		if err := fillWorkloadByPolicy(&expected); err != nil {
+			message = fmt.Sprintf("Policy %s of workload is not valid anymore: %v", w.Policy, err)
+		} else if policyParams(&expected) != policyParams(w) {
+			message = fmt.Sprintf("Workload params differ from changed policy %s", w.Policy)
+		}
+		if message != "" {
+			log.Warningf("Workload %s: %s", w.ID, message)
+			events.Publish(evtypes.WorkloadPolicyDrift, w, message)
+		}
+	}
+}
+
 // Init responsible for database creation
 // this function should be exported to give possibility to use DB
 // for example by Openstack without need of registering workload module
@@ -1223,8 +2205,13 @@ func Init() error {
 		log.Error("Cannot create database")
 	} else {
 		workloadDatabase = temp
+		policy.Database = temp
 		go startDBContentValidation()
+		go startScheduler()
 	}
+	policy.ReapplyHook = ReapplyPolicy
+	policy.UsersHook = workloadsUsingPolicy
+	policy.DriftHook = reportPolicyDrift
 	// CLOS pool has to be initialized before it can be used
 	if err := pqos.InitCLOSPool(); err != nil {
 		log.Errorf("Failed to initialize CLOS pool: %v", err.Error())
@@ -1238,6 +2225,14 @@ func Init() error {
 	if err != nil {
 		return err
 	}
+	isCqmSupported, err = proc.IsCqmAvailable()
+	if err != nil {
+		return err
+	}
+	isL2CATSupported = cache.IsL2CatEnabled()
+	isCdpEnabled = proc.IsEnableCdp()
+	// rebalancer needs to know if cache monitoring is supported
+	go startRebalancer()
 	// Additional check for MBA mode (configured vs. used in workloads in db) needed due to 2 MBA modes and PQOS usage
 	// NOTE TODO: In future it will be good to validate param of each plugin (including RDT) used in stored workloads
 	// - get all stored workloads
@@ -1302,56 +2297,28 @@ func Init() error {
 		} else {
 			mbaMaxValue = cache.MaxMBAPercentage
 		}
+		rdtc := cacheconf.RDTConfig{}
+		if err := viper.UnmarshalKey("rdt", &rdtc); err == nil {
+			mbaPeakMbps = rdtc.MBAPeakMbps
+			if workloadDatabase != nil {
+				go startThrottler(rdtc)
+			}
+		}
 	}
+	// check if workloads from database are still enforced (ex. after RMD restart)
+	startupReconcile()
 	return err
 }
 
 // prepareCoreIDs is responsible for preparting coreIDs
 func prepareCoreIDs(w []string) ([]int, error) {
-	coreids := []int{}
-
-	for _, value := range w {
-
-		// code to handle cases like "12-16" which should return "12 13 14 15 16"
-		dashPosition := strings.Index(value, "-")
-		if dashPosition != (-1) {
-			// '-' exists
-			beforeDashStr := strings.TrimSpace(value[:dashPosition])
-			afterDashStr := strings.TrimSpace(value[dashPosition+1:])
-
-			beforeDash, err := strconv.Atoi(beforeDashStr)
-			if err != nil {
-				log.Errorf("Failed to convert coreID value %v from string to int", beforeDashStr)
-				return coreids, err
-			}
-
-			afterDash, err := strconv.Atoi(afterDashStr)
-			if err != nil {
-				log.Errorf("Failed to convert coreID value %v from string to int", afterDashStr)
-				return coreids, err
-			}
-			// syntax like "8-3" is wrong so need additional check here
-			if beforeDash > afterDash {
-				log.Errorf("Wrong syntax for coreIDs -> %s", value)
-				return coreids, fmt.Errorf("Wrong syntax for coreIDs")
-			}
-
-			i := beforeDash
-			for i <= afterDash {
-				coreids = append(coreids, i)
-				i++
-			}
-		} else {
-			intid, err := strconv.Atoi(strings.TrimSpace(value))
-			if err != nil {
-				log.Errorf("Invalid core id %s - cannot continue", value)
-				return coreids, fmt.Errorf("Invalid core id in array: %s", value)
-			}
-			coreids = append(coreids, intid)
-		}
+	// handles cases like "12-16" which should return "12 13 14 15 16"
+	bm, err := libutil.NewBitmap(w)
+	if err != nil {
+		log.Errorf("Invalid core ids %v - cannot continue", w)
+		return []int{}, err
 	}
-
-	return coreids, nil
+	return bm.ToList(), nil
 }
 
 // shouldRemoveWorkload checks if all processes for workload exists
@@ -1378,6 +2345,7 @@ func startDBContentValidation() {
 	}
 	for {
 		dbContentValidation()
+		expireWorkloads(time.Now())
 		time.Sleep(time.Duration(interval) * time.Second)
 	}
 }
@@ -1391,12 +2359,23 @@ func dbContentValidation() {
 	ws, err := GetAll()
 	if err == nil {
 		for _, singleWorkload := range ws {
+			// tasks of cgroup based workloads are taken from the cgroup
+			if len(singleWorkload.CgroupPath) != 0 {
+				syncCgroupTasks(&singleWorkload)
+				continue
+			}
+			// selector based workloads are kept even if no process matches now
+			if singleWorkload.TaskSelector != nil {
+				syncSelectorTasks(&singleWorkload)
+				continue
+			}
 			// validation only for workloads/policies related with taskID/processID
 			if len(singleWorkload.TaskIDs) != 0 {
 				// remove from DB workloads which are related with not existing
 				// any more tasks/processes in the system (remove when all tasks doesn't exist)
 				if shouldRemoveWorkload(&singleWorkload) {
-					err := Delete(&singleWorkload)
+					stopMonitoring(&singleWorkload)
+					err := deleteWorkload(&singleWorkload, evtypes.WorkloadRemoved, "Workload tasks do not exist anymore")
 					if err != nil {
 						// just log here
 						log.Errorf("dbContentValidation failed to delete invalid workload from db: %s", err)
@@ -1410,3 +2389,118 @@ func dbContentValidation() {
 		log.Errorf("dbContentValidation failed to validate DB for outdated workloads")
 	}
 }
+
+// syncCgroupTasks moves tasks spawned in cgroup of workload after enforcement
+// to workload COS. Workload is removed from DB if its cgroup does not exist anymore
+func syncCgroupTasks(w *wltypes.RDTWorkLoad) {
+	tasks, err := proc.GetCgroupTasks(w.CgroupPath)
+	if err != nil {
+		stopMonitoring(w)
+		if err := deleteWorkload(w, evtypes.WorkloadRemoved, "Workload cgroup removed"); err != nil {
+			// just log here
+			log.Errorf("dbContentValidation failed to delete invalid workload from db: %s", err)
+		}
+		log.Infof("Workload %v deleted by DBValidator (cgroup removed)", w)
+		return
+	}
+	syncTasks(w, tasks)
+}
+
+// syncSelectorTasks moves processes started after enforcement and matching
+// task selector of workload to its resource group
+func syncSelectorTasks(w *wltypes.RDTWorkLoad) {
+	tasks, err := selectTasks(w.TaskSelector)
+	if err != nil {
+		log.Errorf("dbContentValidation failed to select tasks of workload %s: %v", w.ID, err)
+		return
+	}
+	syncTasks(w, tasks)
+}
+
+// syncTasks updates workload tasks to given list, new tasks are added to
+// resource and monitoring groups of workload
+func syncTasks(w *wltypes.RDTWorkLoad, tasks []string) {
+	newTasks := newCgroupTasks(w.TaskIDs, tasks)
+	if len(newTasks) == 0 && len(tasks) == len(w.TaskIDs) {
+		// nothing changed
+		return
+	}
+	if len(newTasks) > 0 && len(w.CosName) > 0 {
+		res, ok := proxyclient.GetResAssociation(pqos.GetAvailableCLOSes())[w.CosName]
+		if !ok {
+			log.Errorf("dbContentValidation failed to find resource group %s of workload %s", w.CosName, w.ID)
+			return
+		}
+		res.Tasks = newTasks
+		if err := proxyclient.Commit(res, w.CosName); err != nil {
+			log.Errorf("dbContentValidation failed to add new tasks of workload %s: %v", w.ID, err)
+			return
+		}
+		log.Infof("Tasks %v added to workload %s", newTasks, w.ID)
+	}
+
+	w.TaskIDs = tasks
+	if len(newTasks) > 0 {
+		// add new tasks to monitoring group too
+		startMonitoring(w)
+	}
+	if err := updateInDB(w); err != nil {
+		log.Errorf("dbContentValidation failed to update workload in db: %s", err)
+	}
+}
+
+// newCgroupTasks returns tasks of cgroup (or selector) that are not in workload yet
+func newCgroupTasks(workloadTasks, cgroupTasks []string) []string {
+	known := make(map[string]bool, len(workloadTasks))
+	for _, t := range workloadTasks {
+		known[t] = true
+	}
+	result := []string{}
+	for _, t := range cgroupTasks {
+		if !known[t] {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// selectTasks returns PIDs of all processes matching task selector
+func selectTasks(sel *wltypes.TaskSelector) ([]string, error) {
+	if len(sel.Comm) == 0 && len(sel.Cmdline) == 0 && len(sel.Unit) == 0 {
+		return nil, fmt.Errorf("Empty task selector")
+	}
+	var commGlob glob.Glob
+	var cmdlineRe *regexp.Regexp
+	var err error
+	if len(sel.Comm) > 0 {
+		if commGlob, err = glob.Compile(sel.Comm); err != nil {
+			return nil, fmt.Errorf("Invalid comm pattern %s: %v", sel.Comm, err)
+		}
+	}
+	if len(sel.Cmdline) > 0 {
+		if cmdlineRe, err = regexp.Compile(sel.Cmdline); err != nil {
+			return nil, fmt.Errorf("Invalid cmdline pattern %s: %v", sel.Cmdline, err)
+		}
+	}
+
+	// never select RMD processes
+	self := map[string]bool{strconv.Itoa(os.Getpid()): true, strconv.Itoa(os.Getppid()): true}
+	tasks := []string{}
+	for pid, p := range proc.ListProcesses() {
+		if self[pid] {
+			continue
+		}
+		if commGlob != nil && !commGlob.Match(p.Comm) {
+			continue
+		}
+		if cmdlineRe != nil && !cmdlineRe.MatchString(p.CmdLine) {
+			continue
+		}
+		if len(sel.Unit) > 0 && proc.GetProcessUnit(pid) != sel.Unit {
+			continue
+		}
+		tasks = append(tasks, pid)
+	}
+	sort.Strings(tasks)
+	return tasks, nil
+}
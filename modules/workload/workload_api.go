@@ -1,11 +1,13 @@
 package workload
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/emicklei/go-restful"
 	rmderror "github.com/intel/rmd/internal/error"
 	wltypes "github.com/intel/rmd/modules/workload/types"
+	"github.com/intel/rmd/utils/acl"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -29,10 +31,26 @@ func Register(prefix string, container *restful.Container) {
 
 	ws.Route(ws.GET("/").To(Get).
 		Doc("Get all work loads").
+		Param(ws.QueryParameter("selector", "label selector (ex. app=db,tier!=batch)").DataType("string")).
+		Param(ws.QueryParameter("status", "workload status").DataType("string")).
+		Param(ws.QueryParameter("origin", "workload origin (ex. REST)").DataType("string")).
+		Param(ws.QueryParameter("policy", "workload policy").DataType("string")).
+		Param(ws.QueryParameter("core_id", "core used by workload").DataType("string")).
+		Param(ws.QueryParameter("task_id", "task of workload").DataType("string")).
+		Param(ws.QueryParameter("sort", "order of workloads: id (default), status, policy or origin, prefixed with - for descending order").DataType("string")).
+		Param(ws.QueryParameter("limit", "max number of returned workloads").DataType("integer")).
+		Param(ws.QueryParameter("continue", "token of next page returned in X-Continue header").DataType("string")).
+		Param(ws.QueryParameter("fields", "comma separated workload fields to return (ex. status,cos_name)").DataType("string")).
 		Operation("WorkLoadGet"))
 
+	ws.Route(ws.DELETE("/").To(DeleteBySelector).
+		Doc("Delete all work loads matching label selector").
+		Param(ws.QueryParameter("selector", "label selector (ex. app=db,tier!=batch)").DataType("string")).
+		Operation("WorkLoadDeleteBySelector"))
+
 	ws.Route(ws.POST("/").To(NewWorkload).
 		Doc("Create new work load").
+		Param(ws.QueryParameter("dry_run", "only calculate allocation without creating workload").DataType("boolean")).
 		Operation("WorkLoadNew"))
 
 	ws.Route(ws.GET("/{id:[0-9]*}").To(GetByID).
@@ -40,6 +58,11 @@ func Register(prefix string, container *restful.Container) {
 		Param(ws.PathParameter("id", "id").DataType("string")).
 		Operation("WorkLoadGetById"))
 
+	ws.Route(ws.GET("/{id:[0-9]*}/metrics").To(GetMetricsByID).
+		Doc("Get monitoring data (cache occupancy and memory bandwidth) of workload by id").
+		Param(ws.PathParameter("id", "id").DataType("string")).
+		Operation("WorkLoadGetMetricsById"))
+
 	ws.Route(ws.PATCH("/{id:[0-9]*}").To(Patch).
 		Doc("Patch workload by id").
 		Param(ws.PathParameter("id", "id").DataType("string")).
@@ -54,8 +77,33 @@ func Register(prefix string, container *restful.Container) {
 }
 
 // Get handles GET /v1/workloads
+// sample GET request: /v1/workloads?policy=gold&sort=-id&limit=100&fields=status,cos_name
 func Get(request *restful.Request, response *restful.Response) {
-	ws, err := GetAll()
+	sel, err := parseSelector(request.QueryParameter("selector"))
+	if err != nil {
+		response.AddHeader("Content-Type", "text/plain")
+		response.WriteErrorString(http.StatusBadRequest, err.Error())
+		return
+	}
+	opts, err := parseListOptions(request.Request.URL.Query())
+	if err != nil {
+		response.AddHeader("Content-Type", "text/plain")
+		response.WriteErrorString(http.StatusBadRequest, err.Error())
+		return
+	}
+	fields, err := parseFields(request.QueryParameter("fields"))
+	if err != nil {
+		response.AddHeader("Content-Type", "text/plain")
+		response.WriteErrorString(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tenant, admin := acl.GetTenant(request)
+	opts.Filter.Match = func(w *wltypes.RDTWorkLoad) bool {
+		// workloads of other tenants are not visible
+		return isVisible(w, tenant, admin) && sel.matches(w.Labels)
+	}
+	ws, next, err := List(opts)
 	if err != nil {
 		response.WriteErrorString(http.StatusInternalServerError, err.Error())
 		return
@@ -63,22 +111,22 @@ func Get(request *restful.Request, response *restful.Response) {
 
 	// create general table for workloads with no backend data for User
 	userws := []wltypes.UserRDTWorkLoad{}
-
-	for _, singleWorkload := range ws {
-		wl := wltypes.UserRDTWorkLoad{}
-		wl.ID = singleWorkload.ID
-		wl.CoreIDs = singleWorkload.CoreIDs
-		wl.TaskIDs = singleWorkload.TaskIDs
-		wl.Policy = singleWorkload.Policy
-		wl.Status = singleWorkload.Status
-		wl.CosName = singleWorkload.CosName
-		wl.Rdt = singleWorkload.Rdt
-		wl.Plugins = singleWorkload.Plugins
-		wl.UUID = singleWorkload.UUID
-		wl.Origin = singleWorkload.Origin
-		userws = append(userws, wl)
+	for i := range ws {
+		userws = append(userws, newUserWorkLoad(&ws[i]))
 	}
 
+	if len(next) > 0 {
+		response.AddHeader(ContinueHeader, continueToken(next))
+	}
+	if len(fields) > 0 {
+		selected, err := selectFields(userws, fields)
+		if err != nil {
+			response.WriteErrorString(http.StatusInternalServerError, err.Error())
+			return
+		}
+		response.WriteEntity(selected)
+		return
+	}
 	response.WriteEntity(userws)
 }
 
@@ -87,7 +135,8 @@ func GetByID(request *restful.Request, response *restful.Response) {
 	id := request.PathParameter("id")
 	log.Infof("Try to get workload by %s", id)
 	wl, err := GetWorkloadByID(id)
-	if len(wl.ID) == 0 {
+	tenant, admin := acl.GetTenant(request)
+	if len(wl.ID) == 0 || !isVisible(&wl, tenant, admin) {
 		response.AddHeader("Content-Type", "text/plain")
 		response.WriteErrorString(http.StatusNotFound, "404: Could not found workload")
 		return
@@ -98,21 +147,41 @@ func GetByID(request *restful.Request, response *restful.Response) {
 	}
 
 	// create workload structure with no backend data for User
-	userwl := wltypes.UserRDTWorkLoad{}
-	userwl.ID = wl.ID
-	userwl.CoreIDs = wl.CoreIDs
-	userwl.TaskIDs = wl.TaskIDs
-	userwl.Policy = wl.Policy
-	userwl.Status = wl.Status
-	userwl.CosName = wl.CosName
-	userwl.Rdt = wl.Rdt
-	userwl.Plugins = wl.Plugins
-	userwl.UUID = wl.UUID
-	userwl.Origin = wl.Origin
+	userwl := newUserWorkLoad(&wl)
 
 	response.WriteEntity(userwl)
 }
 
+// GetMetricsByID handle GET /v1/workloads/{id}/metrics
+func GetMetricsByID(request *restful.Request, response *restful.Response) {
+	id := request.PathParameter("id")
+	log.Infof("Try to get metrics of workload %s", id)
+	wl, err := GetWorkloadByID(id)
+	tenant, admin := acl.GetTenant(request)
+	if len(wl.ID) == 0 || !isVisible(&wl, tenant, admin) {
+		response.AddHeader("Content-Type", "text/plain")
+		response.WriteErrorString(http.StatusNotFound, "404: Could not found workload")
+		return
+	}
+	if err != nil {
+		response.WriteErrorString(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	metrics, err := GetMetrics(&wl)
+	if err != nil {
+		httpStatus := http.StatusInternalServerError
+		if appErr, ok := err.(*rmderror.AppError); ok && appErr.Code >= http.StatusBadRequest {
+			httpStatus = appErr.Code
+		}
+		response.AddHeader("Content-Type", "text/plain")
+		response.WriteErrorString(httpStatus, err.Error())
+		return
+	}
+
+	response.WriteEntity(metrics)
+}
+
 // NewWorkload handle POST /v1/workloads
 // sample POST request data
 // body : '{ "core_ids" : ["1","2"], "policy": "gold" }'
@@ -134,17 +203,8 @@ func NewWorkload(request *restful.Request, response *restful.Response) {
 	}
 
 	// create inner workload structure for all operations
-	wl := new(wltypes.RDTWorkLoad)
-	wl.ID = userWl.ID
-	wl.CoreIDs = userWl.CoreIDs
-	wl.TaskIDs = userWl.TaskIDs
-	wl.Policy = userWl.Policy
-	wl.Status = userWl.Status
-	wl.CosName = userWl.CosName
-	wl.Rdt = userWl.Rdt
-	wl.Plugins = userWl.Plugins
-	wl.UUID = userWl.UUID
-	wl.Origin = userWl.Origin
+	wl := newRDTWorkLoad(userWl)
+	setTenant(wl, request)
 
 	if err := Validate(wl); err != nil {
 		response.AddHeader("Content-Type", "text/plain")
@@ -153,28 +213,29 @@ func NewWorkload(request *restful.Request, response *restful.Response) {
 		return
 	}
 
-	e := Enforce(wl)
-	if e != nil {
-		response.AddHeader("Content-Type", "text/plain")
-		httpStatus := http.StatusInternalServerError
-		appErr, ok := e.(rmderror.AppError)
-		// Some thing wrong in user's request parameters. Delete the DB.
-		if ok && appErr.Code == http.StatusBadRequest {
-			err = Delete(wl)
-			if err != nil {
-				// just log because we already are here in error case
-				log.Errorf("Failed to delete workload from data base")
+	if request.QueryParameter("dry_run") == "true" {
+		result, e := DryRun(wl)
+		if e != nil {
+			response.AddHeader("Content-Type", "text/plain")
+			httpStatus := http.StatusInternalServerError
+			if appErr, ok := e.(*rmderror.AppError); ok && appErr.Code >= http.StatusBadRequest {
+				httpStatus = appErr.Code
 			}
-			httpStatus = http.StatusBadRequest
+			response.WriteErrorString(httpStatus, e.Error())
+			return
 		}
-		response.WriteErrorString(httpStatus, e.Error())
+		response.WriteHeaderAndEntity(http.StatusOK, result)
 		return
 	}
 
-	err = Create(wl)
-	if err != nil {
+	// quota is checked, workload enforced and stored under the workload lock
+	if e := Add(wl); e != nil {
 		response.AddHeader("Content-Type", "text/plain")
-		response.WriteErrorString(http.StatusInternalServerError, err.Error())
+		httpStatus := http.StatusInternalServerError
+		if appErr, ok := e.(*rmderror.AppError); ok && appErr.Code == http.StatusBadRequest {
+			httpStatus = http.StatusBadRequest
+		}
+		response.WriteErrorString(httpStatus, e.Error())
 		return
 	}
 
@@ -183,8 +244,14 @@ func NewWorkload(request *restful.Request, response *restful.Response) {
 	userWl.Status = wl.Status
 	userWl.CosName = wl.CosName
 	userWl.UUID = wl.UUID
+	userWl.Tenant = wl.Tenant
+	// tasks are resolved from cgroup or task selector if given
+	userWl.TaskIDs = wl.TaskIDs
 	// params below could change due to policy/manual params overwritting
 	userWl.Policy = wl.Policy
+	userWl.Demotion = wl.Demotion
+	userWl.Schedule = wl.Schedule
+	userWl.ExpiresAt = wl.ExpiresAt
 	userWl.Rdt = wl.Rdt
 	userWl.Plugins = wl.Plugins
 
@@ -195,7 +262,8 @@ func NewWorkload(request *restful.Request, response *restful.Response) {
 func Patch(request *restful.Request, response *restful.Response) {
 	id := request.PathParameter("id")
 	wl, err := GetWorkloadByID(id)
-	if len(wl.ID) == 0 || err != nil {
+	tenant, admin := acl.GetTenant(request)
+	if len(wl.ID) == 0 || err != nil || !isVisible(&wl, tenant, admin) {
 		response.AddHeader("Content-Type", "text/plain")
 		response.WriteErrorString(http.StatusNotFound, "404: Could not found workload")
 		return
@@ -204,32 +272,22 @@ func Patch(request *restful.Request, response *restful.Response) {
 	// workloads created by REST should be handled only by REST
 	if wl.Origin == "REST" {
 		log.Debug("Origin set as REST - Trying to modify workload...")
-		newwl := new(wltypes.RDTWorkLoad)
+		newwl := new(wltypes.WorkLoadPatch)
 		request.ReadEntity(&newwl)
 		newwl.ID = id
 		log.Infof("Try to patch a workload %v", newwl)
 
 		if err = Update(&wl, newwl); err != nil {
 			httpStatus := http.StatusInternalServerError
-			apperr, ok := err.(rmderror.AppError)
-			if ok && apperr.Code == rmderror.BadRequest {
-				httpStatus = http.StatusBadRequest
+			if appErr, ok := err.(*rmderror.AppError); ok && appErr.Code >= http.StatusBadRequest {
+				httpStatus = appErr.Code
 			}
+			response.AddHeader("Content-Type", "text/plain")
 			response.WriteErrorString(httpStatus, err.Error())
 			return
 		}
 
-		userWl := wltypes.UserRDTWorkLoad{}
-		userWl.ID = wl.ID
-		userWl.CoreIDs = wl.CoreIDs
-		userWl.TaskIDs = wl.TaskIDs
-		userWl.Policy = wl.Policy
-		userWl.Status = wl.Status
-		userWl.CosName = wl.CosName
-		userWl.Rdt = wl.Rdt
-		userWl.Plugins = wl.Plugins
-		userWl.UUID = wl.UUID
-		userWl.Origin = wl.Origin
+		userWl := newUserWorkLoad(&wl)
 
 		response.WriteEntity(userWl)
 	} else {
@@ -245,8 +303,9 @@ func DeleteByID(request *restful.Request, response *restful.Response) {
 	id := request.PathParameter("id")
 	log.Infof("Try to delete workload with id: %s", id)
 	wl, err := GetWorkloadByID(id)
+	tenant, admin := acl.GetTenant(request)
 
-	if len(wl.ID) == 0 {
+	if len(wl.ID) == 0 || !isVisible(&wl, tenant, admin) {
 		response.AddHeader("Content-Type", "text/plain")
 		response.WriteErrorString(http.StatusNotFound, "404: Could not found workload")
 		return
@@ -280,3 +339,209 @@ func DeleteByID(request *restful.Request, response *restful.Response) {
 		return
 	}
 }
+
+// DeleteBySelector handles DELETE /v1/workloads?selector=...
+// all matching REST workloads are deleted in a single batch
+func DeleteBySelector(request *restful.Request, response *restful.Response) {
+	sel, err := parseSelector(request.QueryParameter("selector"))
+	if err == nil && len(sel) == 0 {
+		err = fmt.Errorf("Selector is required to delete workloads")
+	}
+	if err != nil {
+		response.AddHeader("Content-Type", "text/plain")
+		response.WriteErrorString(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ws, err := GetAll()
+	if err != nil {
+		response.WriteErrorString(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ids := []string{}
+	tenant, admin := acl.GetTenant(request)
+	for i := range ws {
+		// workloads created by REST should be handled only by REST
+		if !isVisible(&ws[i], tenant, admin) || ws[i].Origin != "REST" || !sel.matches(ws[i].Labels) {
+			continue
+		}
+		ids = append(ids, ws[i].ID)
+	}
+	log.Infof("Try to delete %d workloads matching selector %s", len(ids), request.QueryParameter("selector"))
+
+	if len(ids) > 0 {
+		if err := Batch(nil, ids); err != nil {
+			httpStatus := http.StatusInternalServerError
+			if appErr, ok := err.(*rmderror.AppError); ok && appErr.Code >= http.StatusBadRequest {
+				httpStatus = appErr.Code
+			}
+			response.AddHeader("Content-Type", "text/plain")
+			response.WriteErrorString(httpStatus, err.Error())
+			return
+		}
+	}
+
+	response.WriteEntity(ids)
+}
+
+// isVisible checks if workload can be seen and modified by tenant of request
+func isVisible(wl *wltypes.RDTWorkLoad, tenant string, admin bool) bool {
+	return admin || wl.Tenant == tenant
+}
+
+// setTenant sets owner of new workload to tenant of request,
+// admins can create workloads of other tenants
+func setTenant(wl *wltypes.RDTWorkLoad, request *restful.Request) {
+	tenant, admin := acl.GetTenant(request)
+	if !admin || len(wl.Tenant) == 0 {
+		wl.Tenant = tenant
+	}
+}
+
+// newRDTWorkLoad creates inner workload structure from workload received from User
+func newRDTWorkLoad(userWl *wltypes.UserRDTWorkLoad) *wltypes.RDTWorkLoad {
+	wl := new(wltypes.RDTWorkLoad)
+	wl.ID = userWl.ID
+	wl.CoreIDs = userWl.CoreIDs
+	wl.TaskIDs = userWl.TaskIDs
+	wl.CgroupPath = userWl.CgroupPath
+	wl.TaskSelector = userWl.TaskSelector
+	wl.Policy = userWl.Policy
+	wl.Priority = userWl.Priority
+	wl.Schedule = userWl.Schedule
+	wl.TTL = userWl.TTL
+	wl.ExpiresAt = userWl.ExpiresAt
+	wl.Status = userWl.Status
+	wl.CosName = userWl.CosName
+	wl.Rdt = userWl.Rdt
+	wl.Plugins = userWl.Plugins
+	wl.UUID = userWl.UUID
+	wl.Origin = userWl.Origin
+	wl.Tenant = userWl.Tenant
+	wl.Labels = userWl.Labels
+	wl.Annotations = userWl.Annotations
+	return wl
+}
+
+// newUserWorkLoad creates workload returned to User (with no backend params)
+func newUserWorkLoad(wl *wltypes.RDTWorkLoad) wltypes.UserRDTWorkLoad {
+	userWl := wltypes.UserRDTWorkLoad{}
+	userWl.ID = wl.ID
+	userWl.CoreIDs = wl.CoreIDs
+	userWl.TaskIDs = wl.TaskIDs
+	userWl.CgroupPath = wl.CgroupPath
+	userWl.TaskSelector = wl.TaskSelector
+	userWl.Policy = wl.Policy
+	userWl.Priority = wl.Priority
+	userWl.Demotion = wl.Demotion
+	userWl.Adjustments = wl.Adjustments
+	userWl.Throttle = wl.Throttle
+	userWl.Schedule = wl.Schedule
+	userWl.ScheduleState = wl.ScheduleState
+	userWl.TTL = wl.TTL
+	userWl.ExpiresAt = wl.ExpiresAt
+	userWl.Status = wl.Status
+	userWl.CosName = wl.CosName
+	userWl.Rdt = wl.Rdt
+	userWl.Plugins = wl.Plugins
+	userWl.UUID = wl.UUID
+	userWl.Origin = wl.Origin
+	userWl.Tenant = wl.Tenant
+	userWl.Labels = wl.Labels
+	userWl.Annotations = wl.Annotations
+	return userWl
+}
+
+// RegisterBatch add handler for /v1/workloads:batch endpoint
+func RegisterBatch(prefix string, container *restful.Container) {
+	ws := new(restful.WebService)
+	ws.
+		Path(prefix + "workloads:batch").
+		Doc("Create and delete work loads as single unit").
+		Consumes(restful.MIME_JSON).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.POST("/").To(NewBatch).
+		Doc("Create and delete work loads, either all changes are done or none of them").
+		Operation("WorkLoadBatch"))
+
+	container.Add(ws)
+}
+
+// NewBatch handles POST /v1/workloads:batch
+// sample POST request data
+// body : '{ "create" : [{ "core_ids" : ["1"], "policy": "gold" }, { "task_ids" : ["123"], "policy": "silver" }],
+//           "delete" : ["3", "4"] }'
+func NewBatch(request *restful.Request, response *restful.Response) {
+	batch := wltypes.WorkLoadBatch{}
+	if err := request.ReadEntity(&batch); err != nil {
+		response.AddHeader("Content-Type", "text/plain")
+		response.WriteErrorString(http.StatusBadRequest, "Failed to read request correctly. Please check request syntax and data")
+		log.Errorf("Failed to read batch request due to: %v", err.Error())
+		return
+	}
+
+	created := make([]*wltypes.RDTWorkLoad, len(batch.Create))
+	for i := range batch.Create {
+		// set owner/origin for workload
+		batch.Create[i].Origin = "REST"
+		created[i] = newRDTWorkLoad(&batch.Create[i])
+		setTenant(created[i], request)
+	}
+	tenant, admin := acl.GetTenant(request)
+	for _, id := range batch.Delete {
+		// workloads of other tenants are not visible
+		if wl, err := GetWorkloadByID(id); err == nil && !isVisible(&wl, tenant, admin) {
+			response.AddHeader("Content-Type", "text/plain")
+			response.WriteErrorString(http.StatusNotFound, "404: Could not found workload "+id)
+			return
+		}
+	}
+	log.Infof("Try to create %d and delete %d workloads in batch", len(created), len(batch.Delete))
+
+	if err := Batch(created, batch.Delete); err != nil {
+		httpStatus := http.StatusInternalServerError
+		if appErr, ok := err.(*rmderror.AppError); ok && appErr.Code >= http.StatusBadRequest {
+			httpStatus = appErr.Code
+		}
+		response.AddHeader("Content-Type", "text/plain")
+		response.WriteErrorString(httpStatus, err.Error())
+		return
+	}
+
+	result := wltypes.WorkLoadBatchResult{Created: []wltypes.UserRDTWorkLoad{}, Deleted: batch.Delete}
+	if result.Deleted == nil {
+		result.Deleted = []string{}
+	}
+	for _, wl := range created {
+		result.Created = append(result.Created, newUserWorkLoad(wl))
+	}
+	response.WriteHeaderAndEntity(http.StatusCreated, result)
+}
+
+// RegisterReconcile add handler for /v1/reconcile endpoint
+func RegisterReconcile(prefix string, container *restful.Container) {
+	ws := new(restful.WebService)
+	ws.
+		Path(prefix + "reconcile").
+		Doc("Show difference between workloads in database and resctrl").
+		Consumes(restful.MIME_JSON).
+		Produces(restful.MIME_JSON)
+
+	ws.Route(ws.GET("/").To(GetReconcile).
+		Doc("Get startup reconciliation report and current difference between workloads and resctrl").
+		Operation("ReconcileGet"))
+
+	container.Add(ws)
+}
+
+// GetReconcile handles GET /v1/reconcile
+func GetReconcile(request *restful.Request, response *restful.Response) {
+	status, err := GetReconcileStatus()
+	if err != nil {
+		response.WriteErrorString(http.StatusInternalServerError, err.Error())
+		return
+	}
+	response.WriteEntity(status)
+}
@@ -1,12 +1,14 @@
 package workload
 
 import (
+	"fmt"
 	"os"
 	"reflect"
 	"testing"
 
 	"github.com/intel/rmd/modules/cache"
 	tw "github.com/intel/rmd/modules/workload/types"
+	libutil "github.com/intel/rmd/utils/bitmap"
 	"github.com/intel/rmd/utils/proc"
 	"github.com/intel/rmd/utils/resctrl"
 	. "github.com/prashantv/gostub"
@@ -241,6 +243,83 @@ func Test_fillWorkloadByPolicy(t *testing.T) {
 	}
 }
 
+func Test_resolveCacheWays(t *testing.T) {
+	// 11 ways of 2.5 MB each
+	stubs := Stub(&getLLCGeometry, func() (uint32, uint32, error) {
+		return 11, 2621440, nil
+	})
+	defer stubs.Reset()
+
+	tests := []struct {
+		name    string
+		params  map[string]interface{}
+		bound   string
+		want    uint32
+		wantErr bool
+	}{
+		{"ways", map[string]interface{}{"max": int64(4)}, "max", 4, false},
+		{"ways from json", map[string]interface{}{"max": float64(4)}, "max", 4, false},
+		{"half of cache", map[string]interface{}{"max_percent": 50}, "max", 6, false},
+		{"whole cache", map[string]interface{}{"max_percent": 100}, "max", 11, false},
+		{"small percentage", map[string]interface{}{"min_percent": 1}, "min", 1, false},
+		{"zero percentage", map[string]interface{}{"min_percent": 0}, "min", 0, false},
+		{"size rounded up", map[string]interface{}{"min_mb": 3}, "min", 2, false},
+		{"fractional size", map[string]interface{}{"min_mb": 2.5}, "min", 1, false},
+		{"size exceeds cache", map[string]interface{}{"max_mb": 30}, "max", 0, true},
+		{"percentage out of range", map[string]interface{}{"max_percent": 120}, "max", 0, true},
+		{"missing param", map[string]interface{}{"min": 2}, "max", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveCacheWays(tt.params, tt.bound)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("resolveCacheWays() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("resolveCacheWays() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_resolveMBA(t *testing.T) {
+	tests := []struct {
+		name    string
+		mbps    bool
+		peak    uint32
+		params  map[string]interface{}
+		want    uint32
+		wantErr bool
+	}{
+		{"percentage mode", false, 0, map[string]interface{}{"percentage": 50, "mbps": 5000}, 50, false},
+		{"mbps mode", true, 0, map[string]interface{}{"percentage": 50, "mbps": 5000}, 5000, false},
+		{"percentage converted to mbps", true, 20000, map[string]interface{}{"percentage": 30}, 6000, false},
+		{"full percentage in mbps mode", true, 0, map[string]interface{}{"percentage": 100}, cache.MaxMBAMbps, false},
+		{"mbps converted to percentage", false, 20000, map[string]interface{}{"mbps": int64(5000)}, 25, false},
+		{"mbps rounded up", false, 30000, map[string]interface{}{"mbps": float64(5000)}, 17, false},
+		{"mbps above peak", false, 20000, map[string]interface{}{"mbps": 40000}, 100, false},
+		{"no peak bandwidth for mbps mode", true, 0, map[string]interface{}{"percentage": 30}, 0, true},
+		{"no peak bandwidth for percentage mode", false, 0, map[string]interface{}{"mbps": 5000}, 0, true},
+		{"invalid percentage", false, 0, map[string]interface{}{"percentage": 0}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubs := Stub(&isMbaMbpsAvailable, tt.mbps).Stub(&mbaPeakMbps, tt.peak)
+			defer stubs.Reset()
+
+			got, err := resolveMBA(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("resolveMBA() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("resolveMBA() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func Test_inCacheList(t *testing.T) {
 
 	type args struct {
@@ -766,3 +845,298 @@ func Test_validate(t *testing.T) {
 		})
 	}
 }
+
+func TestGetMetrics(t *testing.T) {
+	monitored := tw.RDTWorkLoad{ID: "1", CosName: "COS1", MonGroup: "rmd_1"}
+	notMonitored := tw.RDTWorkLoad{ID: "2", CosName: "COS2"}
+
+	tests := []struct {
+		name      string
+		w         *tw.RDTWorkLoad
+		supported bool
+		wantErr   bool
+	}{
+		{"Monitoring not supported", &monitored, false, true},
+		{"Workload without monitoring group", &notMonitored, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubs := Stub(&isCqmSupported, tt.supported)
+			defer stubs.Reset()
+			got, err := GetMetrics(tt.w)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetMetrics() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got.ID != tt.w.ID || got.CosName != tt.w.CosName {
+				t.Errorf("GetMetrics() = %v, want workload %v", got, tt.w.ID)
+			}
+		})
+	}
+}
+
+func Test_validateL2Cache(t *testing.T) {
+	var zero, two, four uint32 = 0, 2, 4
+
+	newWorkload := func(max, min, l2max, l2min *uint32) *tw.RDTWorkLoad {
+		w := &tw.RDTWorkLoad{}
+		w.Rdt.Cache.Max = max
+		w.Rdt.Cache.Min = min
+		w.Rdt.Cache.L2.Max = l2max
+		w.Rdt.Cache.L2.Min = l2min
+		return w
+	}
+
+	tests := []struct {
+		name      string
+		w         *tw.RDTWorkLoad
+		supported bool
+		wantErr   bool
+	}{
+		{"No L2 params", newWorkload(&two, &two, nil, nil), false, false},
+		{"L2 not supported", newWorkload(nil, nil, &two, &two), false, true},
+		{"Only L2 max", newWorkload(nil, nil, &two, nil), true, true},
+		{"L2 guarantee", newWorkload(nil, nil, &two, &two), true, false},
+		{"L2 besteffort with LLC guarantee", newWorkload(&two, &two, &four, &two), true, false},
+		{"L2 min greater than max", newWorkload(nil, nil, &two, &four), true, true},
+		{"L2 shared with LLC guarantee", newWorkload(&two, &two, &zero, &zero), true, true},
+		{"L2 and LLC shared", newWorkload(&zero, &zero, &zero, &zero), true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubs := Stub(&isL2CATSupported, tt.supported)
+			defer stubs.Reset()
+			if err := validateL2Cache(tt.w); (err != nil) != tt.wantErr {
+				t.Errorf("validateL2Cache() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_maxConnectiveWays(t *testing.T) {
+	tests := []struct {
+		name    string
+		mask    string
+		minWays uint32
+		want    uint32
+	}{
+		{"All ways free", "ff", 2, 8},
+		{"Longest range selected", "f3", 2, 4},
+		{"Range shorter than min", "5", 2, 0},
+		{"No free ways", "0", 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bm, _ := libutil.NewBitmap(8, tt.mask)
+			if got := maxConnectiveWays(bm, tt.minWays); got != tt.want {
+				t.Errorf("maxConnectiveWays() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateCDP(t *testing.T) {
+	var zero, one, two, four uint32 = 0, 1, 2, 4
+
+	newWorkload := func(max, min, code, data *uint32) *tw.RDTWorkLoad {
+		w := &tw.RDTWorkLoad{}
+		w.Rdt.Cache.Max = max
+		w.Rdt.Cache.Min = min
+		w.Rdt.Cache.Code = code
+		w.Rdt.Cache.Data = data
+		return w
+	}
+
+	tests := []struct {
+		name    string
+		w       *tw.RDTWorkLoad
+		enabled bool
+		wantErr bool
+	}{
+		{"No code and data", newWorkload(&two, &two, nil, nil), false, false},
+		{"CDP not enabled", newWorkload(&two, &two, &one, &one), false, true},
+		{"Only code", newWorkload(&two, &two, &one, nil), true, true},
+		{"Without max and min", newWorkload(nil, nil, &one, &one), true, true},
+		{"Shared cache", newWorkload(&zero, &zero, &one, &one), true, true},
+		{"Code more than max", newWorkload(&two, &two, &four, &one), true, true},
+		{"Zero data", newWorkload(&two, &two, &one, &zero), true, true},
+		{"Correct guarantee", newWorkload(&two, &two, &one, &two), true, false},
+		{"Correct besteffort", newWorkload(&four, &two, &four, &two), true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubs := Stub(&isCdpEnabled, tt.enabled)
+			defer stubs.Reset()
+			if err := validateCDP(tt.w); (err != nil) != tt.wantErr {
+				t.Errorf("validateCDP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_newResAssForCDP(t *testing.T) {
+	cand0, _ := libutil.NewBitmap(11, "3f0")
+	cand1, _ := libutil.NewBitmap(11, "7ff")
+	candidate := map[string]*libutil.Bitmap{"0": cand0, "1": cand1}
+
+	tests := []struct {
+		name     string
+		er       *tw.EnforceRequest
+		wantCode map[uint8]string
+		wantData map[uint8]string
+	}{
+		{"Code and data not defined",
+			&tw.EnforceRequest{SocketIDs: []uint32{0}},
+			map[uint8]string{0: "3f0", 1: "7ff"},
+			map[uint8]string{0: "3f0", 1: "7ff"}},
+		{"Separate code and data",
+			&tw.EnforceRequest{SocketIDs: []uint32{0}, CodeWays: 2, DataWays: 4},
+			map[uint8]string{0: "300", 1: "7ff"},
+			map[uint8]string{0: "f0", 1: "7ff"}},
+		{"Overlapping code and data",
+			&tw.EnforceRequest{SocketIDs: []uint32{0}, CodeWays: 6, DataWays: 8},
+			map[uint8]string{0: "3f0", 1: "7ff"},
+			map[uint8]string{0: "3f0", 1: "7ff"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resAss := newResAss(candidate, "3")
+			resAss = newResAssForCDP(resAss, candidate, tt.er, "3")
+			if _, ok := resAss.CacheSchemata["L3"]; ok {
+				t.Errorf("newResAssForCDP() L3 schemata should be removed")
+			}
+			for _, c := range resAss.CacheSchemata["L3CODE"] {
+				if c.Mask != tt.wantCode[c.ID] {
+					t.Errorf("newResAssForCDP() code mask on %d = %v, want %v", c.ID, c.Mask, tt.wantCode[c.ID])
+				}
+			}
+			for _, c := range resAss.CacheSchemata["L3DATA"] {
+				if c.Mask != tt.wantData[c.ID] {
+					t.Errorf("newResAssForCDP() data mask on %d = %v, want %v", c.ID, c.Mask, tt.wantData[c.ID])
+				}
+			}
+		})
+	}
+}
+
+func Test_validateCgroupPath(t *testing.T) {
+	var two uint32 = 2
+
+	cgroups := map[string][]string{
+		"app":   {"1", "2"},
+		"empty": {},
+	}
+	getCgroupTasks := func(path string) ([]string, error) {
+		tasks, ok := cgroups[path]
+		if !ok {
+			return nil, fmt.Errorf("Cgroup %s not found", path)
+		}
+		return tasks, nil
+	}
+	listProcesses := func() map[string]proc.Process {
+		return map[string]proc.Process{"1": {Pid: 1}, "2": {Pid: 2}, "3": {Pid: 3}}
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		tasks     []string
+		wantTasks []string
+		wantErr   bool
+	}{
+		{"Tasks from cgroup", "app", nil, []string{"1", "2"}, false},
+		{"Task ids replaced by cgroup tasks", "app", []string{"3"}, []string{"1", "2"}, false},
+		{"Empty cgroup", "empty", nil, nil, true},
+		{"Not existing cgroup", "none", nil, nil, true},
+		{"No cgroup", "", []string{"3"}, []string{"3"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubs := Stub(&proc.GetCgroupTasks, getCgroupTasks)
+			stubs.Stub(&proc.ListProcesses, listProcesses)
+			stubs.Stub(&isL3CATSupported, true)
+			stubs.Stub(&isMbaSupported, false)
+			defer stubs.Reset()
+
+			w := &tw.RDTWorkLoad{CgroupPath: tt.path, TaskIDs: tt.tasks}
+			w.Rdt.Cache.Max = &two
+			w.Rdt.Cache.Min = &two
+			err := validate(w)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(w.TaskIDs, tt.wantTasks) {
+				t.Errorf("validate() task ids = %v, want %v", w.TaskIDs, tt.wantTasks)
+			}
+		})
+	}
+}
+
+func Test_newCgroupTasks(t *testing.T) {
+	tests := []struct {
+		name          string
+		workloadTasks []string
+		cgroupTasks   []string
+		want          []string
+	}{
+		{"No new tasks", []string{"1", "2"}, []string{"1", "2"}, []string{}},
+		{"New task spawned", []string{"1"}, []string{"1", "5"}, []string{"5"}},
+		{"Task exited", []string{"1", "2"}, []string{"2"}, []string{}},
+		{"All tasks new", []string{}, []string{"7", "8"}, []string{"7", "8"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newCgroupTasks(tt.workloadTasks, tt.cgroupTasks); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newCgroupTasks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_selectTasks(t *testing.T) {
+	listProcesses := func() map[string]proc.Process {
+		return map[string]proc.Process{
+			"10": {Pid: 10, Comm: "nginx", CmdLine: "nginx: master process /usr/sbin/nginx"},
+			"11": {Pid: 11, Comm: "nginx", CmdLine: "nginx: worker process"},
+			"20": {Pid: 20, Comm: "redis-server", CmdLine: "/usr/bin/redis-server 127.0.0.1:6379"},
+			"30": {Pid: 30, Comm: "bash", CmdLine: "bash"},
+		}
+	}
+	units := map[string]string{"10": "nginx.service", "11": "nginx.service", "20": "redis.service"}
+	getProcessUnit := func(pid string) string {
+		return units[pid]
+	}
+
+	tests := []struct {
+		name    string
+		sel     tw.TaskSelector
+		want    []string
+		wantErr bool
+	}{
+		{"Comm glob", tw.TaskSelector{Comm: "redis*"}, []string{"20"}, false},
+		{"Cmdline regex", tw.TaskSelector{Cmdline: "worker"}, []string{"11"}, false},
+		{"Systemd unit", tw.TaskSelector{Unit: "nginx.service"}, []string{"10", "11"}, false},
+		{"All criteria", tw.TaskSelector{Comm: "nginx", Cmdline: "^nginx: master", Unit: "nginx.service"}, []string{"10"}, false},
+		{"No match", tw.TaskSelector{Comm: "nginx", Unit: "redis.service"}, []string{}, false},
+		{"Invalid comm glob", tw.TaskSelector{Comm: "[nginx"}, nil, true},
+		{"Invalid cmdline regex", tw.TaskSelector{Cmdline: "(nginx"}, nil, true},
+		{"Empty selector", tw.TaskSelector{}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubs := Stub(&proc.ListProcesses, listProcesses)
+			stubs.Stub(&proc.GetProcessUnit, getProcessUnit)
+			defer stubs.Reset()
+
+			got, err := selectTasks(&tt.sel)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("selectTasks() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectTasks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -63,7 +63,8 @@ func NewEnforcer() (*Enforcer, error) {
 // Enforce does enforce based on request
 func (e *Enforcer) Enforce(request *restful.Request, sub string) bool {
 	allow := false
-	obj := VersionTrim.ReplaceAllString(path.Clean(request.Request.RequestURI), "/")
+	// query string is not a part of ACL object (URL.Path is already unescaped)
+	obj := VersionTrim.ReplaceAllString(path.Clean(request.Request.URL.Path), "/")
 	act := request.Request.Method
 	if e.url != nil {
 		allow = e.url.Enforce(sub, obj, act)
@@ -74,6 +75,41 @@ func (e *Enforcer) Enforce(request *restful.Request, sub string) bool {
 	return allow
 }
 
+// request attributes describing tenant of authorized request
+const (
+	tenantAttribute = "rmd.tenant"
+	adminAttribute  = "rmd.admin"
+)
+
+// SetTenant stores tenant of authorized request and whether it has admin rights
+func SetTenant(request *restful.Request, tenant string, admin bool) {
+	request.SetAttribute(tenantAttribute, tenant)
+	request.SetAttribute(adminAttribute, admin)
+}
+
+// GetTenant returns tenant of request and whether it has admin rights.
+// Requests without tenant set by authorization are anonymous and have no admin rights.
+func GetTenant(request *restful.Request) (string, bool) {
+	tenant, ok := request.Attribute(tenantAttribute).(string)
+	if !ok {
+		return "", false
+	}
+	admin, _ := request.Attribute(adminAttribute).(bool)
+	return tenant, admin
+}
+
+// IsAdminTenant checks if any of given names is configured as admin tenant
+func IsAdminTenant(names ...string) bool {
+	for _, admin := range config.NewACLConfig().AdminTenants {
+		for _, name := range names {
+			if strings.EqualFold(admin, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // GetAdminCerts Get all Admin certification files from a given path.
 func GetAdminCerts() ([]string, error) {
 	aclconf := config.NewACLConfig()
@@ -0,0 +1,72 @@
+package acl
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/casbin/casbin"
+	"github.com/emicklei/go-restful"
+)
+
+const urlACLPath = "../../etc/rmd/acl/url/"
+
+func TestEnforcer_Enforce(t *testing.T) {
+	e := &Enforcer{url: casbin.NewEnforcer(urlACLPath+"model.conf", urlACLPath+"policy.csv")}
+
+	tests := []struct {
+		name   string
+		sub    string
+		method string
+		uri    string
+		want   bool
+	}{
+		{"User lists workloads", "user", "GET", "/v1/workloads", true},
+		{"User cannot create workload", "user", "POST", "/v1/workloads", false},
+		{"Root creates workload", "root", "POST", "/v1/workloads", true},
+		{"Root dry run", "root", "POST", "/v1/workloads?dry_run=true", true},
+		{"Admin patches workload", "admin", "PATCH", "/v1/workloads/1", true},
+		{"User gets events since", "user", "GET", "/v1/events?since=10", true},
+		{"User gets policies of arch", "user", "GET", "/v1/policies?arch=broadwell", true},
+		{"Query does not change object", "user", "DELETE", "/v1/workloads/1?x=/../policies", false},
+		{"User cannot scrape metrics", "user", "GET", "/v1/metrics", false},
+		{"User gets reconcile report", "user", "GET", "/v1/reconcile", true},
+		{"Root creates batch", "root", "POST", "/v1/workloads:batch", true},
+		{"Root deletes by selector", "root", "DELETE", "/v1/workloads?selector=app%3Ddb", true},
+		{"User cannot delete by selector", "user", "DELETE", "/v1/workloads?selector=app%3Ddb", false},
+		{"User cannot create batch", "user", "POST", "/v1/workloads:batch", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := restful.NewRequest(httptest.NewRequest(tt.method, tt.uri, nil))
+			if got := e.Enforce(request, tt.sub); got != tt.want {
+				t.Errorf("Enforce(%s %s) = %v, want %v", tt.method, tt.uri, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetTenant(t *testing.T) {
+	tests := []struct {
+		name      string
+		set       bool
+		tenant    string
+		admin     bool
+		wantAdmin bool
+	}{
+		{"Anonymous", false, "", false, false},
+		{"Tenant", true, "team-a", false, false},
+		{"Admin tenant", true, "ops", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := restful.NewRequest(httptest.NewRequest("GET", "/v1/workloads", nil))
+			if tt.set {
+				SetTenant(request, tt.tenant, tt.admin)
+			}
+			tenant, admin := GetTenant(request)
+			if tenant != tt.tenant || admin != tt.wantAdmin {
+				t.Errorf("GetTenant() = %q, %v, want %q, %v", tenant, admin, tt.tenant, tt.wantAdmin)
+			}
+		})
+	}
+}
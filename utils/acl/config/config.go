@@ -29,6 +29,8 @@ type ACL struct {
 	AdminCert     string `toml:"admincert"`
 	UserCert      string `toml:"usercert"`
 	Authorization Author
+	// AdminTenants are tenants (users, certificate CNs or OUs) allowed to see and modify workloads of all tenants
+	AdminTenants []string `toml:"admintenants"`
 }
 
 var authmap = map[string]Author{
@@ -38,7 +40,7 @@ var authmap = map[string]Author{
 }
 
 var once sync.Once
-var acl = &ACL{"/etc/rmd/acl/", "url", "", "", Signature}
+var acl = &ACL{"/etc/rmd/acl/", "url", "", "", Signature, nil}
 
 // NewACLConfig create new ACL config
 func NewACLConfig() *ACL {
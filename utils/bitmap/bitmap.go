@@ -2,22 +2,45 @@ package bitmap
 
 import (
 	"fmt"
-	"regexp"
+	"math/bits"
 	"strconv"
 	"strings"
-	"unsafe"
 )
 
-// AllDatas what?
-var AllDatas = regexp.MustCompile(`(\d+)`)
+// wordSize is number of bits stored in a single word of Bitmap
+const wordSize = 64
 
-// Bitmap struct represents bit map
+// groupSize is number of bits in a comma separated group of hex (and binary)
+// string of bitmap, same as in cpumask and resctrl files
+const groupSize = 32
+
+// Bitmap struct represents bit map (set of CPUs, cache ways) of any length
 type Bitmap struct {
-	Len  int
-	Bits []int
+	// Len is number of bits in bitmap
+	Len int
+	// words keep bits of bitmap, bit 0 is the lowest bit of the first word
+	words []uint64
+}
+
+// span is a range of bits in list (human style) string, e.g. "2-6" or "^5"
+type span struct {
+	low     int
+	high    int
+	exclude bool
+}
+
+// newBitmap creates empty bitmap of given length
+func newBitmap(length int) *Bitmap {
+	if length < 0 {
+		length = 0
+	}
+	return &Bitmap{Len: length, words: make([]uint64, (length+wordSize-1)/wordSize)}
 }
 
-// NewBitmap create bit map from string, strings ...
+// NewBitmap create bit map from length (int) and value:
+// list of human style strings ([]string{"2-8,^3-4,^7,9", "56-87,^86"}) or hex string ("ff2fff,f1,ffffff0f").
+// If length is not given it is taken from the highest bit in list or from number of groups in hex string.
+// Bits of hex string beyond length are ignored, bits of list beyond length are reported as error.
 // We can add a wraper for NewBitmap
 // such as:
 // func NewCPUBitmap( value ...interface{}) (*Bitmap, error) {
@@ -25,632 +48,413 @@ type Bitmap struct {
 //     return NewBitmap(cpu_numbers, value)
 // }
 func NewBitmap(value ...interface{}) (*Bitmap, error) {
-	// FIXME, need to this code after refacor genBits.
-	lenHumanStyle := func(scope []string) int {
-		m := AllDatas.FindAllString(strings.Join(scope, ","), -1)
-		n := make([]int, len(m))
-		var err error
-
-		for i := 0; i < len(m); i++ {
-			n[i], err = strconv.Atoi(m[i])
-			if err != nil {
-				return -1
-			}
+	length := 0
+	for i, val := range value {
+		// Only support 2 parameters at present.
+		if i >= 2 {
+			break
+		}
+		if v, ok := val.(int); ok {
+			length = v
 		}
+	}
 
-		maxvalue := n[0]
-		for i := 0; i < len(n); i++ {
-			if maxvalue < n[i] {
-				maxvalue = n[i]
-			}
+	for _, val := range value {
+		switch v := val.(type) {
+		case []string:
+			return newBitmapFromList(length, v)
+		case string:
+			return newBitmapFromHex(length, v)
+		case int:
+			continue
+		default:
+			return newBitmap(length), fmt.Errorf("Unknown value type")
 		}
-		return maxvalue
 	}
-	b := new(Bitmap)
-	b.Len = 0
+	return newBitmap(length), nil
+}
+
+// NewBitmapFromWords creates bitmap of given length from 64 bit words (bit 0 is the lowest bit of the first word)
+func NewBitmapFromWords(length int, words []uint64) *Bitmap {
+	b := newBitmap(length)
+	copy(b.words, words)
+	b.trim()
+	return b
+}
 
-	if len(value) > 0 {
-		for i, val := range value {
-			// Only support 2 parameters at present.
-			if i >= 2 {
-				break
+func newBitmapFromList(length int, list []string) (*Bitmap, error) {
+	spans, err := parseList(list)
+	if err != nil {
+		return newBitmap(length), err
+	}
+	if length == 0 {
+		for _, s := range spans {
+			if s.high >= length {
+				length = s.high + 1
 			}
-			switch v := val.(type) {
-			case int:
-				b.Len = v
-				break
+		}
+	}
+
+	b := newBitmap(length)
+	for _, s := range spans {
+		if s.high >= b.Len {
+			err = fmt.Errorf("Bit %d is out of bitmap length %d", s.high, b.Len)
+			continue
+		}
+		for i := s.low; i <= s.high; i++ {
+			if s.exclude {
+				b.Clear(i)
+			} else {
+				b.Set(i)
 			}
 		}
-		for i, val := range value {
-			switch v := val.(type) {
-			case []string:
-				if b.Len == 0 {
+	}
+	return b, err
+}
 
-					b.Len = lenHumanStyle(v) + 1
-				}
+func newBitmapFromHex(length int, s string) (*Bitmap, error) {
+	groups, err := string2data(s)
+	if length == 0 {
+		// This is not accurate, for example:
+		// The 0x7ff should be 11 bits instead of 32
+		// But no harmful
+		length = len(groups) * groupSize
+	}
+	b := newBitmap(length)
+	for i, g := range groups {
+		pos := i * groupSize
+		if pos/wordSize < len(b.words) {
+			b.words[pos/wordSize] |= uint64(g) << uint(pos%wordSize)
+		}
+	}
+	b.trim()
+	return b, err
+}
 
-				bits, err := genBits(v, b.Len)
-				b.Bits = bits
-				return b, err
-			case string:
-				bits, err := genBitsFromHexString(v)
-				b.Bits = bits
-				if b.Len == 0 {
-					// This is not accurate, for example:
-					// The 0x7ff should be 11 bits instead of 32
-					// But no harmful
-					b.Len = len(bits) * 32
-				}
-				return b, err
-			case int:
-				if i >= 2 {
-					break
+// parseList parses human style strings with comma separated bits and ranges,
+// ranges starting with ^ are excluded
+func parseList(list []string) ([]span, error) {
+	spans := []span{}
+	for _, l := range list {
+		for _, item := range strings.Split(l, ",") {
+			item = strings.TrimSpace(item)
+			if len(item) == 0 {
+				continue
+			}
+			s := span{}
+			if strings.HasPrefix(item, "^") {
+				s.exclude = true
+				item = strings.TrimSpace(item[1:])
+			}
+			bounds := strings.SplitN(item, "-", 2)
+			low, err := strconv.ParseUint(strings.TrimSpace(bounds[0]), 10, 31)
+			if err != nil {
+				return spans, fmt.Errorf("wrong expression : %s", l)
+			}
+			high := low
+			if len(bounds) > 1 {
+				high, err = strconv.ParseUint(strings.TrimSpace(bounds[1]), 10, 31)
+				if err != nil || high < low {
+					return spans, fmt.Errorf("wrong expression : %s", l)
 				}
-			default:
-				return b, fmt.Errorf("Unknown value type")
 			}
+			s.low, s.high = int(low), int(high)
+			spans = append(spans, s)
 		}
-		b.Bits = genBitmap(b.Len)
-	} else {
-		b.Bits = genBitmap(b.Len)
 	}
-	return b, nil
+	return spans, nil
 }
 
-// Or does union
-func (b *Bitmap) Or(m *Bitmap) *Bitmap {
-	// FIXME  The follow code are same with and, any design pattern for it?
-	maxc := len(b.Bits)
-	minc := len(m.Bits)
-	maxl := b.Len
-	minl := m.Len
-	maxb := b
-	minb := m
-	if maxl < minl {
-		maxc, minc = minc, maxc
-		maxb, minb = minb, maxb
-		maxl, minl = minl, maxl
+// trim clears bits beyond length of bitmap
+func (b *Bitmap) trim() {
+	if rest := b.Len % wordSize; rest > 0 && len(b.words) > 0 {
+		b.words[len(b.words)-1] &= (1 << uint(rest)) - 1
 	}
+}
 
-	r, _ := NewBitmap(maxl)
-	copy(r.Bits, maxb.Bits)
-	for i := 0; i < minc; i++ {
-		r.Bits[i] = maxb.Bits[i] | minb.Bits[i]
+// word returns i-th word of bitmap (0 if bitmap is shorter)
+func (b *Bitmap) word(i int) uint64 {
+	if i < len(b.words) {
+		return b.words[i]
 	}
+	return 0
+}
 
-	return r
+// groups returns number of 32 bit groups in string of bitmap
+func (b *Bitmap) groups() int {
+	return (b.Len + groupSize - 1) / groupSize
 }
 
-// And does intersection
-func (b *Bitmap) And(m *Bitmap) *Bitmap {
-	// FIXME  The follow code are same with or, any design pattern for it?
-	maxc := len(b.Bits)
-	minc := len(m.Bits)
-	maxl := b.Len
-	minl := m.Len
-	maxb := b
-	minb := m
-	if maxl < minl {
-		maxc, minc = minc, maxc
-		maxb, minb = minb, maxb
-		maxl, minl = minl, maxl
-	}
+// group returns i-th 32 bit group of bitmap
+func (b *Bitmap) group(i int) uint32 {
+	pos := i * groupSize
+	return uint32(b.word(pos/wordSize) >> uint(pos%wordSize))
+}
 
-	r, _ := NewBitmap(minl)
-	for i := 0; i < minc; i++ {
-		r.Bits[i] = maxb.Bits[i] & minb.Bits[i]
+// combine returns bitmap of given length with words of b and m combined by op
+func combine(length int, b, m *Bitmap, op func(x, y uint64) uint64) *Bitmap {
+	r := newBitmap(length)
+	for i := range r.words {
+		r.words[i] = op(b.word(i), m.word(i))
 	}
-
+	r.trim()
 	return r
 }
 
+// Or does union
+func (b *Bitmap) Or(m *Bitmap) *Bitmap {
+	return combine(maxInt(b.Len, m.Len), b, m, func(x, y uint64) uint64 { return x | y })
+}
+
+// And does intersection
+func (b *Bitmap) And(m *Bitmap) *Bitmap {
+	return combine(minInt(b.Len, m.Len), b, m, func(x, y uint64) uint64 { return x & y })
+}
+
 // Xor does difference
 func (b *Bitmap) Xor(m *Bitmap) *Bitmap {
-	// FIXME  The follow code are same with or, any design pattern for it?
-	maxc := len(b.Bits)
-	minc := len(m.Bits)
-	maxl := b.Len
-	minl := m.Len
-	maxb := b
-	minb := m
-	if maxl < minl {
-		maxc, minc = minc, maxc
-		maxb, minb = minb, maxb
-		maxl, minl = minl, maxl
+	return combine(maxInt(b.Len, m.Len), b, m, func(x, y uint64) uint64 { return x ^ y })
+}
+
+// Axor does asymmetric difference (bits of b not set in m)
+func (b *Bitmap) Axor(m *Bitmap) *Bitmap {
+	return combine(b.Len, b, m, func(x, y uint64) uint64 { return x &^ y })
+}
+
+// IsSet checks if given bit is set
+func (b *Bitmap) IsSet(bit int) bool {
+	if bit < 0 || bit >= b.Len {
+		return false
 	}
+	return b.words[bit/wordSize]&(1<<uint(bit%wordSize)) != 0
+}
 
-	r, _ := NewBitmap(maxl)
-	copy(r.Bits, maxb.Bits)
-	for i := 0; i < minc; i++ {
-		r.Bits[i] = maxb.Bits[i] ^ minb.Bits[i]
+// Set sets given bit, bits out of bitmap are ignored
+func (b *Bitmap) Set(bit int) {
+	if bit < 0 || bit >= b.Len {
+		return
 	}
+	b.words[bit/wordSize] |= 1 << uint(bit%wordSize)
+}
 
-	return r
+// Clear clears given bit, bits out of bitmap are ignored
+func (b *Bitmap) Clear(bit int) {
+	if bit < 0 || bit >= b.Len {
+		return
+	}
+	b.words[bit/wordSize] &^= 1 << uint(bit%wordSize)
 }
 
-// Axor does asymmetric difference
-func (b *Bitmap) Axor(m *Bitmap) *Bitmap {
-	// FIXME  The follow code are same with or, any design pattern for it?
-	maxc := len(b.Bits)
-	minc := len(m.Bits)
-	maxl := b.Len
-	minl := m.Len
-	maxb := b
-	minb := m
-	if maxl < minl {
-		maxc, minc = minc, maxc
-		maxb, minb = minb, maxb
-		maxl, minl = minl, maxl
+// Count returns number of set bits
+func (b *Bitmap) Count() int {
+	n := 0
+	for _, w := range b.words {
+		n += bits.OnesCount64(w)
 	}
+	return n
+}
 
-	var r *Bitmap
-	if b.Len == maxl {
-		r, _ = NewBitmap(maxl)
-		copy(r.Bits, maxb.Bits)
-	} else {
-		r, _ = NewBitmap(minl)
+// Next returns position of the first set bit at or after given position,
+// -1 if there is no such bit
+func (b *Bitmap) Next(pos int) int {
+	if pos < 0 {
+		pos = 0
+	}
+	for i := pos / wordSize; i < len(b.words); i++ {
+		w := b.words[i]
+		if i == pos/wordSize {
+			w &^= (1 << uint(pos%wordSize)) - 1
+		}
+		if w != 0 {
+			return i*wordSize + bits.TrailingZeros64(w)
+		}
 	}
+	return -1
+}
 
-	for i := 0; i < minc; i++ {
-		r.Bits[i] = (maxb.Bits[i] ^ minb.Bits[i]) & b.Bits[i]
+// ToList returns positions of all set bits (e.g. CPU ids) in ascending order
+func (b *Bitmap) ToList() []int {
+	list := []int{}
+	for bit := b.Next(0); bit >= 0; bit = b.Next(bit + 1) {
+		list = append(list, bit)
 	}
+	return list
+}
 
-	return r
+// Words returns copy of 64 bit words of bitmap (bit 0 is the lowest bit of the first word)
+func (b *Bitmap) Words() []uint64 {
+	words := make([]uint64, len(b.words))
+	copy(words, b.words)
+	return words
 }
 
-// ToString returns hex string
+// ToString returns hex string, 32 bit groups are separated by comma
 func (b *Bitmap) ToString() string {
-	str := ""
-	l := len(b.Bits)
-	for i, v := range b.Bits {
-		s := ""
-		if i == l-1 {
-			// NOTE Should we limit the length by b.Len?
-			s = fmt.Sprintf("%x", v)
-		} else {
-			// FIXME Hard code 8.
-			s = fmt.Sprintf("%08x", v)
-		}
-		if i == 0 {
-			str = s
+	n := b.groups()
+	strs := make([]string, n)
+	for i := 0; i < n; i++ {
+		if i == n-1 {
+			strs[n-1-i] = fmt.Sprintf("%x", b.group(i))
 		} else {
-			str = s + "," + str
+			strs[n-1-i] = fmt.Sprintf("%08x", b.group(i))
 		}
 	}
-	return str
+	return strings.Join(strs, ",")
 }
 
-// ToBinString binary string
+// ToBinString binary string, 32 bit groups are separated by comma
 func (b *Bitmap) ToBinString() string {
-	// FIXME Hard code 32.
-	bs32 := fmt.Sprintf("%032d", 0)
-	ts := ""
-	for i, v := range b.Bits {
-		rb := strconv.FormatUint(uint64(v), 2)
-		l := len(rb)
-		end := 0
-		if 32 >= l {
-			end = 32 - l
-		}
-		if len(b.Bits) == i+1 {
-			if b.Len%32 >= l {
-				end = b.Len%32 - l
-			}
-		}
-		if 0 == i {
-			ts = (bs32[0:end] + rb)
-		} else {
-			ts = (bs32[0:end] + rb) + "," + ts
+	n := b.groups()
+	strs := make([]string, n)
+	for i := 0; i < n; i++ {
+		width := groupSize
+		if i == n-1 && b.Len%groupSize != 0 {
+			width = b.Len % groupSize
 		}
+		strs[n-1-i] = fmt.Sprintf("%0*b", width, b.group(i))
 	}
-	return ts
+	return strings.Join(strs, ",")
 }
 
-// ToBinStrings to binary string slice
+// ToBinStrings to binary string slice, each string is a run of same bits
+// starting from the lowest bit
 func (b *Bitmap) ToBinStrings() []string {
 	ss := []string{}
-	ts := strings.Replace(b.ToBinString(), ",", "", -1)
-	l := len(ts)
-	if l < 1 {
-		return []string{}
-	}
-	orgV := ts[l-1]
-	orgIndex := l
-	for i := b.Len - 1; i >= 0; i-- {
-		if ts[i] != orgV {
-			ss = append(ss, ts[i+1:orgIndex])
-			orgIndex = i + 1
-			orgV = ts[i]
-		}
-		if i == 0 {
-			ss = append(ss, ts[0:orgIndex])
+	for pos := 0; pos < b.Len; {
+		set := b.IsSet(pos)
+		end := pos + 1
+		for end < b.Len && b.IsSet(end) == set {
+			end++
+		}
+		if set {
+			ss = append(ss, strings.Repeat("1", end-pos))
+		} else {
+			ss = append(ss, strings.Repeat("0", end-pos))
 		}
+		pos = end
 	}
 	return ss
 }
 
-// ToHumanString returns human string of the bitmap, e.g. 1-2,10-11
+// ToHumanString returns human string (CPU list) of the bitmap, e.g. 1-2,10-11
 func (b *Bitmap) ToHumanString() string {
-	ts := b.ToBinStrings()
 	hs := []string{}
-	pos := 0
-	for _, v := range ts {
-		if v[0] == '1' {
-			if len(v) == 1 {
-				hs = append(hs, strconv.Itoa(pos))
-			} else {
-				hs = append(hs,
-					strconv.Itoa(pos)+"-"+strconv.Itoa(pos+len(v)-1))
-			}
+	for low := b.Next(0); low >= 0; {
+		high := low
+		for b.IsSet(high + 1) {
+			high++
 		}
-		pos += len(v)
-	}
-	if len(hs) == 0 {
-		hs = append(hs, "")
+		if low == high {
+			hs = append(hs, strconv.Itoa(low))
+		} else {
+			hs = append(hs, strconv.Itoa(low)+"-"+strconv.Itoa(high))
+		}
+		low = b.Next(high + 1)
 	}
 	return strings.Join(hs, ",")
 }
 
-// MaxConnectiveBits returns MaxConnectiveBits
+// MaxConnectiveBits returns the longest run of set bits (the lowest one if there are more)
 func (b *Bitmap) MaxConnectiveBits() *Bitmap {
-	ss := b.ToBinStrings()
-	totalLen := 0
-	maxI := 0
-	maxLen := 0
-	cur := 0
-	for i, v := range ss {
-		l := len(v)
-		if strings.Contains(v, "1") {
-			if maxLen < l {
-				maxLen = l
-				maxI = i
-				cur = totalLen
-			}
+	r := newBitmap(b.Len)
+	maxLow, maxLen := 0, 0
+	for low := b.Next(0); low >= 0; {
+		high := low
+		for b.IsSet(high + 1) {
+			high++
 		}
-		totalLen += l
+		if high-low+1 > maxLen {
+			maxLow, maxLen = low, high-low+1
+		}
+		low = b.Next(high + 1)
 	}
-
-	// Generate the new Bitmap
-	var r *Bitmap
-	scope := ""
-	if maxLen == 0 {
-		r, _ = NewBitmap(b.Len)
-		return r
-	} else if len(ss[maxI]) == 1 {
-		scope = fmt.Sprintf("%d", cur)
-	} else {
-		scope = fmt.Sprintf("%d-%d", cur, cur+len(ss[maxI])-1)
+	for i := maxLow; i < maxLow+maxLen; i++ {
+		r.Set(i)
 	}
-	r, _ = NewBitmap(b.Len, []string{scope})
 	return r
 }
 
-// GetConnectiveBits returns a connective bits for Bitmap by given ways, offset, and order
+// GetConnectiveBits returns a connective bits for Bitmap by given ways, offset, and order:
+// the first run of ways set bits skipping offset bits from the lowest (fromLow) or the highest bit
 func (b *Bitmap) GetConnectiveBits(ways, offset uint32, fromLow bool) *Bitmap {
-	ts := strings.Replace(b.ToBinString(), ",", "", -1)
-	var total uint32
-	var cur uint32
+	r := newBitmap(b.Len)
 
 	// early return
-	if offset+ways > uint32(len(ts)) {
-		r, _ := NewBitmap(b.Len)
+	if uint64(offset)+uint64(ways) > uint64(b.Len) {
 		return r
 	}
 
+	var total uint32
+	// last bit of found run
+	last := -1
+	step := -1
+	start := b.Len - 1 - int(offset)
 	if fromLow {
-		for i := b.Len - 1 - int(offset); i >= 0; i-- {
-			if ts[i] == '1' {
-				total++
-				if total >= ways {
-					cur = uint32(i)
-					break
-				}
-			} else {
-				total = 0
-			}
+		step = 1
+		start = int(offset)
+	}
+	for bit := start; bit >= 0 && bit < b.Len; bit += step {
+		if !b.IsSet(bit) {
+			total = 0
+			continue
 		}
-	} else {
-		// FIXME  duplicated code
-		for i := offset; i < uint32(b.Len); i++ {
-			if ts[i] == '1' {
-				total++
-				if total >= ways {
-					cur = i
-					break
-				}
-			} else {
-				total = 0
-			}
+		total++
+		if total >= ways {
+			last = bit
+			break
 		}
 	}
-
-	scope := strconv.Itoa(b.Len - 1 - int(cur))
-	if ways > 1 {
-		// Low
-		if fromLow {
-			scope = fmt.Sprintf("%d-%d", uint32(b.Len)-1-cur-(ways-1),
-				uint32(b.Len)-1-cur)
-		} else {
-			// High
-			scope = fmt.Sprintf("%d-%d", uint32(b.Len)-1-cur,
-				uint32(b.Len)-1-cur+(ways-1))
-		}
+	if last < 0 {
+		return r
 	}
-	if total < ways {
-		r, _ := NewBitmap(b.Len)
+
+	if ways <= 1 {
+		r.Set(last)
 		return r
 	}
-	r, _ := NewBitmap(b.Len, []string{scope})
+	for i := 0; i < int(ways); i++ {
+		r.Set(last - i*step)
+	}
 	return r
 }
 
 // IsEmpty returns empty bit map or not
 func (b *Bitmap) IsEmpty() bool {
-	if len(b.Bits) == 0 {
-		return true
-	}
-	r := b.Bits[0]
-	for i, v := range b.Bits {
-		if i > 0 {
-			r = r | v
+	for _, w := range b.words {
+		if w != 0 {
+			return false
 		}
 	}
-	if r == 0 {
-		return true
-	}
-	return false
+	return true
 }
 
-// Maximum returns the highest position of the bit map
+// Maximum returns the highest position of the bit map (number of bits up to the highest set bit)
 func (b *Bitmap) Maximum() uint32 {
-	l := len(b.Bits)
-
-	if len(b.Bits) == 0 {
-		return 0
-	}
-
-	// Find the highest bits
-	val := b.Bits[l-1]
-	max := 0
-	// Bad performance, refactor if possible.
-	for val > 0 {
-		val = val >> 1
-		max = max + 1
-	}
-
-	// each bit indicate 8 hex.
-	return uint32(max + 8*4*(l-1))
-}
-
-// BitMapBadExpression is the wrong expression
-var BitMapBadExpression = regexp.MustCompile(`([^\^\d-,]+)|([^\d]+-.*(,|$))|` +
-	`([^,]*-[^\d]+)|(\^[^\d]+)|((\,\s)?\^$)`)
-
-func sliceString2Int(s []string) ([]int, error) {
-	// 2^32 -1 = 4294967295
-	// len("4294967295") = 10
-	si := make([]int, len(s), len(s))
-	for i, v := range s {
-		ni, err := strconv.ParseInt(v, 10, 32)
-		si[i] = int(ni)
-		if err != nil {
-			return si, err
+	for i := len(b.words) - 1; i >= 0; i-- {
+		if b.words[i] != 0 {
+			return uint32(i*wordSize + bits.Len64(b.words[i]))
 		}
 	}
-	return si, nil
-}
-
-func genBitmap(num int, platform ...int) []int {
-	p := 32
-	if len(platform) > 0 {
-		p = platform[0]
-	}
-	n := (num + p - 1) / p
-	return make([]int, n, n)
-}
-
-// "2-6,^3-4,^5"
-func fillBitMap(bits int, scope string, platform ...int) (int, error) {
-	// "2-6"
-	hyphenSpan := func(scope string, platform ...int) (int, error) {
-		p := 32
-		if len(platform) > 0 {
-			p = platform[0]
-		}
-		scopes := strings.SplitN(scope, "-", 2)
-		low, err := strconv.Atoi(scopes[0])
-		if err != nil {
-			return 0, err
-		}
-		high, err := strconv.Atoi(scopes[1])
-		if err != nil {
-			return 0, err
-		}
-		low = low % p
-		high = high % p
-		// overflow
-		sv := ((1 << uint(high-low+1)) - 1) << uint(low)
-		return sv, nil
-	}
-
-	// "5"
-	singleBit := func(bit int) int {
-		// bit should less than than the platform bits
-		return 1 << uint(bit)
-	}
-
-	p := 32
-	if len(platform) > 0 {
-		p = platform[0]
-	}
-	scopes := strings.Split(scope, ",")
-	for i, v := range scopes {
-		// negative false, positive ture
-		sign := true
-		var err error
-		sv := 0
-		if strings.Contains(v, "^") {
-			sign = false
-			v = strings.TrimSpace(v)
-			v = strings.TrimLeft(v, "^")
-		}
-
-		if strings.Contains(v, "-") {
-			sv, err = hyphenSpan(v)
-			if err != nil {
-				// change it to log
-				fmt.Printf("the %d element is %s, can not be parser", i, scopes[i])
-				return bits, err
-			}
-		} else {
-			vi, err := strconv.Atoi(v)
-			if err != nil {
-				// change it to log
-				fmt.Printf("the %d element is %s, can not be parser", i, scopes[i])
-				return bits, err
-			}
-			vi = vi % p
-			sv = singleBit(vi)
-		}
-		switch sign {
-		case true:
-			bits = bits | sv
-		case false:
-			bits = bits &^ sv
-		}
-	}
-	return bits, nil
-}
-
-//{"2-8,^3-4,^7,9", "56-87,^86"}
-func genBits(mapList []string, bitLen int) ([]int, error) {
-	Bitmap := genBitmap(bitLen)
-	isSpan := func(span string) bool {
-		return strings.Contains(span, "-")
-	}
-
-	locate := func(pos int, platform ...int) int {
-		p := 32
-		if len(platform) > 0 {
-			p = platform[0]
-		}
-		return pos / p
-	}
-
-	spanPhypen2int := func(span string) (int, int, error) {
-		scopes := strings.SplitN(span, "-", 2)
-		low, err := strconv.Atoi(scopes[0])
-		if err != nil {
-			return 0, 0, err
-		}
-		high, err := strconv.Atoi(scopes[1])
-		if err != nil {
-			return low, 0, err
-		}
-		return low, high, nil
-	}
-
-	// a span maybe a cross span, need to split them into small span.
-	// but we must set the max length of span(step).
-	silitSpan := func(span string, steps ...int) ([]string, error) {
-		step := 32
-		if len(steps) > 0 {
-			step = steps[0]
-		}
-		sign := true
-		var err error
-		v := span
-		spans := []string{}
-		if !isSpan(span) {
-			return spans, nil
-		}
-		if strings.Contains(span, "^") {
-			sign = false
-			span = strings.TrimSpace(span)
-			v = strings.TrimLeft(span, "^")
-		}
-		low, high, err := spanPhypen2int(v)
-		if err != nil {
-			return spans, err
-		}
-		if high/step == low/step {
-			return append(spans, span), nil
-		}
-		for i := low / step; i <= high/step; i++ {
-			begin := low
-			end := high
-			if ((i+1)*step - 1) < high {
-				end = (i+1)*step - 1
-			}
-			if i > low/step {
-				begin = i * step
-			}
-			s := fmt.Sprintf("%d-%d", begin, end)
-			if !sign {
-				s = "^" + s
-			}
-			spans = append(spans, s)
-		}
-		return spans, err
-	}
-
-	if len(mapList) == 0 {
-		return Bitmap, nil
-	}
-
-	for _, v := range mapList {
-		// FIXME, remove to before ALL_DATAS?
-		m := BitMapBadExpression.FindAllString(v, -1)
-		if len(m) > 0 {
-			return Bitmap, fmt.Errorf("wrong expression : %s", v)
-		}
-		scopes := strings.Split(v, ",")
-		for _, v := range scopes {
-			// negative false, positive ture
-			if isSpan(v) {
-				spans, err := silitSpan(v)
-				if err != nil {
-					return Bitmap, err
-				}
-				for _, span := range spans {
-					span = strings.TrimSpace(span)
-					low, _, _ := spanPhypen2int(strings.TrimLeft(span, "^"))
-					pos := locate(low)
-					Bitmap[pos], _ = fillBitMap(Bitmap[pos], span)
-				}
-			} else {
-				i, err := strconv.Atoi(strings.TrimLeft(v, "^"))
-				if err != nil {
-					return Bitmap, err
-				}
-				pos := locate(i)
-				Bitmap[pos], _ = fillBitMap(Bitmap[pos], v)
-			}
-		}
-	}
-	return Bitmap, nil
+	return 0
 }
 
 // GenCPUResString {"2-8,^3-4,^7,9", "56-87,^86"}
 func GenCPUResString(mapList []string, bitLen int) (string, error) {
-	Bitmap, err := genBits(mapList, bitLen)
-	str := ""
+	b, err := newBitmapFromList(bitLen, mapList)
 	if err != nil {
-		return str, err
+		return "", err
 	}
-	for i, v := range Bitmap {
-		if i == 0 {
-			str = fmt.Sprintf("%x", v)
-		} else {
-			str = fmt.Sprintf("%x", v) + "," + str
-		}
+	n := b.groups()
+	strs := make([]string, n)
+	for i := 0; i < n; i++ {
+		strs[n-1-i] = fmt.Sprintf("%x", b.group(i))
 	}
-	return str, nil
+	return strings.Join(strs, ","), nil
 }
 
+// string2data parses hex string into 32 bit groups (the lowest group first)
 func string2data(s string) ([]uint, error) {
-	var dummy uint
-	intLen := int(unsafe.Sizeof(dummy))
+	hexLen := groupSize / 4
 	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
 	// a string with comma, such as "ff2fff,f1,ffffff0f"
 	if strings.Contains(s, ",") {
@@ -658,9 +462,9 @@ func string2data(s string) ([]uint, error) {
 		var l = len(ss)
 		datas := make([]uint, l)
 		for i, v := range ss {
-			if len(v) > intLen {
+			if len(v) > hexLen {
 				return datas, fmt.Errorf(
-					"String lenth > %d. I'm not so smart to guest the data type", intLen)
+					"String lenth > %d. I'm not so smart to guest the data type", hexLen)
 			}
 			if ui, err := strconv.ParseUint(v, 16, 32); err == nil {
 				datas[l-1-i] = uint(ui)
@@ -672,11 +476,11 @@ func string2data(s string) ([]uint, error) {
 	}
 	// else: a string without comma, such as "3df00cfff00ffafff"
 	var l = len(s)
-	n := (l - 1 + intLen) / intLen
+	n := (l - 1 + hexLen) / hexLen
 	datas := make([]uint, n)
 	for i := 0; i < n; i++ {
-		start := l - (i+1)*intLen
-		end := l - i*intLen
+		start := l - (i+1)*hexLen
+		end := l - i*hexLen
 		var ns = s[:end]
 		if start > 0 {
 			ns = s[start:end]
@@ -690,10 +494,16 @@ func string2data(s string) ([]uint, error) {
 	return datas, nil
 }
 
-// FIXME unify []int and []uint.
-func genBitsFromHexString(s string) ([]int, error) {
-	d, e := string2data(s)
-	sd := (*(*[]int)(unsafe.Pointer(&d)))[:]
-	return sd, e
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
 
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
 }
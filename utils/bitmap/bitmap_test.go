@@ -11,9 +11,9 @@ func TestNewBitmapUnion(t *testing.T) {
 	b, _ := NewBitmap(88, []string{"0-7,9-12,85-87"})
 	m, _ := NewBitmap([]string{"6-9"})
 	r := b.Or(m)
-	if r.Bits[0] != 0x1FFF || r.Bits[2] != 0xe00000 {
+	if int(r.group(0)) != 0x1FFF || int(r.group(2)) != 0xe00000 {
 		t.Errorf("The union should be : 0xE00000,00000000,00001FFF, now it is 0x%x,%08x,%08x",
-			r.Bits[2], r.Bits[1], r.Bits[0])
+			int(r.group(2)), int(r.group(1)), int(r.group(0)))
 	}
 }
 
@@ -21,18 +21,18 @@ func TestNewBitmap(t *testing.T) {
 	b, _ := NewBitmap(96, "3df00cfff00ffafff")
 	wants := []int{0xffafff, 0xdf00cfff, 0x3}
 	for i, v := range wants {
-		if v != b.Bits[i] {
+		if v != int(b.group(i)) {
 			t.Errorf("The bitmap of index %d should be: 0x%x, but it is: 0x%x",
-				i, v, b.Bits[i])
+				i, v, int(b.group(i)))
 		}
 	}
 
 	b, _ = NewBitmap("3df00cfff00ffafff")
 	wants = []int{0xffafff, 0xdf00cfff, 0x3}
 	for i, v := range wants {
-		if v != b.Bits[i] {
+		if v != int(b.group(i)) {
 			t.Errorf("The bitmap of index %d should be: 0x%x, but it is: 0x%x",
-				i, v, b.Bits[i])
+				i, v, int(b.group(i)))
 		}
 	}
 }
@@ -42,15 +42,15 @@ func TestNewBitmapIntersection(t *testing.T) {
 	b, _ := NewBitmap(88, []string{"0-7,9-12,32-50,85-87"})
 	m, _ := NewBitmap(minlen, []string{"6-9,32-48"})
 	r := b.And(m)
-	// r.Bits[0]
-	len := len(r.Bits)
+	// int(r.group(0))
+	len := r.groups()
 	if len != 2 {
 		t.Errorf("The length of intersection of bit maps should be %d, but get %d.",
 			minlen/32, len)
 	}
-	if r.Bits[0] != 0x2C0 || r.Bits[1] != 0x1FFFF {
+	if int(r.group(0)) != 0x2C0 || int(r.group(1)) != 0x1FFFF {
 		t.Errorf("The intersection should be : 0x00001FFFF,000002C0, now it is 0x%x,%08x",
-			r.Bits[1], r.Bits[0])
+			int(r.group(1)), int(r.group(0)))
 	}
 }
 
@@ -58,9 +58,9 @@ func TestNewBitmapDifference(t *testing.T) {
 	b, _ := NewBitmap(88, []string{"0-7,9-12,85-87"})
 	m, _ := NewBitmap(64, []string{"6-9"})
 	r := b.Xor(m)
-	if r.Bits[0] != 0x1d3f || r.Bits[2] != 0xe00000 {
+	if int(r.group(0)) != 0x1d3f || int(r.group(2)) != 0xe00000 {
 		t.Errorf("The difference should be : 0xE00000,00000000,00001d3f, now it is 0x%x,%08x,%08x",
-			r.Bits[2], r.Bits[1], r.Bits[0])
+			int(r.group(2)), int(r.group(1)), int(r.group(0)))
 	}
 }
 
@@ -69,20 +69,20 @@ func TestNewBitmapAsymmetricDiff(t *testing.T) {
 	b, _ := NewBitmap(88, []string{"0-7,9-12,85-87"})
 	m, _ := NewBitmap(minlen, []string{"6-9"})
 	r := b.Axor(m)
-	if r.Bits[0] != 0x1c3f || r.Bits[2] != 0xe00000 {
+	if int(r.group(0)) != 0x1c3f || int(r.group(2)) != 0xe00000 {
 		t.Errorf("The asymmetric difference should be : 0xE00000,00000000,00001c3f, now it is 0x%x,%08x,%08x",
-			r.Bits[2], r.Bits[1], r.Bits[0])
+			int(r.group(2)), int(r.group(1)), int(r.group(0)))
 	}
 
 	r = m.Axor(b)
-	len := len(r.Bits)
+	len := r.groups()
 	if len != 2 {
 		t.Errorf("The length of intersection of bit maps should be %d, but get %d.",
 			minlen/32, len)
 	}
-	if r.Bits[0] != 0x100 || r.Bits[1] != 0x0 {
+	if int(r.group(0)) != 0x100 || int(r.group(1)) != 0x0 {
 		t.Errorf("The asymmetric difference should be : 0x0,00000100, now it is 0x%x,%08x",
-			r.Bits[1], r.Bits[0])
+			int(r.group(1)), int(r.group(0)))
 	}
 }
 
@@ -129,16 +129,16 @@ func TestBitmapMaxConnectiveBits(t *testing.T) {
 	b, _ := NewBitmap(88, mapList)
 	r := b.MaxConnectiveBits()
 	want := 0x3FFFFC
-	if want != r.Bits[2] {
-		t.Errorf("The value should be '%d', but get '%d'", want, r.Bits[2])
+	if want != int(r.group(2)) {
+		t.Errorf("The value should be '%d', but get '%d'", want, int(r.group(2)))
 	}
 
 	mapList = []string{"1"}
 	b, _ = NewBitmap(24, mapList)
 	r = b.MaxConnectiveBits()
 	want = 0x2
-	if want != r.Bits[0] {
-		t.Errorf("The value should be '%d', but get '%d'", want, r.Bits[0])
+	if want != int(r.group(0)) {
+		t.Errorf("The value should be '%d', but get '%d'", want, int(r.group(0)))
 	}
 }
 
@@ -148,39 +148,39 @@ func TestBitmapGetConnectiveBits(t *testing.T) {
 	b, _ := NewBitmap(88, mapList)
 	r := b.GetConnectiveBits(10, 10, false)
 	want := 0x3FF0
-	if want != r.Bits[2] {
-		t.Errorf("The value should be '0x%x', but get '0x%x'", want, r.Bits[2])
+	if want != int(r.group(2)) {
+		t.Errorf("The value should be '0x%x', but get '0x%x'", want, int(r.group(2)))
 	}
 
 	r = b.GetConnectiveBits(3, 4, false)
 	want = 0xe0000
-	if want != r.Bits[2] {
-		t.Errorf("The value should be '0x%x', but get '0x%x'", want, r.Bits[2])
+	if want != int(r.group(2)) {
+		t.Errorf("The value should be '0x%x', but get '0x%x'", want, int(r.group(2)))
 	}
 
 	r = b.GetConnectiveBits(1, 3, false)
 	want = 0x100000
-	if want != r.Bits[2] {
-		t.Errorf("The value should be '0x%x', but get '0x%x'", want, r.Bits[2])
+	if want != int(r.group(2)) {
+		t.Errorf("The value should be '0x%x', but get '0x%x'", want, int(r.group(2)))
 	}
 
 	r = b.GetConnectiveBits(1, 0, false)
 	want = 0x800000
-	if want != r.Bits[2] {
-		t.Errorf("The value should be '0x%x', but get '0x%x'", want, r.Bits[2])
+	if want != int(r.group(2)) {
+		t.Errorf("The value should be '0x%x', but get '0x%x'", want, int(r.group(2)))
 	}
 
 	/********************* True **************************************/
 	r = b.GetConnectiveBits(2, 3, true)
 	want = 0x60
-	if want != r.Bits[0] {
-		t.Errorf("The value should be '%d', but get '%x'", want, r.Bits[0])
+	if want != int(r.group(0)) {
+		t.Errorf("The value should be '%d', but get '%x'", want, int(r.group(0)))
 	}
 
 	r = b.GetConnectiveBits(1, 3, true)
 	want = 0x20
-	if want != r.Bits[0] {
-		t.Errorf("The value should be '%d', but get '%x'", want, r.Bits[0])
+	if want != int(r.group(0)) {
+		t.Errorf("The value should be '%d', but get '%x'", want, int(r.group(0)))
 	}
 }
 
@@ -467,3 +467,135 @@ func TestBitMapToHumanString(t *testing.T) {
 		t.Errorf("Humman string for `` should be ``")
 	}
 }
+
+// Below cases are for hosts with many CPUs
+func TestBitmapLarge(t *testing.T) {
+	tests := []struct {
+		name      string
+		list      []string
+		wantCount int
+		wantHuman string
+		wantMax   uint32
+	}{
+		{"224 CPUs", []string{"0-223"}, 224, "0-223", 224},
+		{"2 sockets", []string{"0-55,112-167", "56-111,^100-110"}, 157, "0-99,111-167", 168},
+		{"1024 CPUs", []string{"0-1023,^64-127"}, 960, "0-63,128-1023", 1024},
+		{"Last CPU", []string{"1023"}, 1, "1023", 1024},
+		{"Word bounds", []string{"63-64,127-128,191"}, 5, "63-64,127-128,191", 192},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := NewBitmap(1024, tt.list)
+			if err != nil {
+				t.Fatalf("NewBitmap() error = %v", err)
+			}
+			if got := b.Count(); got != tt.wantCount {
+				t.Errorf("Count() = %d, want %d", got, tt.wantCount)
+			}
+			if got := b.ToHumanString(); got != tt.wantHuman {
+				t.Errorf("ToHumanString() = %s, want %s", got, tt.wantHuman)
+			}
+			if got := b.Maximum(); got != tt.wantMax {
+				t.Errorf("Maximum() = %d, want %d", got, tt.wantMax)
+			}
+			if got := len(b.ToList()); got != tt.wantCount {
+				t.Errorf("len(ToList()) = %d, want %d", got, tt.wantCount)
+			}
+
+			// hex string round trip
+			h, err := NewBitmap(1024, b.ToString())
+			if err != nil {
+				t.Fatalf("NewBitmap(%s) error = %v", b.ToString(), err)
+			}
+			if h.ToHumanString() != tt.wantHuman {
+				t.Errorf("Hex round trip = %s, want %s", h.ToHumanString(), tt.wantHuman)
+			}
+			if got := len(strings.Split(b.ToString(), ",")); got != 32 {
+				t.Errorf("ToString() has %d groups, want 32", got)
+			}
+		})
+	}
+}
+
+func TestBitmapLargeOperations(t *testing.T) {
+	all, _ := NewBitmap(1024, []string{"0-1023"})
+	socket0, _ := NewBitmap(1024, []string{"0-55,112-167"})
+	used, _ := NewBitmap([]string{"100-120,900"})
+
+	if got := all.Axor(socket0).And(used).ToHumanString(); got != "100-111,900" {
+		t.Errorf("Axor/And = %s, want 100-111,900", got)
+	}
+	if got := socket0.Or(used).ToHumanString(); got != "0-55,100-167,900" {
+		t.Errorf("Or = %s, want 0-55,100-167,900", got)
+	}
+	if got := socket0.And(used).Len; got != 901 {
+		t.Errorf("And length = %d, want 901", got)
+	}
+	if got := socket0.MaxConnectiveBits().ToHumanString(); got != "0-55" {
+		t.Errorf("MaxConnectiveBits() = %s, want 0-55", got)
+	}
+	if got := socket0.GetConnectiveBits(4, 60, true).ToHumanString(); got != "112-115" {
+		t.Errorf("GetConnectiveBits() = %s, want 112-115", got)
+	}
+}
+
+func TestBitmapNext(t *testing.T) {
+	b, _ := NewBitmap(1024, []string{"5,64,1000"})
+	want := []int{5, 64, 1000}
+	got := []int{}
+	for bit := b.Next(0); bit >= 0; bit = b.Next(bit + 1) {
+		got = append(got, bit)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) || fmt.Sprint(b.ToList()) != fmt.Sprint(want) {
+		t.Errorf("Iteration = %v, ToList() = %v, want %v", got, b.ToList(), want)
+	}
+	if b.Next(1001) != -1 {
+		t.Errorf("Next(1001) = %d, want -1", b.Next(1001))
+	}
+
+	b.Clear(64)
+	b.Set(65)
+	b.Set(2000)
+	if !b.IsSet(65) || b.IsSet(64) || b.IsSet(2000) {
+		t.Errorf("Set/Clear failed: %s", b.ToHumanString())
+	}
+}
+
+func TestBitmapWords(t *testing.T) {
+	words := make([]uint64, 16)
+	words[0] = 0x3
+	words[15] = 1 << 63
+	b := NewBitmapFromWords(16*64, words)
+	if got := b.ToHumanString(); got != "0-1,1023" {
+		t.Errorf("NewBitmapFromWords() = %s, want 0-1,1023", got)
+	}
+	if got := b.Words(); fmt.Sprint(got) != fmt.Sprint(words) {
+		t.Errorf("Words() = %v, want %v", got, words)
+	}
+
+	// bits beyond length are dropped
+	b = NewBitmapFromWords(100, words)
+	if got := b.ToHumanString(); got != "0-1" {
+		t.Errorf("NewBitmapFromWords() = %s, want 0-1", got)
+	}
+}
+
+func TestNewBitmapWrongList(t *testing.T) {
+	tests := []struct {
+		name string
+		len  int
+		list []string
+	}{
+		{"Reversed range", 0, []string{"9-4"}},
+		{"Not a number", 0, []string{"1,a"}},
+		{"Negative", 0, []string{"-1"}},
+		{"Out of length", 88, []string{"0-88"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewBitmap(tt.len, tt.list); err == nil {
+				t.Errorf("NewBitmap(%v) should fail", tt.list)
+			}
+		})
+	}
+}
@@ -40,6 +40,7 @@ type Default struct {
 	UnixSock            string `toml:"unixsock"`
 	PolicyPath          string `toml:"policypath"`
 	OpenStackEnable     bool   `toml:"openstackenable"`
+	KubernetesEnable    bool   `toml:"kubernetesenable"`
 	SysResctrl          string `toml:"sysresctrl"`
 	Plugins             string `toml:"plugins"`
 	DbValidatorInterval uint   `toml:"dbValidatorInterval"`
@@ -75,6 +76,7 @@ var def = Default{
 	"",
 	"/etc/rmd/policy.toml",
 	false,
+	false,
 	"/sys/fs/resctrl",
 	"", // by default do not load any external plugin (if not configured)
 	30,
@@ -10,6 +10,9 @@ package pqos
 //int pqos_wrapper_alloc_assign(const unsigned *core_array, unsigned int core_amount_in_array, unsigned *class_id);
 //int pqos_wrapper_set_mba_for_common_cos(unsigned classID, int mbaMode, const unsigned *mbaMax, const unsigned *socketsToSetArray, int numOfSockets);
 //int pqos_wrapper_alloc_l3cache(unsigned classID, const unsigned *waysMask, const unsigned *socketsToSet, int numOfSockets);
+//int pqos_wrapper_alloc_l3cache_cdp(unsigned classID, const unsigned *codeMask, const unsigned *dataMask, const unsigned *socketsToSet, int numOfSockets);
+//int pqos_wrapper_alloc_l2cache(unsigned classID, const unsigned *waysMask, const unsigned *l2IDs, int numOfIDs);
+//int pqos_wrapper_reset_l2cache(unsigned classID);
 //int pqos_wrapper_assoc_core(unsigned classID, const unsigned *cores, int numOfCores);
 //int pqos_wrapper_assoc_pid(unsigned classID, const unsigned *tasks, int numOfTasks);
 //int pqos_wrapper_get_clos_num(int *l3ca_clos_num, int *mba_clos_num);
@@ -24,6 +27,7 @@ import (
 	"strconv"
 	"strings"
 
+	util "github.com/intel/rmd/utils/bitmap"
 	"github.com/intel/rmd/utils/resctrl"
 	log "github.com/sirupsen/logrus"
 )
@@ -74,6 +78,23 @@ type L3CacheStruct struct {
 	SocketsToSet []int    // sockets to set
 }
 
+// L3CDPCacheStruct contains values needed to set L3 code and data values (CDP mode)
+// amount of elements in CodeMask and DataMask must be equal amount of SocketsToSet
+type L3CDPCacheStruct struct {
+	ClassID      int      // class of service (COS#)
+	CodeMask     []uint64 // bit mask for L3 cache ways used for code for all specified sockets
+	DataMask     []uint64 // bit mask for L3 cache ways used for data for all specified sockets
+	SocketsToSet []int    // sockets to set
+}
+
+// L2CacheStruct contains values needed to set L2 values
+// amount of elements in WaysMask must be equal amount of L2IDsToSet
+type L2CacheStruct struct {
+	ClassID    int      // class of service (COS#)
+	WaysMask   []uint64 // bit mask for L2 cache ways for all specified L2 cache ids
+	L2IDsToSet []int    // L2 cache ids to set
+}
+
 // AssocCoresStruct contains values needed to assoc cores for common ClassID
 type AssocCoresStruct struct {
 	ClassID int   // common class of service (COS#)
@@ -103,6 +124,10 @@ func InitCLOSPool() error {
 	if err != nil {
 		return fmt.Errorf("Error when fetching number of CLOSes: %v", err.Error())
 	}
+	// COS numbers are common for all resources so L2 CAT can limit number of CLOSes too
+	if numOfL2Clos, err := resctrl.GetNumOfL2CLOS(); err == nil && numOfL2Clos < numOfClos {
+		numOfClos = numOfL2Clos
+	}
 	reservedCLOSes = []string{OSGroupCOS, InfraGoupCOS}
 	// lists of available and used CLOSes will never have all platform CLOSes as COS0 and COS1 are reserved
 	availableCLOSes = make([]string, 0, numOfClos-2)
@@ -149,25 +174,63 @@ func AllocateCLOS(res *resctrl.ResAssociation, name string) {
 		}
 	}
 
-	var s = []uint64{}
-	var cacheToSet L3CacheStruct
-	cacheToSet.ClassID = clos
+	if len(res.CacheSchemata["L3"]) >= GetNumOfSockets() {
+		var s = []uint64{}
+		var cacheToSet L3CacheStruct
+		cacheToSet.ClassID = clos
 
-	socketsToSet := []int{}
-	for i := 0; i < GetNumOfSockets(); i++ {
-		// TODO PQOS Add here handling for WaysMask
-		socketsToSet = append(socketsToSet, i)
-		var waysmask uint64 = 0
-		waysmask, _ = strconv.ParseUint(res.CacheSchemata["L3"][i].Mask, 16, 64)
-		s = append(s, waysmask)
+		socketsToSet := []int{}
+		for i := 0; i < GetNumOfSockets(); i++ {
+			// TODO PQOS Add here handling for WaysMask
+			socketsToSet = append(socketsToSet, i)
+			var waysmask uint64 = 0
+			waysmask, _ = strconv.ParseUint(res.CacheSchemata["L3"][i].Mask, 16, 64)
+			s = append(s, waysmask)
+		}
+		cacheToSet.WaysMask = s
+		cacheToSet.SocketsToSet = socketsToSet
+
+		err := AllocL3Cache(cacheToSet)
+		if err != nil {
+			log.Errorf("Failed to allocate L3 cache. Reason: %v", err)
+			return
+		}
 	}
-	cacheToSet.WaysMask = s
-	cacheToSet.SocketsToSet = socketsToSet
 
-	err := AllocL3Cache(cacheToSet)
-	if err != nil {
-		log.Errorf("Failed to allocate L3 cache. Reason: %v", err)
-		return
+	// set L3 code and data masks if specified in res (only when CDP is enabled)
+	if len(res.CacheSchemata["L3CODE"]) >= GetNumOfSockets() && len(res.CacheSchemata["L3DATA"]) >= GetNumOfSockets() {
+		var cdpToSet L3CDPCacheStruct
+		cdpToSet.ClassID = clos
+		for i := 0; i < GetNumOfSockets(); i++ {
+			codemask, _ := strconv.ParseUint(res.CacheSchemata["L3CODE"][i].Mask, 16, 64)
+			datamask, _ := strconv.ParseUint(res.CacheSchemata["L3DATA"][i].Mask, 16, 64)
+			cdpToSet.CodeMask = append(cdpToSet.CodeMask, codemask)
+			cdpToSet.DataMask = append(cdpToSet.DataMask, datamask)
+			cdpToSet.SocketsToSet = append(cdpToSet.SocketsToSet, i)
+		}
+		if err := AllocL3CacheCDP(cdpToSet); err != nil {
+			log.Errorf("Failed to allocate L3 code and data cache. Reason: %v", err)
+			return
+		}
+	}
+
+	// set L2 cache if specified in res (only on platforms with L2 CAT)
+	if len(res.CacheSchemata["L2"]) > 0 {
+		var l2ToSet L2CacheStruct
+		l2ToSet.ClassID = clos
+		for _, elem := range res.CacheSchemata["L2"] {
+			waysmask, err := strconv.ParseUint(elem.Mask, 16, 64)
+			if err != nil {
+				log.Errorf("Invalid L2 cache mask %v on L2 id %v", elem.Mask, elem.ID)
+				return
+			}
+			l2ToSet.WaysMask = append(l2ToSet.WaysMask, waysmask)
+			l2ToSet.L2IDsToSet = append(l2ToSet.L2IDsToSet, int(elem.ID))
+		}
+		if err := AllocL2Cache(l2ToSet); err != nil {
+			log.Errorf("Failed to allocate L2 cache. Reason: %v", err)
+			return
+		}
 	}
 
 	// don't need to invoke AssocTask/AssocCore code for COS#0
@@ -205,7 +268,7 @@ func AllocateCLOS(res *resctrl.ResAssociation, name string) {
 		var tasksToAssoc AssocTasksStruct
 		tasksToAssoc.ClassID = clos
 		tasksToAssoc.Tasks = tasksAsInts
-		err = AssocTask(tasksToAssoc)
+		err := AssocTask(tasksToAssoc)
 		if err != nil {
 			log.Errorf("Failed to associate tasks. Reason: %v", err)
 			return
@@ -491,6 +554,38 @@ func SetMbaForSingleCos(mbaValuesToSet MbaStruct) error {
 	return nil
 }
 
+// SetMbaForCOS sets MBA values (in units of current MBA mode) on given sockets for COS# given by name
+// mbaValues - socket id -> MBA value
+// Function returns operation status (nil or error)
+func SetMbaForCOS(cosName string, mbaValues map[int]int) error {
+	//splits "COS#"" into "COS" and "#"
+	cosSlice := strings.SplitAfter(cosName, "COS")
+	if len(cosSlice) != 2 {
+		return fmt.Errorf("Invalid COS name: %v", cosName)
+	}
+	cosAsInt, err := strconv.Atoi(cosSlice[1])
+	if err != nil {
+		return fmt.Errorf("Invalid COS name: %v", cosName)
+	}
+	if len(mbaValues) == 0 {
+		return errors.New("No MBA values to set")
+	}
+
+	mbaMode, err := CheckMBA()
+	if err != nil {
+		return err
+	}
+
+	var mbaToSet MbaStruct
+	mbaToSet.ClassID = cosAsInt
+	mbaToSet.MbaMode = mbaMode
+	for socket, value := range mbaValues {
+		mbaToSet.SocketsToSet = append(mbaToSet.SocketsToSet, socket)
+		mbaToSet.MbaMaxes = append(mbaToSet.MbaMaxes, value)
+	}
+	return SetMbaForSingleCos(mbaToSet)
+}
+
 // AllocL3Cache allocates L3 Cache for common COS#
 // l3ValuesToSet - contains values needed to set L3 values
 // Function returns operation status (nil or error)
@@ -518,6 +613,72 @@ func AllocL3Cache(l3ValuesToSet L3CacheStruct) error {
 	return nil
 }
 
+// AllocL3CacheCDP allocates L3 Cache code and data ways for common COS#
+// l3ValuesToSet - contains values needed to set L3 code and data values
+// Function returns operation status (nil or error)
+func AllocL3CacheCDP(l3ValuesToSet L3CDPCacheStruct) error {
+
+	if len(l3ValuesToSet.CodeMask) != len(l3ValuesToSet.SocketsToSet) ||
+		len(l3ValuesToSet.DataMask) != len(l3ValuesToSet.SocketsToSet) {
+		return errors.New("Amount of elements in CodeMask and DataMask must be equal amount of sockets")
+	}
+	numOfElements := len(l3ValuesToSet.SocketsToSet)
+	if numOfElements == 0 {
+		return nil
+	}
+	codeMaskAsUInts := make([]C.uint, 0, numOfElements)
+	for _, s := range l3ValuesToSet.CodeMask {
+		codeMaskAsUInts = append(codeMaskAsUInts, C.uint(s))
+	}
+	dataMaskAsUInts := make([]C.uint, 0, numOfElements)
+	for _, s := range l3ValuesToSet.DataMask {
+		dataMaskAsUInts = append(dataMaskAsUInts, C.uint(s))
+	}
+
+	socketsToSetAsUInts := make([]C.uint, 0, numOfElements)
+	for _, s := range l3ValuesToSet.SocketsToSet {
+		socketsToSetAsUInts = append(socketsToSetAsUInts, C.uint(s))
+	}
+	log.Debugf("Want to set code mask: %x data mask: %x on sockets: %v", codeMaskAsUInts, dataMaskAsUInts, socketsToSetAsUInts)
+	result := C.pqos_wrapper_alloc_l3cache_cdp(C.uint(l3ValuesToSet.ClassID), &(codeMaskAsUInts[0]), &(dataMaskAsUInts[0]),
+		&(socketsToSetAsUInts[0]), C.int(numOfElements))
+
+	if result != 0 {
+		return errors.New("Failed to set L3 code and data for common COS")
+	}
+	return nil
+}
+
+// AllocL2Cache allocates L2 Cache for common COS#
+// l2ValuesToSet - contains values needed to set L2 values
+// Function returns operation status (nil or error)
+func AllocL2Cache(l2ValuesToSet L2CacheStruct) error {
+
+	if len(l2ValuesToSet.WaysMask) != len(l2ValuesToSet.L2IDsToSet) {
+		return errors.New("Amount of elements in WaysMask must be equal amount of L2 ids")
+	}
+	numOfElements := len(l2ValuesToSet.L2IDsToSet)
+	if numOfElements == 0 {
+		return nil
+	}
+	waysMaskAsUInts := make([]C.uint, 0, numOfElements)
+	for _, s := range l2ValuesToSet.WaysMask {
+		waysMaskAsUInts = append(waysMaskAsUInts, C.uint(s))
+	}
+
+	l2IDsAsUInts := make([]C.uint, 0, numOfElements)
+	for _, s := range l2ValuesToSet.L2IDsToSet {
+		l2IDsAsUInts = append(l2IDsAsUInts, C.uint(s))
+	}
+	log.Debugf("Want to set L2 wayMask: %x on L2 ids: %v", waysMaskAsUInts, l2IDsAsUInts)
+	result := C.pqos_wrapper_alloc_l2cache(C.uint(l2ValuesToSet.ClassID), &(waysMaskAsUInts[0]), &(l2IDsAsUInts[0]), C.int(numOfElements))
+
+	if result != 0 {
+		return errors.New("Failed to set L2 for common COS")
+	}
+	return nil
+}
+
 // AssocCore associates core
 // coresStruct - contains values needed to associate core
 // Function returns operation status (nil or error)
@@ -605,6 +766,15 @@ func UseAvailableCLOS() (string, error) {
 	return result, nil
 }
 
+// PeekAvailableCLOS returns name of CLOS that would be taken by next UseAvailableCLOS call
+// without moving it to used CLOSes. Used to simulate allocation (dry run)
+func PeekAvailableCLOS() (string, error) {
+	if len(availableCLOSes) < 1 {
+		return "", errors.New("No free CLOS available")
+	}
+	return availableCLOSes[0], nil
+}
+
 // ReturnClos moves CLOS with given name from used list into available list
 func ReturnClos(name string) error {
 	// TODO In future add locks for thread safety (in some rare situtations race condition can appear)
@@ -675,6 +845,19 @@ func GetSharedCLOS() (string, error) {
 	return sharedCLOS, nil
 }
 
+// PeekSharedCLOS returns name of shared COS or name of CLOS that would be reserved for it
+// by GetSharedCLOS. No CLOS is reserved by this function
+func PeekSharedCLOS() (string, error) {
+	if sharedCLOS != "" {
+		return sharedCLOS, nil
+	}
+	name, err := PeekAvailableCLOS()
+	if err != nil {
+		return "", errors.New("No free CLOS left to create shared group")
+	}
+	return name, nil
+}
+
 // IsSharedCLOS returns true if given CLOS name is reserved for shared workloads, false otherwise
 func IsSharedCLOS(name string) bool {
 	return strings.Compare(name, sharedCLOS) == 0
@@ -725,31 +908,12 @@ func CoreMaskToSlice(mask string) ([]int, error) {
 	if len(mask) == 0 {
 		return []int{}, errors.New("Empty mask")
 	}
-	maskParts := strings.Split(mask, ",")
-
-	uintLen := 32
-	maskPartBase := 0
-	if len(maskParts[len(maskParts)-1]) > 8 {
-		uintLen = 64
-	}
-
-	coreList := make([]int, 0, 16) // for most cases there will be no more than 16 cores in workload
-	// process mask parts from last one so from lowest core numbers
-	for index := len(maskParts) - 1; index >= 0; index-- {
-		maskPartAsUint, err := strconv.ParseUint(maskParts[index], 16, uintLen)
-		if err != nil {
-			return []int{}, fmt.Errorf("String parsing error: %v", err.Error())
-		}
-		for shiftNum := 0; shiftNum < uintLen; shiftNum++ {
-			if (maskPartAsUint & 0x0001) == 1 {
-				coreList = append(coreList, shiftNum+maskPartBase)
-			}
-			maskPartAsUint >>= 1
-		}
-		maskPartBase += uintLen
-
+	bm, err := util.NewBitmap(mask)
+	if err != nil {
+		return []int{}, fmt.Errorf("String parsing error: %v", err.Error())
 	}
-	return coreList, nil
+	// process mask from lowest core numbers
+	return bm.ToList(), nil
 }
 
 // resetMBAToDefaults resets MBA to default values for specified COS#
@@ -830,7 +994,20 @@ func resetL3CacheToDefaults(cos int) error {
 	return nil
 }
 
-// ResetCOSParamsToDefaults resets L3 cache and MBA to default values for specified COS#
+// resetL2CacheToDefaults resets L2 cache to default values for specified COS#
+// Nothing is done on platforms without L2 CAT
+func resetL2CacheToDefaults(cos int) error {
+
+	log.Debugf("Setting L2 cache to default values for COS%d", cos)
+
+	result := C.pqos_wrapper_reset_l2cache(C.uint(cos))
+	if result != 0 {
+		return errors.New("Failed to reset L2 cache to defaults")
+	}
+	return nil
+}
+
+// ResetCOSParamsToDefaults resets L3 cache, L2 cache and MBA to default values for specified COS#
 func ResetCOSParamsToDefaults(cosName string) error {
 	log.Debugf("Setting L3 cache to default values for %v", cosName)
 	//splits "COS#"" into "COS" and "#"
@@ -847,6 +1024,12 @@ func ResetCOSParamsToDefaults(cosName string) error {
 		return err
 	}
 
+	err = resetL2CacheToDefaults(cosAsInt)
+	if err != nil {
+		log.Errorf("%v", err)
+		return err
+	}
+
 	err = resetMBAToDefaults(cosAsInt)
 	if err != nil {
 		log.Errorf("%v", err)
@@ -130,6 +130,38 @@ func TestGetSharedCLOS(t *testing.T) {
 	}
 }
 
+func TestPeekSharedCLOS(t *testing.T) {
+	tests := []struct {
+		name     string
+		shared   string
+		avCloses []string
+		want     string
+		wantErr  bool
+	}{
+		{"Negative", "", []string{}, "", true},
+		{"Positive 1 (next available COS)", "", []string{"COS3", "COS4"}, "COS3", false},
+		{"Positive 2 (existing shared COS)", "COS5", []string{"COS3"}, "COS5", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sharedCLOS = tt.shared
+			availableCLOSes = tt.avCloses
+			got, err := PeekSharedCLOS()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PeekSharedCLOS() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("PeekSharedCLOS() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(tt.avCloses, availableCLOSes) {
+				t.Errorf("PeekSharedCLOS() changed available list to %v", availableCLOSes)
+			}
+		})
+	}
+	sharedCLOS = ""
+}
+
 func TestGetAvailableCLOSes(t *testing.T) {
 	tests := []struct {
 		name  string
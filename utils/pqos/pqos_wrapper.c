@@ -36,6 +36,9 @@ int pqos_wrapper_alloc_release(const unsigned *core_array, unsigned int core_amo
 int pqos_wrapper_alloc_assign(const unsigned *core_array, unsigned int core_amount_in_array, unsigned *class_id);
 int pqos_wrapper_set_mba_for_common_cos(unsigned classID, int mbaMode, const unsigned *mbaMax, const unsigned *socketsToSetArray, int numOfSockets);
 int pqos_wrapper_alloc_l3cache(unsigned classID, const unsigned *waysMask, const unsigned *socketsToSet, int numOfSockets);
+int pqos_wrapper_alloc_l3cache_cdp(unsigned classID, const unsigned *codeMask, const unsigned *dataMask, const unsigned *socketsToSet, int numOfSockets);
+int pqos_wrapper_alloc_l2cache(unsigned classID, const unsigned *waysMask, const unsigned *l2IDs, int numOfIDs);
+int pqos_wrapper_reset_l2cache(unsigned classID);
 int pqos_wrapper_assoc_core(unsigned classID, const unsigned *cores, int numOfCores);
 int pqos_wrapper_assoc_pid(unsigned classID, const unsigned *tasks, int numOfTasks);
 int pqos_wrapper_get_clos_num(int *l3ca_clos_num, int *mba_clos_num);
@@ -151,6 +154,141 @@ int pqos_wrapper_alloc_l3cache(unsigned classID, const unsigned *waysMask, const
     return PQOS_RETVAL_OK;
 }
 
+/*Allocate L3Cache with separate code and data masks (CDP has to be enabled)
+    @param [in]  classID        class of service
+    @param [in]  codeMask       L3 cache ways masks for code for all specified sockets
+    @param [in]  dataMask       L3 cache ways masks for data for all specified sockets
+    @param [in]  socketsToSet   sockets to set
+    @param [in]  numOfSockets   amount of elements in codeMask and dataMask which should be also amount of elements in socketsToSet
+    @return operation status - PQOS_RETVAL_OK on success
+*/
+int pqos_wrapper_alloc_l3cache_cdp(unsigned classID, const unsigned *codeMask, const unsigned *dataMask, const unsigned *socketsToSet, int numOfSockets)
+{
+    const struct pqos_cpuinfo *p_cpu = NULL;
+    const struct pqos_cap *p_cap = NULL;
+    unsigned l3cat_id_count, *p_l3cat_ids = NULL;
+
+    int ret = pqos_cap_get(&p_cap, &p_cpu);
+    if (ret != PQOS_RETVAL_OK)
+    {
+        debug_print("Error retrieving PQoS capabilities!\n");
+        return PQOS_RETVAL_ERROR;
+    }
+
+    p_l3cat_ids = pqos_cpu_get_l3cat_ids(p_cpu, &l3cat_id_count);
+    if (p_l3cat_ids == NULL)
+    {
+        debug_print("Error retrieving CPU socket information!\n");
+        return PQOS_RETVAL_ERROR;
+    }
+
+    struct pqos_l3ca l3ca;
+    for (int i = 0; i < numOfSockets; i++)
+    {
+        memset(&l3ca, 0x0, sizeof(struct pqos_l3ca));
+        l3ca.class_id = classID;
+        l3ca.cdp = 1;
+        l3ca.u.s.code_mask = (uint64_t)codeMask[i];
+        l3ca.u.s.data_mask = (uint64_t)dataMask[i];
+
+        int socket = socketsToSet[i];
+        debug_print("Setting L3 code: %x data: %x on socket: %d for clos: %d\n", (int)codeMask[i], (int)dataMask[i], socket, (int)classID);
+
+        ret = pqos_l3ca_set(p_l3cat_ids[socket], 1, &l3ca);
+        if (ret != PQOS_RETVAL_OK)
+        {
+            debug_print("Setting up CDP cache allocation class of service failed!\n");
+            free(p_l3cat_ids);
+            return PQOS_RETVAL_ERROR;
+        }
+    }
+
+    free(p_l3cat_ids);
+    return PQOS_RETVAL_OK;
+}
+
+/*Allocate L2Cache
+    @param [in]  classID    class of service
+    @param [in]  waysMask   L2 cache ways masks for all specified L2 cache ids
+    @param [in]  l2IDs      L2 cache ids (as used in resctrl schemata) to set
+    @param [in]  numOfIDs   amount of elements in waysMask which should be also amount of elements in l2IDs
+    @return operation status - PQOS_RETVAL_OK on success
+*/
+int pqos_wrapper_alloc_l2cache(unsigned classID, const unsigned *waysMask, const unsigned *l2IDs, int numOfIDs)
+{
+    struct pqos_l2ca l2ca;
+    for (int i = 0; i < numOfIDs; i++)
+    {
+        memset(&l2ca, 0x0, sizeof(struct pqos_l2ca));
+        l2ca.class_id = classID;
+        l2ca.u.ways_mask = (uint64_t)waysMask[i];
+
+        debug_print("Setting L2 cache value: %x on L2 id: %d for clos: %d\n", (int)waysMask[i], (int)l2IDs[i], (int)classID);
+
+        int ret = pqos_l2ca_set(l2IDs[i], 1, &l2ca);
+        if (ret != PQOS_RETVAL_OK)
+        {
+            debug_print("Setting up L2 cache allocation class of service failed!\n");
+            return PQOS_RETVAL_ERROR;
+        }
+    }
+
+    return PQOS_RETVAL_OK;
+}
+
+/*Reset L2Cache of given class of service to default (all ways) on all L2 cache ids
+    Nothing is done if platform does not support L2 CAT
+    @param [in]  classID    class of service
+    @return operation status - PQOS_RETVAL_OK on success
+*/
+int pqos_wrapper_reset_l2cache(unsigned classID)
+{
+    const struct pqos_cpuinfo *p_cpu = NULL;
+    const struct pqos_cap *p_cap = NULL;
+    const struct pqos_capability *cap_l2ca = NULL;
+    unsigned l2id_count = 0, *p_l2ids = NULL;
+
+    int ret = pqos_cap_get(&p_cap, &p_cpu);
+    if (ret != PQOS_RETVAL_OK)
+    {
+        debug_print("Error retrieving PQoS capabilities!\n");
+        return PQOS_RETVAL_ERROR;
+    }
+
+    ret = pqos_cap_get_type(p_cap, PQOS_CAP_TYPE_L2CA, &cap_l2ca);
+    if (ret != PQOS_RETVAL_OK || cap_l2ca == NULL)
+    {
+        debug_print("L2 CAT not supported - nothing to reset\n");
+        return PQOS_RETVAL_OK;
+    }
+
+    p_l2ids = pqos_cpu_get_l2ids(p_cpu, &l2id_count);
+    if (p_l2ids == NULL)
+    {
+        debug_print("Error retrieving L2 cache ids!\n");
+        return PQOS_RETVAL_ERROR;
+    }
+
+    struct pqos_l2ca l2ca;
+    for (unsigned i = 0; i < l2id_count; i++)
+    {
+        memset(&l2ca, 0x0, sizeof(struct pqos_l2ca));
+        l2ca.class_id = classID;
+        l2ca.u.ways_mask = (1ULL << p_cpu->l2.num_ways) - 1;
+
+        ret = pqos_l2ca_set(p_l2ids[i], 1, &l2ca);
+        if (ret != PQOS_RETVAL_OK)
+        {
+            debug_print("Resetting L2 cache allocation class of service failed!\n");
+            free(p_l2ids);
+            return PQOS_RETVAL_ERROR;
+        }
+    }
+
+    free(p_l2ids);
+    return PQOS_RETVAL_OK;
+}
+
 // Checks if MBA is supported and in which mode
 // [out] mbaMode where 0 percentage mode
 //                     1 MBps mode
@@ -32,8 +32,13 @@ var (
 	MbaInfoPath string
 	// Resctrl mounted with Mba Mbps
 	isMbaMbpsEnabled bool
+	// SysCgroup is the mount point of cgroup filesystem
+	SysCgroup = "/sys/fs/cgroup"
 )
 
+// cgroup v1 hierarchies searched (in order) for relative cgroup path
+var cgroupV1Hierarchies = []string{"cpuset", "cpu,cpuacct", "pids", "memory", "systemd"}
+
 // rdt_a, cat_l3, cdp_l3, cqm, cqm_llc, cqm_occup_llc
 // cqm_mbm_total, cqm_mbm_local
 func parseCPUInfoFile(flag string) (bool, error) {
@@ -97,6 +102,15 @@ func IsCqmAvailable() (bool, error) {
 	return parseCPUInfoFile("cqm")
 }
 
+// IsMbmAvailable returns MBM feature available or not
+func IsMbmAvailable() (bool, error) {
+	total, err := parseCPUInfoFile("cqm_mbm_total")
+	if err != nil || total {
+		return total, err
+	}
+	return parseCPUInfoFile("cqm_mbm_local")
+}
+
 // IsCdpAvailable returns CDP feature available or not
 func IsCdpAvailable() (bool, error) {
 	return parseCPUInfoFile("cdp_l3")
@@ -122,6 +136,11 @@ var IsL3CatAvailable = func() (bool, error) {
 	return parseCPUInfoFile("cat_l3")
 }
 
+// IsL2CatAvailable returns L2 CAT feature available or not
+var IsL2CatAvailable = func() (bool, error) {
+	return parseCPUInfoFile("cat_l2")
+}
+
 // we can use shell command: "mount -l -t resctrl"
 var findMountDir = func(mountdir string) (string, error) {
 	f, err := os.Open(MountInfoPath)
@@ -162,6 +181,16 @@ func IsEnableCdp() bool {
 	return strings.Contains(mount, flag)
 }
 
+// IsEnableMbaMbps returns if resctrl is mounted with MBA in MBps mode or not
+func IsEnableMbaMbps() bool {
+	var flag = "mba_MBps"
+	mount, err := findMountDir(ResctrlPath)
+	if err != nil {
+		return false
+	}
+	return strings.Contains(mount, flag)
+}
+
 // IsEnableCat returns if CAT is enabled or not
 func IsEnableCat() bool {
 	var flag = "cdp"
@@ -187,10 +216,11 @@ var IsEnableMba = func() (bool, error) {
 	return false, err
 }
 
-// Process struct with pid and command line
+// Process struct with pid, command line and process name
 type Process struct {
 	Pid     int
 	CmdLine string
+	Comm    string
 }
 
 // ListProcesses returns all process on the host
@@ -204,13 +234,55 @@ var ListProcesses = func() map[string]Process {
 
 			cmd, _ := ioutil.ReadFile(file)
 			cmdString := strings.Join(strings.Split(string(cmd), "\x00"), " ")
-			processes[listfs[2]] = Process{pid, cmdString}
+			comm, _ := ioutil.ReadFile(filepath.Join(filepath.Dir(file), "comm"))
+			processes[listfs[2]] = Process{pid, cmdString, strings.TrimSpace(string(comm))}
 		}
 	}
 
 	return processes
 }
 
+// GetCgroupTasks returns PIDs of all processes in cgroup.
+// Path can be absolute or relative to cgroup mount point (unified hierarchy
+// is checked first, then cgroup v1 hierarchies)
+var GetCgroupTasks = func(path string) ([]string, error) {
+	dirs := []string{path}
+	if !filepath.IsAbs(path) {
+		dirs = []string{filepath.Join(SysCgroup, path)}
+		for _, h := range cgroupV1Hierarchies {
+			dirs = append(dirs, filepath.Join(SysCgroup, h, path))
+		}
+	}
+	for _, dir := range dirs {
+		data, err := ioutil.ReadFile(filepath.Join(dir, "cgroup.procs"))
+		if err == nil {
+			return strings.Fields(string(data)), nil
+		}
+	}
+	return nil, fmt.Errorf("Cgroup %s not found", path)
+}
+
+// GetProcessUnit returns systemd unit of process (last element of its cgroup path)
+var GetProcessUnit = func(pid string) string {
+	data, err := ioutil.ReadFile(filepath.Join("/proc", pid, "cgroup"))
+	if err != nil {
+		return ""
+	}
+	unit := ""
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		// hierarchy-ID:controller-list:cgroup-path
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		// systemd hierarchy (v1) or unified hierarchy (v2)
+		if fields[1] == "name=systemd" || (fields[0] == "0" && fields[1] == "") {
+			unit = filepath.Base(fields[2])
+		}
+	}
+	return unit
+}
+
 // GetCPUAffinity returns the affinity of a given task id
 func GetCPUAffinity(Pid string) (*util.Bitmap, error) {
 	// each uint is 64 bits
@@ -229,25 +301,14 @@ func GetCPUAffinity(Pid string) (*util.Bitmap, error) {
 		uintptr(unsafe.Pointer(&mask[0])),
 	)
 
-	// util.Bitmap.Bits accept 32 bit int type, need to covert it
-	var bits []int
-	for _, i := range mask {
-		val := uint(i)
-		// FIXME: what's the hell, find low 32 bits
-		bits = append(bits, int((val<<32)>>32))
-		bits = append(bits, int(val>>32))
-	}
 	if ierr != 0 {
 		return nil, ierr
 	}
-	// this is so hacking to construct a Bitmap,
-	// Bitmap shouldn't expose detail to other package at all
-	return &util.Bitmap{
-		// Need to set correctly and carefully, this is the total cpu
-		// number
-		Len:  16 * 64,
-		Bits: bits,
-	}, nil
+	words := make([]uint64, len(mask))
+	for i, m := range mask {
+		words[i] = uint64(m)
+	}
+	return util.NewBitmapFromWords(len(mask)*64, words), nil
 }
 
 // SetCPUAffinity set a process/thread's CPU affinity
@@ -260,9 +321,10 @@ func SetCPUAffinity(Pid string, cpus *util.Bitmap) error {
 		return err
 	}
 
-	for idx, bit := range cpus.Bits {
-		ubit := uintptr(bit)
-		mask[idx/2] |= ubit
+	for i, w := range cpus.Words() {
+		if i < len(mask) {
+			mask[i] = uintptr(w)
+		}
 	}
 
 	_, _, ierr := syscall.RawSyscall(unix.SYS_SCHED_SETAFFINITY, uintptr(pid), uintptr(len(mask)*8), uintptr(unsafe.Pointer(&mask[0])))
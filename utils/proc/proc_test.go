@@ -2,6 +2,10 @@ package proc
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strconv"
 	"testing"
 
@@ -50,3 +54,55 @@ func TestGetCPUAffinity(t *testing.T) {
 
 	testhelpers.CleanupProcess(ospid)
 }
+
+func TestGetCgroupTasks(t *testing.T) {
+	root, err := ioutil.TempDir("", "cgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		"unified/app/cgroup.procs":     "10\n11\n",
+		"cpuset/legacy/cgroup.procs":   "20\n",
+		"unified/empty/cgroup.procs":   "",
+		"pids/other/app2/cgroup.procs": "30\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldCgroup, oldHierarchies := SysCgroup, cgroupV1Hierarchies
+	SysCgroup = filepath.Join(root, "unified")
+	cgroupV1Hierarchies = []string{"../cpuset", "../pids"}
+	defer func() { SysCgroup, cgroupV1Hierarchies = oldCgroup, oldHierarchies }()
+
+	tests := []struct {
+		name    string
+		path    string
+		want    []string
+		wantErr bool
+	}{
+		{"Unified hierarchy", "app", []string{"10", "11"}, false},
+		{"Cgroup v1 hierarchy", "legacy", []string{"20"}, false},
+		{"Absolute path", filepath.Join(root, "pids/other/app2"), []string{"30"}, false},
+		{"Empty cgroup", "empty", []string{}, false},
+		{"Not existing cgroup", "none", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetCgroupTasks(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetCgroupTasks() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetCgroupTasks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
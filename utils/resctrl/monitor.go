@@ -0,0 +1,105 @@
+// +build linux
+
+package resctrl
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// MonData is a single CMT/MBM sample of one monitoring domain
+type MonData struct {
+	// LLCOccupancy is the last level cache occupancy in bytes
+	LLCOccupancy uint64 `json:"llc_occupancy"`
+	// MBMTotalBytes is the total memory bandwidth counter in bytes
+	MBMTotalBytes uint64 `json:"mbm_total_bytes"`
+	// MBMLocalBytes is the local memory bandwidth counter in bytes
+	MBMLocalBytes uint64 `json:"mbm_local_bytes"`
+}
+
+// monGroupPath returns path of monitoring group "name" inside resource group "group"
+// For default resource group "." it is the mon_groups directory of the resctrl root
+func monGroupPath(group, name string) string {
+	return filepath.Join(SysResctrl, group, "mon_groups", name)
+}
+
+// CreateMonGroup creates monitoring group "name" under resource group "group"
+// and assigns given tasks and cpus to it. If the monitoring group already exists
+// only tasks and cpus are (re)assigned.
+// Tasks and cpus have to belong to the parent resource group already.
+func CreateMonGroup(group, name string, tasks []string, cpus string) error {
+	if name == "" {
+		return fmt.Errorf("empty monitoring group name")
+	}
+	path := monGroupPath(group, name)
+	if err := os.Mkdir(path, 0755); err != nil && !os.IsExist(err) {
+		return fmt.Errorf("failed to create monitoring group %s: %v", path, err)
+	}
+	// write one task one time, or write will fail
+	for _, v := range tasks {
+		if err := writeFile(path, "tasks", v); err != nil {
+			return err
+		}
+	}
+	if len(cpus) > 0 {
+		if err := writeFile(path, "cpus", cpus); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DestroyMonGroup removes monitoring group "name" from resource group "group"
+// Tasks and cpus of the monitoring group are moved back to the parent group by kernel
+func DestroyMonGroup(group, name string) error {
+	if name == "" {
+		return nil
+	}
+	err := os.Remove(monGroupPath(group, name))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// GetMonData reads monitoring data of monitoring group "name" in resource group "group"
+// Result is a map of L3 domain id (as in mon_data/mon_L3_XX) to the sample
+// Events not supported by platform (or marked as "Unavailable" by kernel) are reported as 0
+func GetMonData(group, name string) (map[string]MonData, error) {
+	basepath := filepath.Join(monGroupPath(group, name), "mon_data")
+	domains, err := ioutil.ReadDir(basepath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]MonData)
+	for _, d := range domains {
+		if !d.IsDir() || !strings.HasPrefix(d.Name(), "mon_L3_") {
+			continue
+		}
+		id := strings.TrimPrefix(d.Name(), "mon_L3_")
+		path := filepath.Join(basepath, d.Name())
+		result[id] = MonData{
+			LLCOccupancy:  readMonEvent(path, "llc_occupancy"),
+			MBMTotalBytes: readMonEvent(path, "mbm_total_bytes"),
+			MBMLocalBytes: readMonEvent(path, "mbm_local_bytes"),
+		}
+	}
+	return result, nil
+}
+
+func readMonEvent(path, event string) uint64 {
+	data, err := ioutil.ReadFile(filepath.Join(path, event))
+	if err != nil {
+		return 0
+	}
+	val, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return val
+}
@@ -0,0 +1,92 @@
+// +build linux
+
+package resctrl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGetMonData(t *testing.T) {
+	root, err := ioutil.TempDir("", "resctrl")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	oldResctrl := SysResctrl
+	SysResctrl = root
+	defer func() { SysResctrl = oldResctrl }()
+
+	domain := filepath.Join(root, "COS1", "mon_groups", "rmd_1", "mon_data", "mon_L3_00")
+	if err := os.MkdirAll(domain, 0755); err != nil {
+		t.Fatalf("Failed to create mon_data: %v", err)
+	}
+	ioutil.WriteFile(filepath.Join(domain, "llc_occupancy"), []byte("1048576\n"), 0644)
+	ioutil.WriteFile(filepath.Join(domain, "mbm_total_bytes"), []byte("2048\n"), 0644)
+	ioutil.WriteFile(filepath.Join(domain, "mbm_local_bytes"), []byte("Unavailable\n"), 0644)
+
+	tests := []struct {
+		name    string
+		group   string
+		mon     string
+		want    map[string]MonData
+		wantErr bool
+	}{
+		{"existing group", "COS1", "rmd_1",
+			map[string]MonData{"00": MonData{LLCOccupancy: 1048576, MBMTotalBytes: 2048}}, false},
+		{"missing group", "COS1", "rmd_2", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetMonData(tt.group, tt.mon)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetMonData() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetMonData() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateDestroyMonGroup(t *testing.T) {
+	root, err := ioutil.TempDir("", "resctrl")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	oldResctrl := SysResctrl
+	SysResctrl = root
+	defer func() { SysResctrl = oldResctrl }()
+
+	os.MkdirAll(filepath.Join(root, "COS1", "mon_groups"), 0755)
+
+	if err := CreateMonGroup("COS1", "rmd_1", []string{"1"}, "3"); err != nil {
+		t.Errorf("CreateMonGroup() error = %v", err)
+	}
+	// second call only reassigns tasks and cpus
+	if err := CreateMonGroup("COS1", "rmd_1", []string{"2"}, ""); err != nil {
+		t.Errorf("CreateMonGroup() on existing group error = %v", err)
+	}
+	if err := CreateMonGroup("COS1", "", nil, ""); err == nil {
+		t.Errorf("CreateMonGroup() with empty name should fail")
+	}
+	// in real resctrl monitoring group directory contains only kernel files
+	os.Remove(filepath.Join(root, "COS1", "mon_groups", "rmd_1", "tasks"))
+	os.Remove(filepath.Join(root, "COS1", "mon_groups", "rmd_1", "cpus"))
+	if err := DestroyMonGroup("COS1", "rmd_1"); err != nil {
+		t.Errorf("DestroyMonGroup() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "COS1", "mon_groups", "rmd_1")); !os.IsNotExist(err) {
+		t.Errorf("DestroyMonGroup() monitoring group still exists")
+	}
+	if err := DestroyMonGroup("COS1", "rmd_1"); err != nil {
+		t.Errorf("DestroyMonGroup() on removed group error = %v", err)
+	}
+}
@@ -399,6 +399,16 @@ func RemoveTasks(tasks []string) error {
 	return err
 }
 
+// GetNumOfL2CLOS returns number of COSes for L2 Cache
+// Error is returned if platform does not support L2 CAT
+func GetNumOfL2CLOS() (int, error) {
+	data, err := ioutil.ReadFile(SysResctrl + "/info/L2/num_closids")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
 // GetNumOfCLOS returns number COSes for L3 Cache, MBA or both depending on input params
 // If both L3 Cache and MBA selected function returns lower of two values
 func GetNumOfCLOS(getL3Clos, getMbaClos bool) (int, error) {
@@ -409,7 +419,12 @@ func GetNumOfCLOS(getL3Clos, getMbaClos bool) (int, error) {
 
 	var numL3Clos, numMbaClos int
 	if getL3Clos {
-		l3NumFile, err := os.Open(SysResctrl + "/info/L3/num_closids")
+		l3Path := SysResctrl + "/info/L3/num_closids"
+		if _, err := os.Stat(l3Path); os.IsNotExist(err) {
+			// with CDP enabled there are separate L3CODE and L3DATA resources
+			l3Path = SysResctrl + "/info/L3CODE/num_closids"
+		}
+		l3NumFile, err := os.Open(l3Path)
 		if err != nil {
 			return 0, err
 		}
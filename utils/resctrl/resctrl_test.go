@@ -26,3 +26,35 @@ func TestGetRdtCosInfo(t *testing.T) {
 		fmt.Println(info)
 	}
 }
+
+func TestFormatCacheSchemata(t *testing.T) {
+	tests := []struct {
+		name     string
+		schemata map[string][]CacheCos
+		want     string
+	}{
+		{"L3 only",
+			map[string][]CacheCos{"L3": {{1, "f"}, {0, "ff"}}},
+			"L3:0=ff;1=f"},
+		{"CDP code and data",
+			map[string][]CacheCos{
+				"L3DATA": {{0, "f0"}, {1, "f0"}},
+				"L3CODE": {{0, "3"}, {1, "3"}},
+			},
+			"L3CODE:0=3;1=3\nL3DATA:0=f0;1=f0"},
+		{"L3 skipped in CDP mode",
+			map[string][]CacheCos{
+				"L3":     {{0, "ff"}},
+				"L3CODE": {{0, "3"}},
+				"L3DATA": {{0, "c"}},
+			},
+			"L3CODE:0=3\nL3DATA:0=c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatCacheSchemata(tt.schemata); got != tt.want {
+				t.Errorf("formatCacheSchemata() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	util "github.com/intel/rmd/utils"
@@ -146,21 +147,7 @@ func (t SchemataTask) Run() error {
 	dataCache = ""
 	dataMba = ""
 	if len(t.CacheSchemata) > 0 {
-		schemata := make([]string, 0, 10)
-		for k, v := range t.CacheSchemata {
-			str := make([]string, 0, 10)
-			// resctrl require we have strict cache id order
-			for cacheid := 0; cacheid < len(v); cacheid++ {
-				for _, cos := range v {
-					if uint8(cacheid) == cos.ID {
-						str = append(str, fmt.Sprintf("%d=%s", cos.ID, cos.Mask))
-						break
-					}
-				}
-			}
-			schemata = append(schemata, strings.Join([]string{k, strings.Join(str, ";")}, ":"))
-		}
-		dataCache = strings.Join(schemata, "\n")
+		dataCache = formatCacheSchemata(t.CacheSchemata)
 	}
 	if len(t.MbaSchemata) > 0 {
 		schemataMba := make([]string, 0, 10)
@@ -192,6 +179,40 @@ func (t SchemataTask) Run() error {
 	return nil
 }
 
+// formatCacheSchemata returns cache lines of schemata file in resource name order
+// With CDP enabled kernel accepts only L3CODE and L3DATA lines so L3 line is
+// skipped if any of them is defined
+func formatCacheSchemata(cacheSchemata map[string][]CacheCos) string {
+	_, hasCode := cacheSchemata["L3CODE"]
+	_, hasData := cacheSchemata["L3DATA"]
+
+	keys := make([]string, 0, len(cacheSchemata))
+	for k := range cacheSchemata {
+		if k == "L3" && (hasCode || hasData) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	schemata := make([]string, 0, len(keys))
+	for _, k := range keys {
+		v := cacheSchemata[k]
+		str := make([]string, 0, 10)
+		// resctrl require we have strict cache id order
+		for cacheid := 0; cacheid < len(v); cacheid++ {
+			for _, cos := range v {
+				if uint8(cacheid) == cos.ID {
+					str = append(str, fmt.Sprintf("%d=%s", cos.ID, cos.Mask))
+					break
+				}
+			}
+		}
+		schemata = append(schemata, strings.Join([]string{k, strings.Join(str, ";")}, ":"))
+	}
+	return strings.Join(schemata, "\n")
+}
+
 // Rollback to revert it
 func (t SchemataTask) Rollback() error {
 	// NOTE, do not need to revert the Schemata to the snapshort
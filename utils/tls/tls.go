@@ -118,7 +118,21 @@ func ACL(req *restful.Request, resp *restful.Response, chain *restful.FilterChai
 		return
 	}
 
-	if req.Request.TLS == nil || (clientauth > tls.NoClientCert && clientauth < tls.RequireAndVerifyClientCert) {
+	if req.Request.TLS == nil {
+		// plain HTTP (debug mode) and unix socket clients are trusted
+		acl.SetTenant(req, "", true)
+		chain.ProcessFilter(req, resp)
+		return
+	}
+
+	if clientauth > tls.NoClientCert && clientauth < tls.RequireAndVerifyClientCert {
+		// client certificate is optional, clients without verified one are anonymous
+		if len(req.Request.TLS.VerifiedChains) > 0 {
+			cn := req.Request.TLS.PeerCertificates[0].Subject.CommonName
+			acl.SetTenant(req, cn, acl.IsAdminTenant(cn))
+		} else {
+			acl.SetTenant(req, "", false)
+		}
 		chain.ProcessFilter(req, resp)
 		return
 	}
@@ -136,6 +150,7 @@ func ACL(req *restful.Request, resp *restful.Response, chain *restful.FilterChai
 		}
 
 		// Check user against ACL rules
+		acl.SetTenant(req, u, acl.IsAdminTenant(u))
 		check(u)
 		return
 	}
@@ -147,12 +162,14 @@ func ACL(req *restful.Request, resp *restful.Response, chain *restful.FilterChai
 		sig := string(req.Request.TLS.PeerCertificates[0].Signature)
 		for _, s := range GetAdminCertSignatures() {
 			if strings.Compare(string(sig), s) == 0 {
+				acl.SetTenant(req, cn, true)
 				chain.ProcessFilter(req, resp)
 				return
 			}
 		}
 		for _, s := range GetUserCertSignatures() {
 			if strings.Compare(string(sig), s) == 0 {
+				acl.SetTenant(req, cn, acl.IsAdminTenant(cn))
 				check(aclConf.CertClientUserRole)
 				return
 			}
@@ -168,6 +185,8 @@ func ACL(req *restful.Request, resp *restful.Response, chain *restful.FilterChai
 		OU := req.Request.TLS.PeerCertificates[0].Subject.OrganizationalUnit
 		for _, v := range OU {
 			if e.Enforce(req, strings.ToLower(v)) == true {
+				// workloads are owned by organizational unit
+				acl.SetTenant(req, strings.ToLower(v), acl.IsAdminTenant(v))
 				chain.ProcessFilter(req, resp)
 				return
 			}
@@ -178,6 +197,7 @@ func ACL(req *restful.Request, resp *restful.Response, chain *restful.FilterChai
 	}
 
 	if author == aclConf.CN {
+		acl.SetTenant(req, cn, acl.IsAdminTenant(cn))
 		check(cn)
 	}
 }